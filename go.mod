@@ -3,6 +3,9 @@ module github.com/feehi.io/gopkg
 go 1.14
 
 require (
-	github.com/stretchr/testify v1.6.1
+	github.com/stretchr/testify v1.7.0
+	go.opentelemetry.io/otel/trace v1.0.1
 	google.golang.org/api v0.35.0 // indirect
+	google.golang.org/grpc v1.31.1
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,166 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxQueuedBatches max count of full batches waiting to be sent, batches over it are dropped
+const maxQueuedBatches = 16
+
+var errBatcherClosed = errors.New("log output is closed")
+
+// batcher collect items and send them in batches by count, bytes or interval.
+// Batches are sent in order by a sender goroutine, so add never waits for a send. Full batches are queued
+// up to maxQueuedBatches, and dropped when the queue is full, dropped items are reported by next flush.
+type batcher struct {
+	maxCount int
+	maxBytes int
+	send     func(items []interface{}) error
+
+	mu    sync.Mutex
+	items []interface{}
+	size  int
+	// errs errors of batches not sent by flush, reported by next flush
+	errs    []string
+	dropped int
+	closed  bool
+
+	// batches full batches waiting for sender goroutine
+	batches chan []interface{}
+	// flushes ask sender goroutine to send queued batches and current batch, reply is closed once they are sent
+	flushes chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+func newBatcher(maxCount int, maxBytes int, interval time.Duration, send func(items []interface{}) error) *batcher {
+	b := &batcher{
+		maxCount: maxCount,
+		maxBytes: maxBytes,
+		send:     send,
+		batches:  make(chan []interface{}, maxQueuedBatches),
+		flushes:  make(chan chan struct{}),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go b.run(interval)
+	return b
+}
+
+// run send queued batches, and current batch every interval or on flush.
+func (b *batcher) run(interval time.Duration) {
+	defer close(b.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case items := <-b.batches:
+			b.record(b.send(items))
+		case <-ticker.C:
+			b.sendAll()
+		case reply := <-b.flushes:
+			b.sendAll()
+			close(reply)
+		case <-b.done:
+			b.sendAll()
+			return
+		}
+	}
+}
+
+// sendAll send queued batches then current batch, so batches are sent in order they are filled.
+func (b *batcher) sendAll() {
+	// only sender goroutine receives from batches, so it never blocks
+	for len(b.batches) > 0 {
+		b.record(b.send(<-b.batches))
+	}
+	b.mu.Lock()
+	items := b.items
+	b.items = nil
+	b.size = 0
+	b.mu.Unlock()
+	if len(items) > 0 {
+		b.record(b.send(items))
+	}
+}
+
+// add add an item of size bytes, current batch is queued first when item makes it exceed maxBytes,
+// and batch is queued once it reaches maxCount or maxBytes. It returns error after close.
+func (b *batcher) add(item interface{}, size int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return errBatcherClosed
+	}
+	if len(b.items) > 0 && b.size+size > b.maxBytes {
+		b.enqueue()
+	}
+	b.items = append(b.items, item)
+	b.size += size
+	if len(b.items) >= b.maxCount || b.size >= b.maxBytes {
+		b.enqueue()
+	}
+	return nil
+}
+
+// enqueue hand current batch to sender goroutine, it is dropped when queue is full. It must be called with mu held.
+func (b *batcher) enqueue() {
+	select {
+	case b.batches <- b.items:
+	default:
+		b.dropped += len(b.items)
+	}
+	b.items = nil
+	b.size = 0
+}
+
+func (b *batcher) record(err error) {
+	if err == nil {
+		return
+	}
+	b.mu.Lock()
+	b.errs = append(b.errs, err.Error())
+	b.mu.Unlock()
+}
+
+// flush send queued batches and current batch at once, return their errors, errors of batches sent
+// since last flush and count of dropped items.
+func (b *batcher) flush() error {
+	reply := make(chan struct{})
+	select {
+	case b.flushes <- reply:
+		<-reply
+	case <-b.stopped:
+	}
+	b.mu.Lock()
+	errs := b.errs
+	b.errs = nil
+	if b.dropped > 0 {
+		errs = append(errs, fmt.Sprintf("send queue is full, %d rows dropped", b.dropped))
+		b.dropped = 0
+	}
+	b.mu.Unlock()
+	if len(errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
+
+// close send queued batches and current batch, then stop sender goroutine. Later adds return error,
+// and later closes return nil.
+func (b *batcher) close() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+	close(b.done)
+	<-b.stopped
+	return b.flush()
+}
@@ -0,0 +1,93 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatcher(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]interface{}
+	b := newBatcher(3, 100, time.Hour, func(items []interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, items)
+		if len(batches) == 1 {
+			return errors.New("first batch error")
+		}
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				b.add(fmt.Sprintf("%d-%d", i, j), 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	err := b.flush()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "first batch error", err.Error())
+	}
+	assert.Nil(t, b.close())
+
+	// every item is sent once, items of one goroutine keep their order
+	sent := map[string]bool{}
+	last := map[byte]byte{}
+	for _, batch := range batches {
+		assert.True(t, len(batch) <= 3)
+		for _, item := range batch {
+			s := item.(string)
+			assert.False(t, sent[s])
+			sent[s] = true
+			assert.True(t, s[2] >= last[s[0]])
+			last[s[0]] = s[2]
+		}
+	}
+	assert.Equal(t, 20, len(sent))
+}
+
+func TestBatcher_queue(t *testing.T) {
+	started, release := make(chan struct{}, 1), make(chan struct{})
+	var mu sync.Mutex
+	var sent []interface{}
+	b := newBatcher(1, 100, time.Hour, func(items []interface{}) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, items...)
+		return nil
+	})
+
+	// add never waits for a blocked send, batches over the queue are dropped
+	assert.Nil(t, b.add(0, 1))
+	<-started
+	for i := 1; i < maxQueuedBatches+3; i++ {
+		assert.Nil(t, b.add(i, 1))
+	}
+	close(release)
+	err := b.flush()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "send queue is full, 2 rows dropped", err.Error())
+	}
+	assert.Equal(t, maxQueuedBatches+1, len(sent))
+	assert.Nil(t, b.flush())
+
+	assert.Nil(t, b.close())
+	assert.Equal(t, errBatcherClosed, b.add(0, 1))
+	assert.Nil(t, b.close())
+	assert.Nil(t, b.flush())
+}
@@ -0,0 +1,274 @@
+package logs
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// NewCBORFormatter create a CBORFormatter
+func NewCBORFormatter() *CBORFormatter {
+	return &CBORFormatter{}
+}
+
+// CBORFormatter format log to a CBOR(RFC 8949) row, prefixed with 4 bytes big endian length.
+// Rows can be read back with NewCBORDecoder.
+type CBORFormatter struct {
+}
+
+// Format format log to a length prefixed CBOR row
+func (f CBORFormatter) Format(commonFields []*CommonField, content *Content) []byte {
+	e := &cborEncoder{buf: make([]byte, 0, 256)}
+	encodeRecord(e, commonFields, content)
+	return frame(e.bytes())
+}
+
+// CBOR major types
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// CBOR tags for date/time
+const (
+	cborTagDateTimeString = 0
+	cborTagEpochDateTime  = 1
+)
+
+type cborEncoder struct {
+	buf []byte
+}
+
+func (e *cborEncoder) bytes() []byte {
+	return e.buf
+}
+
+func (e *cborEncoder) head(major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		e.buf = append(e.buf, major|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, major|25, byte(n>>8), byte(n))
+	case n <= math.MaxUint32:
+		e.buf = append(e.buf, major|26)
+		e.buf = appendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, major|27)
+		e.buf = appendUint64(e.buf, n)
+	}
+}
+
+func (e *cborEncoder) mapHeader(n int) {
+	e.head(cborMap, uint64(n))
+}
+
+func (e *cborEncoder) arrayHeader(n int) {
+	e.head(cborArray, uint64(n))
+}
+
+func (e *cborEncoder) str(s string) {
+	e.head(cborText, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *cborEncoder) int(i int64) {
+	if i >= 0 {
+		e.head(cborUint, uint64(i))
+	} else {
+		e.head(cborNegInt, uint64(-1-i))
+	}
+}
+
+// time encode t as tag 0 RFC3339 string, which keeps nanoseconds precision
+func (e *cborEncoder) time(t time.Time) {
+	e.head(cborTag, cborTagDateTimeString)
+	e.str(t.UTC().Format(time.RFC3339Nano))
+}
+
+// unmarshalCBOR decode a CBOR value.
+// Maps are decoded to map[string]interface{}, arrays to []interface{}, integers to int64(or uint64 when overflow int64),
+// tag 0 and tag 1 to time.Time. Indefinite length items are not supported.
+func unmarshalCBOR(b []byte) (interface{}, error) {
+	d := &cborDecoder{buf: b}
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(d.buf) {
+		return nil, fmt.Errorf("cbor: %d trailing bytes", len(d.buf)-d.off)
+	}
+	return v, nil
+}
+
+type cborDecoder struct {
+	buf []byte
+	off int
+}
+
+func (d *cborDecoder) next(n uint64) ([]byte, error) {
+	if uint64(len(d.buf)-d.off) < n {
+		return nil, fmt.Errorf("cbor: unexpected end of data at offset %d", d.off)
+	}
+	b := d.buf[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+// head read major type and argument
+func (d *cborDecoder) head() (byte, byte, uint64, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info <= 27:
+		b, err = d.next(1 << (info - 24))
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return major, info, n, nil
+	}
+	return 0, 0, 0, fmt.Errorf("cbor: unsupported additional info %d at offset %d", info, d.off-1)
+}
+
+func (d *cborDecoder) value() (interface{}, error) {
+	major, info, n, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case cborNegInt:
+		if n > math.MaxInt64 {
+			return nil, fmt.Errorf("cbor: negative integer overflow")
+		}
+		return -1 - int64(n), nil
+	case cborBytes, cborText:
+		b, err := d.next(n)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborText {
+			return string(b), nil
+		}
+		return append([]byte(nil), b...), nil
+	case cborArray:
+		if n > uint64(len(d.buf)-d.off) {
+			return nil, fmt.Errorf("cbor: array length %d exceeds data", n)
+		}
+		items := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			v, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case cborMap:
+		if n > uint64(len(d.buf)-d.off) {
+			return nil, fmt.Errorf("cbor: map length %d exceeds data", n)
+		}
+		m := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			k, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			v, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprintf("%v", k)] = v
+		}
+		return m, nil
+	case cborTag:
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		return cborTagValue(n, v)
+	}
+	return d.simpleValue(info, n)
+}
+
+func cborTagValue(tag uint64, v interface{}) (interface{}, error) {
+	switch tag {
+	case cborTagDateTimeString:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("cbor: tag 0 content should be string")
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	case cborTagEpochDateTime:
+		switch epoch := v.(type) {
+		case int64:
+			return time.Unix(epoch, 0).UTC(), nil
+		case float64:
+			sec, frac := math.Modf(epoch)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC(), nil
+		}
+		return nil, fmt.Errorf("cbor: tag 1 content should be number")
+	}
+	return v, nil
+}
+
+func (d *cborDecoder) simpleValue(info byte, n uint64) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		return float16ToFloat64(uint16(n)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(n))), nil
+	case 27:
+		return math.Float64frombits(n), nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", n)
+}
+
+func float16ToFloat64(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var v float64
+	switch exp {
+	case 0:
+		v = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			v = math.Inf(1)
+		} else {
+			v = math.NaN()
+		}
+	default:
+		v = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		v = -v
+	}
+	return v
+}
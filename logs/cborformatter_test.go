@@ -0,0 +1,72 @@
+package logs
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCBORFormatter(t *testing.T) {
+	assert.NotNil(t, NewCBORFormatter())
+}
+
+func TestCBOREncoder(t *testing.T) {
+	testCases := []struct {
+		Encode   func(e *cborEncoder)
+		Expected []byte
+	}{
+		{Encode: func(e *cborEncoder) { e.int(10) }, Expected: []byte{0x0a}},
+		{Encode: func(e *cborEncoder) { e.int(500) }, Expected: []byte{0x19, 0x01, 0xf4}},
+		{Encode: func(e *cborEncoder) { e.int(-100) }, Expected: []byte{0x38, 0x63}},
+		{Encode: func(e *cborEncoder) { e.str("IETF") }, Expected: []byte{0x64, 'I', 'E', 'T', 'F'}},
+		{Encode: func(e *cborEncoder) { e.mapHeader(2) }, Expected: []byte{0xa2}},
+		{Encode: func(e *cborEncoder) { e.arrayHeader(25) }, Expected: []byte{0x98, 0x19}},
+		{
+			Encode:   func(e *cborEncoder) { e.time(time.Unix(0, 0)) },
+			Expected: append([]byte{0xc0, 0x74}, "1970-01-01T00:00:00Z"...),
+		},
+	}
+	for _, testCase := range testCases {
+		e := &cborEncoder{}
+		testCase.Encode(e)
+		assert.Equal(t, testCase.Expected, e.bytes())
+	}
+}
+
+func TestUnmarshalCBOR(t *testing.T) {
+	testCases := []struct {
+		Input    []byte
+		Expected interface{}
+		Error    bool
+	}{
+		{Input: []byte{0xf6}, Expected: nil},
+		{Input: []byte{0xf4}, Expected: false},
+		{Input: []byte{0x1b, 0, 0, 0, 0xe8, 0xd4, 0xa5, 0x10, 0}, Expected: int64(1000000000000)},
+		{Input: []byte{0x20}, Expected: int64(-1)},
+		{Input: []byte{0xf9, 0x3e, 0x00}, Expected: 1.5},
+		{Input: []byte{0xf9, 0x7c, 0x00}, Expected: math.Inf(1)},
+		{Input: []byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}, Expected: 1.1},
+		{Input: []byte{0x42, 0x01, 0x02}, Expected: []byte{0x01, 0x02}},
+		{Input: []byte{0x82, 0x01, 0x61, 'a'}, Expected: []interface{}{int64(1), "a"}},
+		{Input: []byte{0xa1, 0x61, 'a', 0x01}, Expected: map[string]interface{}{"a": int64(1)}},
+		{Input: []byte{0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}, Expected: time.Unix(1363896240, 0).UTC()},
+		{Input: []byte{0x9f}, Error: true},
+		{Input: []byte{0x82, 0x01}, Error: true},
+		{Input: []byte{0xc0, 0x01}, Error: true},
+	}
+	for _, testCase := range testCases {
+		v, err := unmarshalCBOR(testCase.Input)
+		if testCase.Error {
+			assert.NotNil(t, err, testCase.Input)
+		} else {
+			assert.Nil(t, err, testCase.Input)
+			assert.Equal(t, testCase.Expected, v)
+		}
+	}
+}
+
+func BenchmarkCBORFormatter_Format(b *testing.B) {
+	benchmarkFormatter(b, NewCBORFormatter())
+}
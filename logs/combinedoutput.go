@@ -0,0 +1,323 @@
+package logs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultFailoverCheckInterval default interval a failed output of Failover is checked again
+const DefaultFailoverCheckInterval = 10 * time.Second
+
+// HealthChecker is implemented by outputs can tell whether their sink is available, such as network output.
+// Failover checks an output with it before writing to the output, since such outputs may buffer rows
+// without error while their sink is down.
+type HealthChecker interface {
+	HealthCheck() error
+}
+
+// healthCheck check output if it is a HealthChecker, nil otherwise
+func healthCheck(output Output) error {
+	if checker, ok := output.(HealthChecker); ok {
+		return checker.HealthCheck()
+	}
+	return nil
+}
+
+// composedOutput is implemented by outputs wrap other outputs, format returns row formatted by log instance
+// formatter for wrapped outputs not receiving content, it formats at most once.
+type composedOutput interface {
+	writeComposed(commonFields []*CommonField, content *Content, format func() []byte) error
+}
+
+// lazyFormat return a func formats row with formatter on first call
+func lazyFormat(formatter Formatter, commonFields []*CommonField, content *Content) func() []byte {
+	var buf []byte
+	return func() []byte {
+		if buf == nil {
+			buf = formatter.Format(commonFields, content)
+		}
+		return buf
+	}
+}
+
+// writeOutput write row to output as content when it receives content, otherwise as formatted row
+func writeOutput(output Output, commonFields []*CommonField, content *Content, format func() []byte) error {
+	switch o := output.(type) {
+	case composedOutput:
+		return o.writeComposed(commonFields, content, format)
+	case ContentOutput:
+		return o.WriteContent(commonFields, content)
+	}
+	_, err := output.Write(format())
+	return err
+}
+
+// joinErrors join errors of wrapped outputs with their index, nil when there is no error
+func joinErrors(name string, errs map[int]error, n int) error {
+	var messages []string
+	for i := 0; i < n; i++ {
+		if err, ok := errs[i]; ok {
+			messages = append(messages, fmt.Sprintf("%s output %d error: %s", name, i, err))
+		}
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(messages, "; "))
+}
+
+// closeWrapped close wrapped outputs are io.Closer, errors are reported with their index
+func closeWrapped(name string, outputs []Output) error {
+	errs := map[int]error{}
+	for i, output := range outputs {
+		if closer, ok := output.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs[i] = err
+			}
+		}
+	}
+	return joinErrors(name, errs, len(outputs))
+}
+
+// FailoverConfig config of NewFailoverOutput
+type FailoverConfig struct {
+	// CheckInterval min interval a failed output is checked or written again, default is DefaultFailoverCheckInterval
+	CheckInterval time.Duration
+}
+
+// Failover create a failover output with default config, see NewFailoverOutput
+func Failover(primary Output, fallbacks ...Output) Output {
+	return NewFailoverOutput(FailoverConfig{}, primary, fallbacks...)
+}
+
+// NewFailoverOutput create a output writes a row to the first available output of primary and fallbacks.
+// An output is failed when its health check, Write or Flush returns error, and the row is written to next output.
+// Outputs implement HealthChecker are checked before every write, so rows are not buffered by a network
+// output while it is disconnected. A failed output is available again after CheckInterval and its check passes.
+// Outputs not need level of a row are skipped. Rows written with Write are not skipped by level.
+func NewFailoverOutput(config FailoverConfig, primary Output, fallbacks ...Output) Output {
+	if config.CheckInterval <= 0 {
+		config.CheckInterval = DefaultFailoverCheckInterval
+	}
+	outputs := append([]Output{primary}, fallbacks...)
+	return &failoverOutput{
+		config:   config,
+		outputs:  outputs,
+		failedAt: make([]time.Time, len(outputs)),
+	}
+}
+
+type failoverOutput struct {
+	config  FailoverConfig
+	outputs []Output
+
+	mu sync.Mutex
+	// failedAt when outputs failed, zero for available outputs
+	failedAt []time.Time
+}
+
+func (o *failoverOutput) IsLevelNeedRecord(s Severity) bool {
+	for _, output := range o.outputs {
+		if output.IsLevelNeedRecord(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *failoverOutput) Write(p []byte) (int, error) {
+	err := o.write(nil, func(output Output) error {
+		_, err := output.Write(p)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteContent write content, it is formatted by default formatter for outputs not receiving content
+func (o *failoverOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	return o.writeComposed(commonFields, content, lazyFormat(defaultFormatter(), commonFields, content))
+}
+
+func (o *failoverOutput) writeComposed(commonFields []*CommonField, content *Content, format func() []byte) error {
+	return o.write(content, func(output Output) error {
+		return writeOutput(output, commonFields, content, format)
+	})
+}
+
+func (o *failoverOutput) write(content *Content, write func(output Output) error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	errs := map[int]error{}
+	for i, output := range o.outputs {
+		if content != nil && !output.IsLevelNeedRecord(content.Headers.Level) {
+			continue
+		}
+		if !o.available(i) {
+			continue
+		}
+		err := healthCheck(output)
+		if err == nil {
+			if err = write(output); err == nil {
+				return nil
+			}
+		}
+		errs[i] = err
+		o.failedAt[i] = timeNow()
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no failover output is available")
+	}
+	return joinErrors("failover", errs, len(o.outputs))
+}
+
+// available whether output i can be checked and written, it must be called with mu held.
+func (o *failoverOutput) available(i int) bool {
+	if o.failedAt[i].IsZero() {
+		return true
+	}
+	if timeNow().Sub(o.failedAt[i]) < o.config.CheckInterval {
+		return false
+	}
+	o.failedAt[i] = time.Time{}
+	return true
+}
+
+// Flush flush all outputs, outputs failed to flush are failed over.
+func (o *failoverOutput) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	errs := map[int]error{}
+	for i, output := range o.outputs {
+		if err := output.Flush(); err != nil {
+			errs[i] = err
+			o.failedAt[i] = timeNow()
+		}
+	}
+	return joinErrors("failover", errs, len(o.outputs))
+}
+
+// Close close outputs are io.Closer
+func (o *failoverOutput) Close() error {
+	return closeWrapped("failover", o.outputs)
+}
+
+// Tee create a output writes a row to all outputs need its level.
+// A row is written to every output even some of them fail, errors are reported with index of outputs.
+func Tee(outputs ...Output) Output {
+	return &teeOutput{outputs: outputs}
+}
+
+type teeOutput struct {
+	outputs []Output
+}
+
+func (o *teeOutput) IsLevelNeedRecord(s Severity) bool {
+	for _, output := range o.outputs {
+		if output.IsLevelNeedRecord(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write write a formatted row to all outputs
+func (o *teeOutput) Write(p []byte) (int, error) {
+	errs := map[int]error{}
+	for i, output := range o.outputs {
+		if _, err := output.Write(p); err != nil {
+			errs[i] = err
+		}
+	}
+	if err := joinErrors("tee", errs, len(o.outputs)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteContent write content, it is formatted by default formatter for outputs not receiving content
+func (o *teeOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	return o.writeComposed(commonFields, content, lazyFormat(defaultFormatter(), commonFields, content))
+}
+
+func (o *teeOutput) writeComposed(commonFields []*CommonField, content *Content, format func() []byte) error {
+	errs := map[int]error{}
+	for i, output := range o.outputs {
+		if !output.IsLevelNeedRecord(content.Headers.Level) {
+			continue
+		}
+		if err := writeOutput(output, commonFields, content, format); err != nil {
+			errs[i] = err
+		}
+	}
+	return joinErrors("tee", errs, len(o.outputs))
+}
+
+func (o *teeOutput) Flush() error {
+	errs := map[int]error{}
+	for i, output := range o.outputs {
+		if err := output.Flush(); err != nil {
+			errs[i] = err
+		}
+	}
+	return joinErrors("tee", errs, len(o.outputs))
+}
+
+// Close close outputs are io.Closer
+func (o *teeOutput) Close() error {
+	return closeWrapped("tee", o.outputs)
+}
+
+// Filter create a output writes rows to output only when predicate returns true for their content.
+// Rows written with Write have no content, they are not filtered.
+func Filter(output Output, predicate func(content *Content) bool) Output {
+	return &filterOutput{output: output, predicate: predicate}
+}
+
+type filterOutput struct {
+	output    Output
+	predicate func(content *Content) bool
+}
+
+func (o *filterOutput) IsLevelNeedRecord(s Severity) bool {
+	return o.output.IsLevelNeedRecord(s)
+}
+
+func (o *filterOutput) Write(p []byte) (int, error) {
+	return o.output.Write(p)
+}
+
+// WriteContent write content, it is formatted by default formatter when output does not receive content
+func (o *filterOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	return o.writeComposed(commonFields, content, lazyFormat(defaultFormatter(), commonFields, content))
+}
+
+func (o *filterOutput) writeComposed(commonFields []*CommonField, content *Content, format func() []byte) error {
+	if !o.predicate(content) {
+		return nil
+	}
+	return writeOutput(o.output, commonFields, content, format)
+}
+
+func (o *filterOutput) Flush() error {
+	return o.output.Flush()
+}
+
+// HealthCheck check output if it is a HealthChecker
+func (o *filterOutput) HealthCheck() error {
+	return healthCheck(o.output)
+}
+
+// Close close output if it is an io.Closer
+func (o *filterOutput) Close() error {
+	if closer, ok := o.output.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package logs
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCombinedContent(level Severity, message string) *Content {
+	return &Content{Headers: MessageHeader{Level: level, Time: time.Unix(1605884645, 0)}, Message: message}
+}
+
+func TestFailover(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	now := time.Unix(1605884645, 0)
+	timeNow = func() time.Time {
+		return now
+	}
+	primary := &testContentSinkOutput{}
+	fallback := &testContentSinkOutput{}
+	output := Failover(primary, fallback).(ContentOutput)
+
+	assert.Nil(t, output.WriteContent(nil, testCombinedContent(InfoLog, "a")))
+	primary.setDown(true)
+	// row failed on primary is written to fallback
+	assert.Nil(t, output.WriteContent(nil, testCombinedContent(InfoLog, "b")))
+	now = now.Add(DefaultFailoverCheckInterval - time.Second)
+	primary.setDown(false)
+	assert.Nil(t, output.WriteContent(nil, testCombinedContent(InfoLog, "c")))
+	// primary is written again after check interval when its health check passes
+	primary.setDown(true)
+	now = now.Add(time.Second)
+	assert.Nil(t, output.WriteContent(nil, testCombinedContent(InfoLog, "d")))
+	primary.setDown(false)
+	assert.Nil(t, output.WriteContent(nil, testCombinedContent(InfoLog, "e")))
+	now = now.Add(DefaultFailoverCheckInterval)
+	assert.Nil(t, output.WriteContent(nil, testCombinedContent(InfoLog, "f")))
+
+	assert.Nil(t, output.Flush())
+	assert.Equal(t, []string{"INFO a", "INFO f"}, primary.received())
+	assert.Equal(t, []string{"INFO b", "INFO c", "INFO d", "INFO e"}, fallback.received())
+
+	// output failed to flush is failed over
+	output.WriteContent(nil, testCombinedContent(InfoLog, "g"))
+	primary.setDown(true)
+	err := output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "failover output 0 error: sink is down", err.Error())
+	}
+	primary.setDown(false)
+	output.WriteContent(nil, testCombinedContent(InfoLog, "h"))
+	assert.Nil(t, output.Flush())
+	assert.Equal(t, []string{"INFO b", "INFO c", "INFO d", "INFO e", "INFO h"}, fallback.received())
+
+	fallback.setDown(true)
+	now = now.Add(DefaultFailoverCheckInterval)
+	primary.setDown(true)
+	// primary failed its health check, fallback failed to write
+	err = output.WriteContent(nil, testCombinedContent(InfoLog, "i"))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "failover output 0 error: sink is down; failover output 1 error: sink is down", err.Error())
+	}
+	err = output.WriteContent(nil, testCombinedContent(InfoLog, "j"))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "no failover output is available", err.Error())
+	}
+}
+
+func TestFailover_network(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	now := time.Now()
+	timeNow = func() time.Time {
+		return now
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	network, err := NewNetworkOutput(AllSeverities, NetworkConfig{Network: "tcp", Address: address, MinBackoff: time.Second})
+	assert.Nil(t, err)
+	defer network.(io.Closer).Close()
+	fallback := &testContentSinkOutput{}
+	// health check is passed through formatted output
+	output := Failover(NewFormattedOutput(network, NewStringFormatter("{MESSAGE}", "", false)), fallback).(ContentOutput)
+
+	// rows are not buffered by disconnected network output
+	assert.Nil(t, output.WriteContent(nil, testCombinedContent(InfoLog, "a")))
+	assert.Nil(t, output.WriteContent(nil, testCombinedContent(InfoLog, "b")))
+	assert.Nil(t, output.Flush())
+	assert.Equal(t, []string{"INFO a", "INFO b"}, fallback.received())
+
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Skipf("listen %s again error %s", address, err)
+	}
+	defer listener.Close()
+	lines := testLineServer(t, listener, nil)
+	now = now.Add(DefaultFailoverCheckInterval)
+	assert.Nil(t, output.WriteContent(nil, testCombinedContent(InfoLog, "c")))
+	assert.Nil(t, output.Flush())
+	assert.Equal(t, "c\n", testReceive(t, lines))
+	assert.Equal(t, 2, len(fallback.received()))
+}
+
+func TestFailover_level(t *testing.T) {
+	primary := &testContentSinkOutput{testSinkOutput{levels: []Severity{ErrorLog}}}
+	fallback := &testContentSinkOutput{testSinkOutput{levels: []Severity{InfoLog, ErrorLog}}}
+	output := Failover(primary, fallback)
+	assert.True(t, output.IsLevelNeedRecord(InfoLog))
+	assert.False(t, output.IsLevelNeedRecord(DebugLog))
+	output.(ContentOutput).WriteContent(nil, testCombinedContent(InfoLog, "a"))
+	output.(ContentOutput).WriteContent(nil, testCombinedContent(ErrorLog, "b"))
+	assert.Nil(t, output.Flush())
+	assert.Equal(t, []string{"ERROR b"}, primary.received())
+	assert.Equal(t, []string{"INFO a"}, fallback.received())
+}
+
+func TestTee(t *testing.T) {
+	errorOnly := &testContentSinkOutput{testSinkOutput{levels: []Severity{ErrorLog}}}
+	all := &testContentSinkOutput{}
+	down := &testContentSinkOutput{testSinkOutput{down: true}}
+	output := Tee(errorOnly, all, down)
+	assert.True(t, output.IsLevelNeedRecord(DebugLog))
+
+	err := output.(ContentOutput).WriteContent(nil, testCombinedContent(InfoLog, "a"))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "tee output 2 error: sink is down", err.Error())
+	}
+	output.(ContentOutput).WriteContent(nil, testCombinedContent(ErrorLog, "b"))
+	n, err := output.Write([]byte("raw\n"))
+	assert.Equal(t, 0, n)
+	assert.NotNil(t, err)
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "tee output 2 error: sink is down", err.Error())
+	}
+	assert.Equal(t, []string{"ERROR b", "raw\n"}, errorOnly.received())
+	assert.Equal(t, []string{"INFO a", "ERROR b", "raw\n"}, all.received())
+}
+
+func TestFilter(t *testing.T) {
+	sink := &testContentSinkOutput{testSinkOutput{levels: []Severity{InfoLog}}}
+	output := Filter(sink, func(content *Content) bool {
+		return strings.HasPrefix(content.Message, "keep")
+	})
+	assert.True(t, output.IsLevelNeedRecord(InfoLog))
+	assert.False(t, output.IsLevelNeedRecord(ErrorLog))
+	output.(ContentOutput).WriteContent(nil, testCombinedContent(InfoLog, "keep a"))
+	output.(ContentOutput).WriteContent(nil, testCombinedContent(InfoLog, "drop b"))
+	output.Write([]byte("raw\n"))
+	assert.Nil(t, output.Flush())
+	assert.Equal(t, []string{"INFO keep a", "raw\n"}, sink.received())
+}
+
+func TestCombinedOutput_logging(t *testing.T) {
+	file := &testSinkOutput{}
+	content := &testContentSinkOutput{}
+	fallback := &testSinkOutput{}
+	output := Tee(
+		Filter(file, func(content *Content) bool {
+			for _, field := range content.Fields {
+				if field.Key() == "audit" {
+					return true
+				}
+			}
+			return false
+		}),
+		Failover(content, fallback),
+	)
+	l := NewLogging(WithOutput(output), WithCommonField("service", "order"), WithFormatter(NewStringFormatter("{LEVEL} {MESSAGE} {FIELDS}", "", false)))
+	l.Info(context.Background(), "login", String("audit", "1"))
+	l.Warning(context.Background(), "slow")
+	assert.Nil(t, l.Sync())
+
+	// plain outputs get rows formatted by log instance formatter
+	assert.Equal(t, []string{"INFO login {audit:1}\n"}, file.received())
+	assert.Equal(t, []string{"INFO login service=order audit=1", "WARNING slow service=order"}, content.received())
+	assert.Equal(t, 0, len(fallback.received()))
+}
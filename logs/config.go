@@ -0,0 +1,442 @@
+package logs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Config describe a log instance, it can be loaded by LoadConfigFile or LoadConfigEnv.
+//
+//	min_level: info
+//	vmodule: storage/*=debug,http=warning
+//	common_fields: {service: order}
+//	formatter: {type: json}
+//	outputs:
+//	  - type: stdout
+//	    formatter: {type: console}
+//	  - type: rotating
+//	    path: /var/log/order.log
+//	    levels: [warning, error, fatal]
+//	    max_size: 104857600
+//	    max_backups: 7
+//	  - type: network
+//	    network: tcp
+//	    address: collector:5170
+//	    tls: {ca_file: /etc/ssl/collector-ca.pem}
+type Config struct {
+	// MinLevel rows below it are dropped, default is debug
+	MinLevel string `json:"min_level" yaml:"min_level"`
+	// VModule per caller file min levels such as storage/*=debug,http=warning, see ParseVModule
+	VModule string `json:"vmodule" yaml:"vmodule"`
+	// DirHeader whether add dir in log file header
+	DirHeader bool `json:"dir_header" yaml:"dir_header"`
+	// ChannelSize max buffered rows, 0 is default 1000
+	ChannelSize int `json:"channel_size" yaml:"channel_size"`
+	// CommonFields logged with every row in key order, nil logs host name
+	CommonFields map[string]string `json:"common_fields" yaml:"common_fields"`
+	// Formatter used by outputs without their own formatter, nil is the default string formatter
+	Formatter *FormatterConfig `json:"formatter" yaml:"formatter"`
+	// Outputs nil logs to stdout
+	Outputs []OutputConfig `json:"outputs" yaml:"outputs"`
+}
+
+// FormatterConfig describe a formatter
+type FormatterConfig struct {
+	// Type one of string, json, console, template, msgpack, cbor
+	Type string `json:"type" yaml:"type"`
+	// Template template of string and template formatter, empty is the formatter default template
+	Template string `json:"template" yaml:"template"`
+	// TimeFormat time layout of string, console and template formatter, empty is "2006-01-02 15:04:05"
+	TimeFormat string `json:"time_format" yaml:"time_format"`
+	// UTC whether format time in UTC
+	UTC bool `json:"utc" yaml:"utc"`
+	// Color whether console formatter colors rows, nil detects whether stdout or stderr output is a terminal,
+	// rows of other outputs are not colored
+	Color *bool `json:"color" yaml:"color"`
+}
+
+// OutputConfig describe an output
+type OutputConfig struct {
+	// Type one of stdout, stderr, file, rotating, syslog, network
+	Type string `json:"type" yaml:"type"`
+	// Levels levels output records, empty is all levels
+	Levels []string `json:"levels" yaml:"levels"`
+	// Formatter formatter of this output, nil uses Config.Formatter
+	Formatter *FormatterConfig `json:"formatter" yaml:"formatter"`
+	// Path file path of file and rotating output
+	Path string `json:"path" yaml:"path"`
+	// MaxSize max bytes of rotating output file, 0 is DefaultRotatingMaxSize
+	MaxSize int64 `json:"max_size" yaml:"max_size"`
+	// MaxBackups max rotated files kept, 0 is unlimited
+	MaxBackups int `json:"max_backups" yaml:"max_backups"`
+	// MaxAge max age of rotated files kept such as 168h, empty is unlimited
+	MaxAge string `json:"max_age" yaml:"max_age"`
+	// Network network of syslog(empty is local syslog) and network(tcp, udp, unix) output
+	Network string `json:"network" yaml:"network"`
+	// Address address of syslog and network output
+	Address string `json:"address" yaml:"address"`
+	// Tag syslog tag, empty is program name
+	Tag string `json:"tag" yaml:"tag"`
+	// Framing framing of network output, newline(default), length or null
+	Framing string `json:"framing" yaml:"framing"`
+	// BufferSize max bytes buffered by network output while disconnected, 0 is DefaultNetworkBufferSize
+	BufferSize int `json:"buffer_size" yaml:"buffer_size"`
+	// TLS connect network output with TLS when it is not nil
+	TLS *TLSConfig `json:"tls" yaml:"tls"`
+}
+
+// TLSConfig describe TLS of a client connection
+type TLSConfig struct {
+	// CAFile PEM CA certificates verify server, empty uses system CAs
+	CAFile string `json:"ca_file" yaml:"ca_file"`
+	// CertFile and KeyFile PEM client certificate and key, empty is no client certificate
+	CertFile string `json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `json:"key_file" yaml:"key_file"`
+	// ServerName verified server name, empty is host of address
+	ServerName string `json:"server_name" yaml:"server_name"`
+	// InsecureSkipVerify whether skip verifying server certificate
+	InsecureSkipVerify bool `json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+}
+
+var formatterTypes = []string{"string", "json", "console", "template", "msgpack", "cbor"}
+
+var outputTypes = []string{"stdout", "stderr", "file", "rotating", "syslog", "network"}
+
+// Validate check config, all problems are reported with their config path, such as
+// outputs[1].levels[0]: unknown log level "verbose", should be one of debug, info, warning, error, fatal
+func (c *Config) Validate() error {
+	var problems []string
+	report := func(path string, format string, args ...interface{}) {
+		problems = append(problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if c.MinLevel != "" {
+		if _, err := ParseSeverity(c.MinLevel); err != nil {
+			report("min_level", "%s", err)
+		}
+	}
+	if _, err := ParseVModule(c.VModule); err != nil {
+		report("vmodule", "%s", err)
+	}
+	if c.ChannelSize < 0 {
+		report("channel_size", "should not be negative, got %d", c.ChannelSize)
+	}
+	for key := range c.CommonFields {
+		if key == "" {
+			report("common_fields", "key should not be empty")
+		}
+	}
+	if c.Formatter != nil {
+		c.Formatter.validate("formatter", report)
+	}
+	for i, output := range c.Outputs {
+		output.validate(fmt.Sprintf("outputs[%d]", i), report)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid log config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (c *FormatterConfig) validate(path string, report func(path string, format string, args ...interface{})) {
+	switch c.Type {
+	case "string", "json", "console", "msgpack", "cbor":
+	case "template":
+		if _, err := NewTemplateFormatter(c.template(), c.timeFormat(), c.UTC); err != nil {
+			report(path+".template", "%s", err)
+		}
+	case "":
+		report(path+".type", "is required, should be one of %s", strings.Join(formatterTypes, ", "))
+	default:
+		report(path+".type", "unknown formatter type %q, should be one of %s", c.Type, strings.Join(formatterTypes, ", "))
+	}
+}
+
+func (c *OutputConfig) validate(path string, report func(path string, format string, args ...interface{})) {
+	for i, level := range c.Levels {
+		if _, err := ParseSeverity(level); err != nil {
+			report(fmt.Sprintf("%s.levels[%d]", path, i), "%s", err)
+		}
+	}
+	if c.Formatter != nil {
+		c.Formatter.validate(path+".formatter", report)
+	}
+	switch c.Type {
+	case "stdout", "stderr":
+	case "file", "rotating":
+		if c.Path == "" {
+			report(path+".path", "is required for %s output", c.Type)
+		}
+		if c.MaxSize < 0 {
+			report(path+".max_size", "should not be negative, got %d", c.MaxSize)
+		}
+		if c.MaxBackups < 0 {
+			report(path+".max_backups", "should not be negative, got %d", c.MaxBackups)
+		}
+		if c.MaxAge != "" {
+			if d, err := time.ParseDuration(c.MaxAge); err != nil || d < 0 {
+				report(path+".max_age", "invalid duration %q, should be like 168h", c.MaxAge)
+			}
+		}
+	case "syslog":
+		if c.Network != "" && c.Address == "" {
+			report(path+".address", "is required when network is set")
+		}
+	case "network":
+		switch c.Network {
+		case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6", "unix", "unixgram":
+		case "":
+			report(path+".network", "is required for network output")
+		default:
+			report(path+".network", "unknown network %q, should be one of tcp, udp, unix", c.Network)
+		}
+		if c.Address == "" {
+			report(path+".address", "is required for network output")
+		}
+		switch c.Framing {
+		case "", "newline", "length", "null":
+		default:
+			report(path+".framing", "unknown framing %q, should be one of newline, length, null", c.Framing)
+		}
+		if c.BufferSize < 0 {
+			report(path+".buffer_size", "should not be negative, got %d", c.BufferSize)
+		}
+		if c.TLS != nil {
+			switch c.Network {
+			case "udp", "udp4", "udp6", "unixgram":
+				report(path+".tls", "is not supported for %s network", c.Network)
+			}
+			if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+				report(path+".tls", "cert_file and key_file should be set together")
+			}
+		}
+	case "":
+		report(path+".type", "is required, should be one of %s", strings.Join(outputTypes, ", "))
+	default:
+		report(path+".type", "unknown output type %q, should be one of %s", c.Type, strings.Join(outputTypes, ", "))
+	}
+}
+
+func (c *FormatterConfig) template() string {
+	if c.Template != "" {
+		return c.Template
+	}
+	if c.Type == "template" {
+		return DefaultTemplateFormatTemplate
+	}
+	return DefaultStringFormatTemplate
+}
+
+func (c *FormatterConfig) timeFormat() string {
+	if c.TimeFormat != "" {
+		return c.TimeFormat
+	}
+	return defaultTimeHeaderFormat()
+}
+
+// build create formatter, config should be validated. Console formatter colors rows when file is a terminal
+// and Color is nil, file is nil for outputs not writing to a terminal.
+func (c *FormatterConfig) build(file *os.File) (Formatter, error) {
+	switch c.Type {
+	case "json":
+		return NewJSONFormatter(), nil
+	case "console":
+		f := NewConsoleFormatter(c.timeFormat(), c.UTC)
+		f.Color = ColorEnabled(file)
+		if c.Color != nil {
+			f.Color = *c.Color
+		}
+		return f, nil
+	case "template":
+		return NewTemplateFormatter(c.template(), c.timeFormat(), c.UTC)
+	case "msgpack":
+		return NewMsgPackFormatter(), nil
+	case "cbor":
+		return NewCBORFormatter(), nil
+	}
+	return NewStringFormatter(c.template(), c.timeFormat(), c.UTC), nil
+}
+
+// build create client tls.Config, certificate files are loaded here.
+func (c *TLSConfig) build() (*tls.Config, error) {
+	config := &tls.Config{ServerName: c.ServerName, InsecureSkipVerify: c.InsecureSkipVerify}
+	if c.CAFile != "" {
+		pem, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read TLS CA file error: %s", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in TLS CA file %s", c.CAFile)
+		}
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load TLS certificate error: %s", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func (c *OutputConfig) levels() []Severity {
+	if len(c.Levels) == 0 {
+		return AllSeverities
+	}
+	levels := make([]Severity, 0, len(c.Levels))
+	for _, level := range c.Levels {
+		s, _ := ParseSeverity(level)
+		levels = append(levels, s)
+	}
+	return levels
+}
+
+// colorFile file checked whether rows are written to a terminal, nil for outputs not writing to stdout or stderr
+func (c *OutputConfig) colorFile() *os.File {
+	switch c.Type {
+	case "stdout":
+		return os.Stdout
+	case "stderr":
+		return os.Stderr
+	}
+	return nil
+}
+
+// build create output, config should be validated. Global console formatter detecting color is built for
+// every output has no formatter, since whether rows are colored depends on the output.
+func (c *OutputConfig) build(global *FormatterConfig) (Output, error) {
+	levels := c.levels()
+	formatterConfig := c.Formatter
+	if formatterConfig == nil && global != nil && global.Type == "console" && global.Color == nil && c.Type != "syslog" {
+		formatterConfig = global
+	}
+	var formatter Formatter
+	if formatterConfig != nil {
+		var err error
+		if formatter, err = formatterConfig.build(c.colorFile()); err != nil {
+			return nil, err
+		}
+	}
+
+	var output Output
+	var err error
+	switch c.Type {
+	case "stdout":
+		output = NewStdOutOutput(levels)
+	case "stderr":
+		output = NewOutPut(levels, os.Stderr)
+	case "file":
+		output, err = NewFileOutput(levels, c.Path)
+	case "rotating":
+		maxAge, _ := time.ParseDuration(c.MaxAge)
+		output, err = NewRotatingFileOutput(levels, RotatingFileConfig{
+			Filename:   c.Path,
+			MaxSize:    c.MaxSize,
+			MaxBackups: c.MaxBackups,
+			MaxAge:     maxAge,
+		})
+	case "syslog":
+		// syslog output formats rows itself
+		return NewSyslogOutput(levels, c.Network, c.Address, c.Tag, formatter)
+	case "network":
+		config := NetworkConfig{Network: c.Network, Address: c.Address, BufferSize: c.BufferSize}
+		switch c.Framing {
+		case "length":
+			config.Framing = LengthPrefixFraming
+		case "null":
+			config.Framing = NullFraming
+		}
+		if c.TLS != nil {
+			if config.TLS, err = c.TLS.build(); err != nil {
+				return nil, err
+			}
+		}
+		output, err = NewNetworkOutput(levels, config)
+	default:
+		return nil, fmt.Errorf("unknown output type %q", c.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if formatter != nil {
+		output = NewFormattedOutput(output, formatter)
+	}
+	return output, nil
+}
+
+// options validate config and convert it to options, outputs are opened here.
+func (c *Config) options() ([]Option, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	var opts []Option
+	if c.MinLevel != "" {
+		minLevel, _ := ParseSeverity(c.MinLevel)
+		opts = append(opts, WithMinLevel(minLevel))
+	}
+	vModule, _ := ParseVModule(c.VModule)
+	opts = append(opts, WithVModule(vModule))
+	opts = append(opts, WithAddDirHeader(c.DirHeader))
+	if c.ChannelSize > 0 {
+		opts = append(opts, WithMaxLogChanNum(c.ChannelSize))
+	}
+	keys := make([]string, 0, len(c.CommonFields))
+	for key := range c.CommonFields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		opts = append(opts, WithCommonField(key, c.CommonFields[key]))
+	}
+	if c.Formatter != nil {
+		formatter, err := c.Formatter.build(nil)
+		if err != nil {
+			return nil, fmt.Errorf("formatter: %s", err)
+		}
+		opts = append(opts, WithFormatter(formatter))
+	}
+	outputs := make([]Output, 0, len(c.Outputs))
+	for i := range c.Outputs {
+		output, err := c.Outputs[i].build(c.Formatter)
+		if err != nil {
+			closeOutputs(outputs)
+			return nil, fmt.Errorf("outputs[%d]: %s", i, err)
+		}
+		outputs = append(outputs, output)
+	}
+	for _, output := range outputs {
+		opts = append(opts, WithOutput(output))
+	}
+	return opts, nil
+}
+
+// closeOutputs close outputs implement io.Closer
+func closeOutputs(outputs []Output) []error {
+	var errs []error
+	for _, output := range outputs {
+		if closer, ok := output.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+// NewLoggingFromConfig create log instance from config, return error when config is invalid or an output can not be opened.
+func NewLoggingFromConfig(config *Config) (*logging, error) {
+	opts, err := config.options()
+	if err != nil {
+		return nil, err
+	}
+	return NewLogging(opts...), nil
+}
@@ -0,0 +1,287 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	testCases := []struct {
+		Input    Config
+		Expected string
+	}{
+		{Input: Config{}, Expected: ""},
+		{
+			Input: Config{
+				MinLevel:     "warn",
+				VModule:      "storage/*=debug,http=error",
+				CommonFields: map[string]string{"service": "order"},
+				Formatter:    &FormatterConfig{Type: "console"},
+				Outputs: []OutputConfig{
+					{Type: "stdout", Levels: []string{"info", "error"}},
+					{Type: "rotating", Path: "/var/log/app.log", MaxAge: "168h", Formatter: &FormatterConfig{Type: "json"}},
+					{Type: "syslog"},
+					{Type: "network", Network: "tcp", Address: "127.0.0.1:5170"},
+				},
+			},
+			Expected: "",
+		},
+		{
+			Input:    Config{MinLevel: "verbose"},
+			Expected: `invalid log config: min_level: unknown log level "verbose", should be one of debug, info, warning, error, fatal`,
+		},
+		{
+			Input:    Config{VModule: "storage/*"},
+			Expected: `invalid log config: vmodule: invalid vmodule item "storage/*", should be pattern=level`,
+		},
+		{
+			Input:    Config{ChannelSize: -1, CommonFields: map[string]string{"": "value"}},
+			Expected: "invalid log config: channel_size: should not be negative, got -1; common_fields: key should not be empty",
+		},
+		{
+			Input:    Config{Formatter: &FormatterConfig{Type: "xml"}},
+			Expected: `invalid log config: formatter.type: unknown formatter type "xml", should be one of string, json, console, template, msgpack, cbor`,
+		},
+		{
+			Input:    Config{Formatter: &FormatterConfig{Type: "template", Template: "{{.Missing}}"}},
+			Expected: "invalid log config: formatter.template: execute log template error",
+		},
+		{
+			Input:    Config{Outputs: []OutputConfig{{Type: "stdout"}, {Type: "fiel", Levels: []string{"info", "verbose"}}}},
+			Expected: `invalid log config: outputs[1].levels[1]: unknown log level "verbose", should be one of debug, info, warning, error, fatal; outputs[1].type: unknown output type "fiel", should be one of stdout, stderr, file, rotating, syslog, network`,
+		},
+		{
+			Input:    Config{Outputs: []OutputConfig{{}}},
+			Expected: "invalid log config: outputs[0].type: is required, should be one of stdout, stderr, file, rotating, syslog, network",
+		},
+		{
+			Input:    Config{Outputs: []OutputConfig{{Type: "rotating", MaxSize: -1, MaxBackups: -1, MaxAge: "7d"}}},
+			Expected: `invalid log config: outputs[0].path: is required for rotating output; outputs[0].max_size: should not be negative, got -1; outputs[0].max_backups: should not be negative, got -1; outputs[0].max_age: invalid duration "7d", should be like 168h`,
+		},
+		{
+			Input:    Config{Outputs: []OutputConfig{{Type: "network", Network: "http"}, {Type: "syslog", Network: "udp"}}},
+			Expected: `invalid log config: outputs[0].network: unknown network "http", should be one of tcp, udp, unix; outputs[0].address: is required for network output; outputs[1].address: is required when network is set`,
+		},
+		{
+			Input: Config{Outputs: []OutputConfig{
+				{Type: "network", Network: "tcp", Address: "127.0.0.1:5170", Framing: "length", TLS: &TLSConfig{CAFile: "ca.pem"}},
+				{Type: "network", Network: "udp", Address: "127.0.0.1:5170", Framing: "json", BufferSize: -1, TLS: &TLSConfig{CertFile: "cert.pem"}},
+			}},
+			Expected: `invalid log config: outputs[1].framing: unknown framing "json", should be one of newline, length, null; outputs[1].buffer_size: should not be negative, got -1; outputs[1].tls: is not supported for udp network; outputs[1].tls: cert_file and key_file should be set together`,
+		},
+		{
+			Input:    Config{Outputs: []OutputConfig{{Type: "stdout", Formatter: &FormatterConfig{}}}},
+			Expected: "invalid log config: outputs[0].formatter.type: is required, should be one of string, json, console, template, msgpack, cbor",
+		},
+	}
+	for _, testCase := range testCases {
+		err := testCase.Input.Validate()
+		if testCase.Expected == "" {
+			assert.Nil(t, err)
+			continue
+		}
+		if assert.NotNil(t, err) {
+			assert.True(t, strings.HasPrefix(err.Error(), testCase.Expected), err.Error())
+		}
+	}
+}
+
+func TestFormatterConfig_build(t *testing.T) {
+	color := true
+	testCases := []struct {
+		Input    FormatterConfig
+		Expected Formatter
+	}{
+		{Input: FormatterConfig{Type: "string"}, Expected: NewStringFormatter(DefaultStringFormatTemplate, defaultTimeHeaderFormat(), false)},
+		{Input: FormatterConfig{Type: "string", Template: "{MESSAGE}", TimeFormat: "15:04", UTC: true}, Expected: NewStringFormatter("{MESSAGE}", "15:04", true)},
+		{Input: FormatterConfig{Type: "json"}, Expected: NewJSONFormatter()},
+		{Input: FormatterConfig{Type: "msgpack"}, Expected: NewMsgPackFormatter()},
+		{Input: FormatterConfig{Type: "cbor"}, Expected: NewCBORFormatter()},
+		{Input: FormatterConfig{Type: "console", Color: &color}, Expected: func() Formatter {
+			f := NewConsoleFormatter(defaultTimeHeaderFormat(), false)
+			f.Color = true
+			return f
+		}()},
+	}
+	for _, testCase := range testCases {
+		formatter, err := testCase.Input.build(nil)
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.Expected, formatter)
+	}
+
+	formatter, err := (&FormatterConfig{Type: "template"}).build(nil)
+	assert.Nil(t, err)
+	assert.IsType(t, &TemplateFormatter{}, formatter)
+}
+
+func TestOutputConfig_build_color(t *testing.T) {
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		osGetenv = os.Getenv
+	}()
+	osGetenv = func(string) string {
+		return ""
+	}
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	// character device is detected as terminal
+	os.Stdout, err = os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	assert.Nil(t, err)
+	defer os.Stdout.Close()
+	os.Stderr, err = os.Create(filepath.Join(dir, "stderr"))
+	assert.Nil(t, err)
+	defer os.Stderr.Close()
+
+	console := &FormatterConfig{Type: "console"}
+	testCases := []struct {
+		Input struct {
+			Output OutputConfig
+			Global *FormatterConfig
+		}
+		Expected bool
+	}{
+		{Input: struct {
+			Output OutputConfig
+			Global *FormatterConfig
+		}{Output: OutputConfig{Type: "stdout"}, Global: console}, Expected: true},
+		{Input: struct {
+			Output OutputConfig
+			Global *FormatterConfig
+		}{Output: OutputConfig{Type: "stdout", Formatter: console}}, Expected: true},
+		{Input: struct {
+			Output OutputConfig
+			Global *FormatterConfig
+		}{Output: OutputConfig{Type: "stderr", Formatter: console}}, Expected: false},
+		{Input: struct {
+			Output OutputConfig
+			Global *FormatterConfig
+		}{Output: OutputConfig{Type: "file", Path: filepath.Join(dir, "app.log"), Formatter: console}}, Expected: false},
+		{Input: struct {
+			Output OutputConfig
+			Global *FormatterConfig
+		}{Output: OutputConfig{Type: "file", Path: filepath.Join(dir, "app.log")}, Global: console}, Expected: false},
+	}
+	for _, testCase := range testCases {
+		output, err := testCase.Input.Output.build(testCase.Input.Global)
+		assert.Nil(t, err)
+		if formatted, ok := output.(*formattedOutput); assert.True(t, ok, testCase.Input.Output.Type) {
+			assert.Equal(t, testCase.Expected, formatted.formatter.(*ConsoleFormatter).Color, testCase.Input.Output.Type)
+		}
+	}
+
+	// global formatter with color set is used by log instance
+	output, err := (&OutputConfig{Type: "stdout"}).build(&FormatterConfig{Type: "console", Color: new(bool)})
+	assert.Nil(t, err)
+	_, ok := output.(*formattedOutput)
+	assert.False(t, ok)
+}
+
+func TestNewLoggingFromConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	l, err := NewLoggingFromConfig(&Config{
+		MinLevel:     "info",
+		VModule:      "other=debug",
+		DirHeader:    true,
+		ChannelSize:  10,
+		CommonFields: map[string]string{"service": "order", "region": "eu"},
+		Formatter:    &FormatterConfig{Type: "string", Template: "{LEVEL} {MESSAGE}"},
+		Outputs: []OutputConfig{
+			{Type: "file", Path: filepath.Join(dir, "all.log")},
+			{Type: "rotating", Path: filepath.Join(dir, "error.log"), Levels: []string{"error"}, Formatter: &FormatterConfig{Type: "json"}},
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, InfoLog, l.opts().minLevel)
+	assert.Equal(t, "other=debug", l.opts().vModule.String())
+	assert.True(t, l.opts().addDirHeader)
+	assert.Equal(t, 10, cap(l.contentChan))
+	assert.Equal(t, []*CommonField{NewCommonField("region", "eu"), NewCommonField("service", "order")}, l.opts().commonFields)
+
+	l.Debug(context.Background(), "dropped by min level")
+	l.Info(context.Background(), "info row")
+	l.Error(context.Background(), "error row")
+	l.Sync()
+
+	all, _ := ioutil.ReadFile(filepath.Join(dir, "all.log"))
+	assert.Equal(t, "INFO info row\nERROR error row\n", string(all))
+	errorRows, _ := ioutil.ReadFile(filepath.Join(dir, "error.log"))
+	row := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(errorRows, &row))
+	assert.Equal(t, "error row", row["message"])
+	assert.Equal(t, 1, strings.Count(string(errorRows), "\n"))
+}
+
+func TestNewLoggingFromConfig_default(t *testing.T) {
+	l, err := NewLoggingFromConfig(&Config{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(l.opts().outputs))
+	assert.Equal(t, defaultFormatter(), l.opts().formatter)
+	assert.Equal(t, defaultOptions().maxLogChanNum, cap(l.contentChan))
+}
+
+func TestNewLoggingFromConfig_network(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		received <- line
+	}()
+
+	l, err := NewLoggingFromConfig(&Config{
+		Formatter: &FormatterConfig{Type: "string", Template: "{MESSAGE}"},
+		Outputs:   []OutputConfig{{Type: "network", Network: "tcp", Address: listener.Addr().String()}},
+	})
+	assert.Nil(t, err)
+	l.Info(context.Background(), "network row")
+	assert.Nil(t, l.Sync())
+	assert.Equal(t, "network row\n", <-received)
+}
+
+func TestNewLoggingFromConfig_error(t *testing.T) {
+	_, err := NewLoggingFromConfig(&Config{MinLevel: "verbose"})
+	assert.NotNil(t, err)
+
+	_, err = NewLoggingFromConfig(&Config{Outputs: []OutputConfig{{Type: "file", Path: filepath.Join("not", "exists", "dir", "app.log")}}})
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "outputs[0]: open log file error"), err.Error())
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+	// network output dials lazily, dial error is reported by Sync
+	l, err := NewLoggingFromConfig(&Config{Outputs: []OutputConfig{{Type: "network", Network: "tcp", Address: address}}})
+	assert.Nil(t, err)
+	l.Info(context.Background(), "network row")
+	errs := l.Sync()
+	if assert.Equal(t, 1, len(errs)) {
+		assert.True(t, strings.HasPrefix(errs[0].Error(), "log network output tcp://"+address+" error: dial: "), errs[0].Error())
+	}
+
+	_, err = NewLoggingFromConfig(&Config{Outputs: []OutputConfig{{Type: "network", Network: "tcp", Address: address, TLS: &TLSConfig{CAFile: filepath.Join("not", "exists.pem")}}}})
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "outputs[0]: read TLS CA file error"), err.Error())
+	}
+}
@@ -0,0 +1,150 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseJSONConfig parse JSON config, unknown keys are rejected.
+func ParseJSONConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return nil, fmt.Errorf("parse JSON log config error: %s", err)
+	}
+	return config, nil
+}
+
+// ParseYAMLConfig parse YAML config, unknown keys are rejected.
+func ParseYAMLConfig(data []byte) (*Config, error) {
+	config := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("parse YAML log config error: %s", err)
+	}
+	return config, nil
+}
+
+// LoadConfigFile load config from a .json, .yaml or .yml file and validate it.
+func LoadConfigFile(filename string) (*Config, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read log config error: %s", err)
+	}
+	var config *Config
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		config, err = ParseJSONConfig(data)
+	case ".yaml", ".yml":
+		config, err = ParseYAMLConfig(data)
+	default:
+		return nil, fmt.Errorf("unknown log config file extension %q, should be .json, .yaml or .yml", filepath.Ext(filename))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	if err = config.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return config, nil
+}
+
+// LoadConfigEnv load config from LOGS_* environment variables.
+// LOGS_CONFIG is loaded first by LoadConfigFile when set, then other variables override it:
+//
+//	LOGS_MIN_LEVEL=info
+//	LOGS_VMODULE=storage/*=debug,http=warning
+//	LOGS_DIR_HEADER=true
+//	LOGS_CHANNEL_SIZE=5000
+//	LOGS_COMMON_FIELDS=service=order,region=eu
+//	LOGS_FORMATTER=json
+//	LOGS_OUTPUTS=stdout,rotating:/var/log/order.log,network:tcp://127.0.0.1:5170,syslog:udp://127.0.0.1:514
+func LoadConfigEnv() (*Config, error) {
+	config := &Config{}
+	if filename := osGetenv("LOGS_CONFIG"); filename != "" {
+		var err error
+		if config, err = LoadConfigFile(filename); err != nil {
+			return nil, fmt.Errorf("LOGS_CONFIG: %s", err)
+		}
+	}
+	if v := osGetenv("LOGS_MIN_LEVEL"); v != "" {
+		config.MinLevel = v
+	}
+	if v := osGetenv("LOGS_VMODULE"); v != "" {
+		config.VModule = v
+	}
+	if v := osGetenv("LOGS_DIR_HEADER"); v != "" {
+		dirHeader, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("LOGS_DIR_HEADER: invalid bool %q", v)
+		}
+		config.DirHeader = dirHeader
+	}
+	if v := osGetenv("LOGS_CHANNEL_SIZE"); v != "" {
+		channelSize, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("LOGS_CHANNEL_SIZE: invalid integer %q", v)
+		}
+		config.ChannelSize = channelSize
+	}
+	if v := osGetenv("LOGS_COMMON_FIELDS"); v != "" {
+		config.CommonFields = map[string]string{}
+		for i, pair := range strings.Split(v, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return nil, fmt.Errorf("LOGS_COMMON_FIELDS: item %d %q should be key=value", i, pair)
+			}
+			config.CommonFields[kv[0]] = kv[1]
+		}
+	}
+	if v := osGetenv("LOGS_FORMATTER"); v != "" {
+		config.Formatter = &FormatterConfig{Type: v}
+	}
+	if v := osGetenv("LOGS_OUTPUTS"); v != "" {
+		config.Outputs = nil
+		for i, item := range strings.Split(v, ",") {
+			output, err := parseEnvOutput(item)
+			if err != nil {
+				return nil, fmt.Errorf("LOGS_OUTPUTS: item %d %q %s", i, item, err)
+			}
+			config.Outputs = append(config.Outputs, output)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// parseEnvOutput parse type[:target], target is path of file and rotating output, network://address of syslog and network output.
+func parseEnvOutput(item string) (OutputConfig, error) {
+	parts := strings.SplitN(item, ":", 2)
+	output := OutputConfig{Type: parts[0]}
+	if len(parts) == 1 {
+		return output, nil
+	}
+	target := parts[1]
+	switch output.Type {
+	case "file", "rotating":
+		output.Path = target
+	case "syslog", "network":
+		network := strings.SplitN(target, "://", 2)
+		if len(network) != 2 {
+			return output, fmt.Errorf("should be %s:network://address", output.Type)
+		}
+		output.Network, output.Address = network[0], network[1]
+	default:
+		return output, fmt.Errorf("%s output does not accept target", output.Type)
+	}
+	return output, nil
+}
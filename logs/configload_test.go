@@ -0,0 +1,190 @@
+package logs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testYAMLConfig = `
+min_level: info
+dir_header: true
+channel_size: 100
+common_fields:
+  service: order
+formatter:
+  type: json
+outputs:
+  - type: stdout
+    formatter: {type: console, color: false}
+  - type: rotating
+    path: /var/log/order.log
+    levels: [warning, error, fatal]
+    max_size: 1048576
+    max_backups: 7
+    max_age: 168h
+`
+
+const testJSONConfig = `{
+  "min_level": "info",
+  "dir_header": true,
+  "channel_size": 100,
+  "common_fields": {"service": "order"},
+  "formatter": {"type": "json"},
+  "outputs": [
+    {"type": "stdout", "formatter": {"type": "console", "color": false}},
+    {"type": "rotating", "path": "/var/log/order.log", "levels": ["warning", "error", "fatal"], "max_size": 1048576, "max_backups": 7, "max_age": "168h"}
+  ]
+}`
+
+func testExpectedConfig() *Config {
+	color := false
+	return &Config{
+		MinLevel:     "info",
+		DirHeader:    true,
+		ChannelSize:  100,
+		CommonFields: map[string]string{"service": "order"},
+		Formatter:    &FormatterConfig{Type: "json"},
+		Outputs: []OutputConfig{
+			{Type: "stdout", Formatter: &FormatterConfig{Type: "console", Color: &color}},
+			{Type: "rotating", Path: "/var/log/order.log", Levels: []string{"warning", "error", "fatal"}, MaxSize: 1 << 20, MaxBackups: 7, MaxAge: "168h"},
+		},
+	}
+}
+
+func TestParseYAMLConfig(t *testing.T) {
+	config, err := ParseYAMLConfig([]byte(testYAMLConfig))
+	assert.Nil(t, err)
+	assert.Equal(t, testExpectedConfig(), config)
+
+	config, err = ParseYAMLConfig([]byte(""))
+	assert.Nil(t, err)
+	assert.Equal(t, &Config{}, config)
+
+	_, err = ParseYAMLConfig([]byte("outputs:\n  - type: stdout\n    level: info\n"))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "line 3: field level not found")
+	}
+}
+
+func TestParseJSONConfig(t *testing.T) {
+	config, err := ParseJSONConfig([]byte(testJSONConfig))
+	assert.Nil(t, err)
+	assert.Equal(t, testExpectedConfig(), config)
+
+	_, err = ParseJSONConfig([]byte(`{"outputs": [{"type": "stdout", "level": "info"}]}`))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), `unknown field "level"`)
+	}
+	_, err = ParseJSONConfig([]byte(`{"channel_size": "big"}`))
+	assert.NotNil(t, err)
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	write := func(name string, content string) string {
+		filename := filepath.Join(dir, name)
+		assert.Nil(t, ioutil.WriteFile(filename, []byte(content), 0644))
+		return filename
+	}
+
+	testCases := []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: write("logs.yaml", testYAMLConfig), Expected: ""},
+		{Input: write("logs.YML", testYAMLConfig), Expected: ""},
+		{Input: write("logs.json", testJSONConfig), Expected: ""},
+		{Input: write("logs.toml", ""), Expected: `unknown log config file extension ".toml"`},
+		{Input: filepath.Join(dir, "missing.yaml"), Expected: "read log config error"},
+		{Input: write("invalid.yaml", "min_level: verbose\n"), Expected: filepath.Join(dir, "invalid.yaml") + `: invalid log config: min_level: unknown log level "verbose"`},
+		{Input: write("broken.json", "{"), Expected: filepath.Join(dir, "broken.json") + ": parse JSON log config error"},
+	}
+	for _, testCase := range testCases {
+		config, err := LoadConfigFile(testCase.Input)
+		if testCase.Expected == "" {
+			assert.Nil(t, err)
+			assert.Equal(t, testExpectedConfig(), config)
+			continue
+		}
+		if assert.NotNil(t, err, testCase.Input) {
+			assert.True(t, strings.HasPrefix(err.Error(), testCase.Expected), err.Error())
+		}
+	}
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	defer func() {
+		osGetenv = os.Getenv
+	}()
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "logs.yaml")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(testYAMLConfig), 0644))
+
+	testCases := []struct {
+		Input    map[string]string
+		Expected *Config
+		Error    string
+	}{
+		{Input: map[string]string{}, Expected: &Config{}},
+		{Input: map[string]string{"LOGS_CONFIG": filename}, Expected: testExpectedConfig()},
+		{
+			Input: map[string]string{
+				"LOGS_CONFIG":        filename,
+				"LOGS_MIN_LEVEL":     "error",
+				"LOGS_VMODULE":       "storage/*=debug",
+				"LOGS_DIR_HEADER":    "false",
+				"LOGS_CHANNEL_SIZE":  "5000",
+				"LOGS_COMMON_FIELDS": "service=payment,region=eu=1",
+				"LOGS_FORMATTER":     "console",
+				"LOGS_OUTPUTS":       "stderr,file:/tmp/a.log,rotating:/tmp/b.log,syslog:udp://127.0.0.1:514,network:tcp://127.0.0.1:5170",
+			},
+			Expected: &Config{
+				MinLevel:     "error",
+				VModule:      "storage/*=debug",
+				ChannelSize:  5000,
+				CommonFields: map[string]string{"service": "payment", "region": "eu=1"},
+				Formatter:    &FormatterConfig{Type: "console"},
+				Outputs: []OutputConfig{
+					{Type: "stderr"},
+					{Type: "file", Path: "/tmp/a.log"},
+					{Type: "rotating", Path: "/tmp/b.log"},
+					{Type: "syslog", Network: "udp", Address: "127.0.0.1:514"},
+					{Type: "network", Network: "tcp", Address: "127.0.0.1:5170"},
+				},
+			},
+		},
+		{Input: map[string]string{"LOGS_CONFIG": filepath.Join(dir, "missing.yaml")}, Error: "LOGS_CONFIG: read log config error"},
+		{Input: map[string]string{"LOGS_DIR_HEADER": "yes"}, Error: `LOGS_DIR_HEADER: invalid bool "yes"`},
+		{Input: map[string]string{"LOGS_CHANNEL_SIZE": "big"}, Error: `LOGS_CHANNEL_SIZE: invalid integer "big"`},
+		{Input: map[string]string{"LOGS_COMMON_FIELDS": "service=order,region"}, Error: `LOGS_COMMON_FIELDS: item 1 "region" should be key=value`},
+		{Input: map[string]string{"LOGS_OUTPUTS": "stdout,network:127.0.0.1:5170"}, Error: `LOGS_OUTPUTS: item 1 "network:127.0.0.1:5170" should be network:network://address`},
+		{Input: map[string]string{"LOGS_OUTPUTS": "stdout:/tmp/a.log"}, Error: `LOGS_OUTPUTS: item 0 "stdout:/tmp/a.log" stdout output does not accept target`},
+		{Input: map[string]string{"LOGS_OUTPUTS": "file"}, Error: "invalid log config: outputs[0].path: is required for file output"},
+		{Input: map[string]string{"LOGS_VMODULE": "storage/*=verbose"}, Error: `invalid log config: vmodule: invalid vmodule item "storage/*=verbose"`},
+		{Input: map[string]string{"LOGS_MIN_LEVEL": "verbose"}, Error: `invalid log config: min_level: unknown log level "verbose"`},
+	}
+	for _, testCase := range testCases {
+		env := testCase.Input
+		osGetenv = func(key string) string {
+			return env[key]
+		}
+		config, err := LoadConfigEnv()
+		if testCase.Error != "" {
+			if assert.NotNil(t, err) {
+				assert.True(t, strings.HasPrefix(err.Error(), testCase.Error), err.Error())
+			}
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.Expected, config)
+	}
+}
@@ -0,0 +1,159 @@
+package logs
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ANSI escape codes used by ConsoleFormatter
+const (
+	colorReset   = "\x1b[0m"
+	colorDim     = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorBoldRed = "\x1b[1;31m"
+)
+
+var severityColor = []string{
+	DebugLog:   colorMagenta,
+	InfoLog:    colorGreen,
+	WarningLog: colorYellow,
+	ErrorLog:   colorRed,
+	FatalLog:   colorBoldRed,
+}
+
+// DefaultConsoleMessageWidth default ConsoleFormatter message column width
+const DefaultConsoleMessageWidth = 40
+
+// DefaultConsoleCallerWidth default ConsoleFormatter caller(file:line) column width
+const DefaultConsoleCallerWidth = 20
+
+// NewConsoleFormatter create a ConsoleFormatter.
+// Color is enabled only when os.Stdout is a terminal, see ColorEnabled.
+func NewConsoleFormatter(timeFormat string, toUTCTime bool) *ConsoleFormatter {
+	return &ConsoleFormatter{
+		TimeFormat:   timeFormat,
+		ToUTCTime:    toUTCTime,
+		CallerWidth:  DefaultConsoleCallerWidth,
+		MessageWidth: DefaultConsoleMessageWidth,
+		Color:        ColorEnabled(os.Stdout),
+	}
+}
+
+// ConsoleFormatter format log to a human-friendly row, often used for local development.
+// Row looks like `15:04:05 INFO    a.go:12              message    key=value`,
+// field values contain line breaks(such as stack traces) are printed indented below the row.
+type ConsoleFormatter struct {
+	TimeFormat   string
+	ToUTCTime    bool
+	CallerWidth  int
+	MessageWidth int
+	Color        bool
+}
+
+// Format format log to a console row
+func (f ConsoleFormatter) Format(commonFields []*CommonField, content *Content) []byte {
+	var b strings.Builder
+	t := content.Headers.Time
+	if f.ToUTCTime {
+		t = t.UTC()
+	}
+	b.WriteString(f.paint(colorDim, t.Format(f.TimeFormat)))
+	b.WriteByte(' ')
+	b.WriteString(f.paint(severityColor[content.Headers.Level], padRight(severityName[content.Headers.Level], 7)))
+	b.WriteByte(' ')
+	if content.Headers.TraceID != "" {
+		traceID := content.Headers.TraceID
+		if content.Headers.SpanID != "" {
+			traceID += "/" + content.Headers.SpanID
+		}
+		b.WriteString(f.paint(colorBlue, traceID))
+		b.WriteByte(' ')
+	}
+	b.WriteString(f.paint(colorDim, padRight(content.Headers.File+":"+strconv.Itoa(content.Headers.Line), f.CallerWidth)))
+	b.WriteByte(' ')
+
+	var multiline []Field
+	var pairs []string
+	for _, field := range content.Fields {
+		if strings.Contains(field.Value(), "\n") {
+			multiline = append(multiline, field)
+			continue
+		}
+		pairs = append(pairs, f.paint(colorCyan, field.Key()+"=")+consoleValue(field.Value()))
+	}
+	for _, commonField := range commonFields {
+		pairs = append(pairs, f.paint(colorDim, commonField.Key+"="+consoleValue(commonField.Value)))
+	}
+	if len(pairs) > 0 {
+		b.WriteString(padRight(content.Message, f.MessageWidth))
+		b.WriteByte(' ')
+		b.WriteString(strings.Join(pairs, " "))
+	} else {
+		b.WriteString(content.Message)
+	}
+	b.WriteByte('\n')
+
+	for _, field := range multiline {
+		b.WriteString("    ")
+		b.WriteString(f.paint(colorCyan, field.Key()+":"))
+		b.WriteByte('\n')
+		for _, line := range strings.Split(strings.TrimRight(field.Value(), "\n"), "\n") {
+			b.WriteString("        ")
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	return []byte(b.String())
+}
+
+func (f ConsoleFormatter) paint(color string, s string) string {
+	if !f.Color || s == "" {
+		return s
+	}
+	return color + s + colorReset
+}
+
+// padRight pad s with spaces to width runes
+func padRight(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+func consoleValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\"=") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+var osGetenv = os.Getenv
+
+// ColorEnabled report whether colorized log should be written to file.
+// NO_COLOR environment variable disables color, FORCE_COLOR environment variable forces color,
+// otherwise color is enabled only when file is a terminal.
+func ColorEnabled(file *os.File) bool {
+	if osGetenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := osGetenv("FORCE_COLOR"); force != "" && force != "0" && force != "false" {
+		return true
+	}
+	if file == nil || osGetenv("TERM") == "dumb" {
+		return false
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
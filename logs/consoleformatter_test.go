@@ -0,0 +1,136 @@
+package logs
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConsoleFormatter(t *testing.T) {
+	consoleFormatter := NewConsoleFormatter(time.Kitchen, true)
+	assert.Equal(t, time.Kitchen, consoleFormatter.TimeFormat)
+	assert.True(t, consoleFormatter.ToUTCTime)
+	assert.Equal(t, DefaultConsoleCallerWidth, consoleFormatter.CallerWidth)
+	assert.Equal(t, DefaultConsoleMessageWidth, consoleFormatter.MessageWidth)
+}
+
+func TestConsoleFormatter_Format(t *testing.T) {
+	testCases := []struct {
+		Mock struct {
+			ConsoleFormatter *ConsoleFormatter
+		}
+		Input struct {
+			CommonField []*CommonField
+			Content     *Content
+		}
+		Expected string
+	}{
+		{
+			Mock: struct {
+				ConsoleFormatter *ConsoleFormatter
+			}{ConsoleFormatter: &ConsoleFormatter{TimeFormat: "15:04:05", CallerWidth: 10, MessageWidth: 14}},
+			Input: struct {
+				CommonField []*CommonField
+				Content     *Content
+			}{CommonField: nil, Content: mockContent()},
+			Expected: "00:00:00 DEBUG   test_trace_id test.go:101 test message\n",
+		},
+		{
+			Mock: struct {
+				ConsoleFormatter *ConsoleFormatter
+			}{ConsoleFormatter: &ConsoleFormatter{TimeFormat: "15:04:05", CallerWidth: 12, MessageWidth: 14}},
+			Input: struct {
+				CommonField []*CommonField
+				Content     *Content
+			}{CommonField: []*CommonField{{Key: "instance", Value: "testMachine"}}, Content: func() *Content {
+				content := mockContent()
+				content.Headers.TraceID = ""
+				content.Fields = []Field{String("category", "Go"), String("name", "feehi io")}
+				return content
+			}()},
+			Expected: "00:00:00 DEBUG   test.go:101  test message   category=Go name=\"feehi io\" instance=testMachine\n",
+		},
+		{
+			Mock: struct {
+				ConsoleFormatter *ConsoleFormatter
+			}{ConsoleFormatter: &ConsoleFormatter{TimeFormat: "15:04:05", CallerWidth: 0, MessageWidth: 0}},
+			Input: struct {
+				CommonField []*CommonField
+				Content     *Content
+			}{CommonField: nil, Content: func() *Content {
+				content := mockContent()
+				content.Fields = []Field{String("user_id", "1"), String("stack", "goroutine 1 [running]:\nmain.main()\n")}
+				return content
+			}()},
+			Expected: "00:00:00 DEBUG   test_trace_id test.go:101 test message user_id=1\n    stack:\n        goroutine 1 [running]:\n        main.main()\n",
+		},
+		{
+			Mock: struct {
+				ConsoleFormatter *ConsoleFormatter
+			}{ConsoleFormatter: &ConsoleFormatter{TimeFormat: "15:04:05", CallerWidth: 0, MessageWidth: 0, Color: true}},
+			Input: struct {
+				CommonField []*CommonField
+				Content     *Content
+			}{CommonField: nil, Content: func() *Content {
+				content := mockContent()
+				content.Headers.Level = ErrorLog
+				content.Fields = []Field{String("user_id", "1")}
+				return content
+			}()},
+			Expected: "\x1b[2m00:00:00\x1b[0m \x1b[31mERROR  \x1b[0m \x1b[34mtest_trace_id\x1b[0m \x1b[2mtest.go:101\x1b[0m test message \x1b[36muser_id=\x1b[0m1\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		message := testCase.Mock.ConsoleFormatter.Format(testCase.Input.CommonField, testCase.Input.Content)
+		assert.Equal(t, testCase.Expected, string(message))
+	}
+}
+
+func TestPadRight(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: "abc", Expected: "abc  "},
+		{Input: "日志", Expected: "日志   "},
+		{Input: "abcdef", Expected: "abcdef"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, padRight(testCase.Input, 5))
+	}
+}
+
+func TestColorEnabled(t *testing.T) {
+	defer func() {
+		osGetenv = os.Getenv
+	}()
+	fl, err := ioutil.TempFile("", "console")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fl.Name())
+	defer fl.Close()
+
+	testCases := []struct {
+		Env      map[string]string
+		File     *os.File
+		Expected bool
+	}{
+		{Env: map[string]string{}, File: fl, Expected: false},
+		{Env: map[string]string{}, File: nil, Expected: false},
+		{Env: map[string]string{"FORCE_COLOR": "1"}, File: fl, Expected: true},
+		{Env: map[string]string{"FORCE_COLOR": "0"}, File: fl, Expected: false},
+		{Env: map[string]string{"FORCE_COLOR": "1", "NO_COLOR": "1"}, File: fl, Expected: false},
+	}
+	for _, testCase := range testCases {
+		env := testCase.Env
+		osGetenv = func(key string) string {
+			return env[key]
+		}
+		assert.Equal(t, testCase.Expected, ColorEnabled(testCase.File), testCase.Env)
+	}
+}
@@ -0,0 +1,65 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type contextFieldsKey struct{}
+
+// ContextWithFields return a copy of ctx carries fields, fields already in ctx are kept.
+// Every log row logged with the returned ctx(or its children) will contain these fields.
+func ContextWithFields(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	exists := FieldsFromContext(ctx)
+	merged := make([]Field, 0, len(exists)+len(fields))
+	merged = append(append(merged, exists...), fields...)
+	return context.WithValue(ctx, contextFieldsKey{}, merged)
+}
+
+// FieldsFromContext return fields attached by ContextWithFields
+func FieldsFromContext(ctx context.Context) []Field {
+	fields, _ := ctx.Value(contextFieldsKey{}).([]Field)
+	return fields
+}
+
+// contextFieldsExtractor extract fields attached by ContextWithFields, it is enabled by default.
+func contextFieldsExtractor(ctx context.Context) (TraceInfo, []Field) {
+	return TraceInfo{}, FieldsFromContext(ctx)
+}
+
+// WithContextField register a context key, ctx value of key will be logged as field named name.
+// Can called multi times, such as WithContextField(RequestIDKey, "request_id"), WithContextField(TenantKey, "tenant_id").
+func WithContextField(key interface{}, name string) Option {
+	return WithContextExtractor(func(ctx context.Context) (TraceInfo, []Field) {
+		value := ctx.Value(key)
+		if value == nil {
+			if contextKey, ok := key.(ContextKey); ok {
+				checkContextKey(ctx, contextKey)
+			}
+			return TraceInfo{}, nil
+		}
+		return TraceInfo{}, []Field{Any(name, value)}
+	})
+}
+
+var mismatchedContextKeys sync.Map
+
+// checkContextKey warn once when ctx has no value for ContextKey key but has one for the untyped string key,
+// such as context.WithValue(ctx, "trace_id", id), which never matches TraceIDIdentifier.
+// The string key is not looked up any more once key is warned.
+func checkContextKey(ctx context.Context, key ContextKey) {
+	if _, warned := mismatchedContextKeys.Load(key); warned {
+		return
+	}
+	if ctx.Value(string(key)) == nil {
+		return
+	}
+	if _, warned := mismatchedContextKeys.LoadOrStore(key, struct{}{}); warned {
+		return
+	}
+	fmt.Printf("context value set with string key %q is ignored, use logs.ContextKey(%q) as key \n", string(key), string(key))
+}
@@ -0,0 +1,73 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextWithFields(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, ctx, ContextWithFields(ctx))
+	assert.Nil(t, FieldsFromContext(ctx))
+
+	parent := ContextWithFields(ctx, String("request_id", "r1"))
+	child := ContextWithFields(parent, String("user_id", "u1"))
+	assert.Equal(t, []Field{String("request_id", "r1")}, FieldsFromContext(parent))
+	assert.Equal(t, []Field{String("request_id", "r1"), String("user_id", "u1")}, FieldsFromContext(child))
+}
+
+func TestLoggingT_contextFields(t *testing.T) {
+	type tenantKey struct{}
+	const routeKey ContextKey = "route"
+	outputCollects := bytes.Buffer{}
+	l := NewLogging(
+		WithFormatter(NewStringFormatter("{MESSAGE} {FIELDS}", defaultTimeHeaderFormat(), false)),
+		WithOutput(NewOutPut(AllSeverities, &outputCollects)),
+		WithContextField(tenantKey{}, "tenant_id"),
+		WithContextField(routeKey, "route"),
+	)
+	ctx := ContextWithFields(context.Background(), String("request_id", "r1"))
+	ctx = context.WithValue(ctx, tenantKey{}, 10)
+	ctx = context.WithValue(ctx, routeKey, "/users")
+	l.Info(ctx, "with fields", String("k", "v"))
+	l.Info(context.Background(), "without fields")
+	l.Sync()
+	assert.Equal(t, "with fields {k:v,request_id:r1,tenant_id:10,route:/users}\nwithout fields\n", outputCollects.String())
+}
+
+func TestCheckContextKey(t *testing.T) {
+	defer func() {
+		mismatchedContextKeys = sync.Map{}
+	}()
+	outputCollects := bytes.Buffer{}
+	l := NewLogging(WithOutput(NewOutPut(AllSeverities, &outputCollects)), WithContextField(ContextKey("user_id"), "user_id"))
+	ctx := context.WithValue(context.Background(), "trace_id", "untyped")
+	ctx = context.WithValue(ctx, "user_id", "untyped")
+	stdOut := testCaptureSTDOutput(func() {
+		l.Info(ctx, "mismatched key")
+		l.Info(ctx, "mismatched key again")
+		l.Info(context.WithValue(context.Background(), TraceIDIdentifier, "typed"), "typed key")
+		l.Sync()
+	})
+	assert.Equal(t, "context value set with string key \"trace_id\" is ignored, use logs.ContextKey(\"trace_id\") as key \n"+
+		"context value set with string key \"user_id\" is ignored, use logs.ContextKey(\"user_id\") as key \n", stdOut)
+	assert.NotContains(t, outputCollects.String(), "untyped")
+
+	counting := &testCountingContext{Context: ctx}
+	checkContextKey(counting, TraceIDIdentifier)
+	assert.Equal(t, 0, counting.lookups)
+}
+
+type testCountingContext struct {
+	context.Context
+	lookups int
+}
+
+func (c *testCountingContext) Value(key interface{}) interface{} {
+	c.lookups++
+	return c.Context.Value(key)
+}
@@ -0,0 +1,187 @@
+package logs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxFrameSize limit a single framed row size when decoding, avoid allocating huge memory for broken streams
+const maxFrameSize = 64 << 20
+
+// recordEncoder encode log record values to a binary format.
+type recordEncoder interface {
+	mapHeader(n int)
+	arrayHeader(n int)
+	str(s string)
+	int(i int64)
+	time(t time.Time)
+	bytes() []byte
+}
+
+// encodeRecord encode log row as
+// {"headers": {"level", "trace_id", "span_id", "trace_flags", "time", "line", "file"}, "message", "fields": [{key: value}], "common_fields": [{key: value}]}
+// which is the same layout with JSONFormatter.
+func encodeRecord(e recordEncoder, commonFields []*CommonField, content *Content) {
+	e.mapHeader(4)
+	e.str("headers")
+	e.mapHeader(7)
+	e.str("level")
+	e.int(int64(content.Headers.Level))
+	e.str("trace_id")
+	e.str(content.Headers.TraceID)
+	e.str("span_id")
+	e.str(content.Headers.SpanID)
+	e.str("trace_flags")
+	e.int(int64(content.Headers.TraceFlags))
+	e.str("time")
+	e.time(content.Headers.Time)
+	e.str("line")
+	e.int(int64(content.Headers.Line))
+	e.str("file")
+	e.str(content.Headers.File)
+	e.str("message")
+	e.str(content.Message)
+	e.str("fields")
+	e.arrayHeader(len(content.Fields))
+	for _, field := range content.Fields {
+		e.mapHeader(1)
+		e.str(field.Key())
+		e.str(field.Value())
+	}
+	e.str("common_fields")
+	e.arrayHeader(len(commonFields))
+	for _, commonField := range commonFields {
+		e.mapHeader(1)
+		e.str(commonField.Key)
+		e.str(commonField.Value)
+	}
+}
+
+// frame prefix payload with 4 bytes big endian length.
+func frame(payload []byte) []byte {
+	buf := make([]byte, 4, 4+len(payload))
+	binary.BigEndian.PutUint32(buf, uint32(len(payload)))
+	return append(buf, payload...)
+}
+
+// Decoder read framed binary log rows(written by MsgPackFormatter or CBORFormatter) back to Content.
+type Decoder struct {
+	reader    *bufio.Reader
+	unmarshal func(b []byte) (interface{}, error)
+}
+
+// NewMsgPackDecoder create a decoder for MsgPackFormatter output stream
+func NewMsgPackDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		reader:    bufio.NewReader(r),
+		unmarshal: unmarshalMsgPack,
+	}
+}
+
+// NewCBORDecoder create a decoder for CBORFormatter output stream
+func NewCBORDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		reader:    bufio.NewReader(r),
+		unmarshal: unmarshalCBOR,
+	}
+}
+
+// Decode read next log row from stream.
+// Return io.EOF when there are no more rows, io.ErrUnexpectedEOF when the stream ends in the middle of a row.
+func (d *Decoder) Decode() ([]*CommonField, *Content, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(d.reader, size[:]); err != nil {
+		return nil, nil, err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxFrameSize {
+		return nil, nil, fmt.Errorf("log frame size %d exceeds limit %d", n, maxFrameSize)
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(d.reader, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, nil, err
+	}
+	v, err := d.unmarshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodeRecord(v)
+}
+
+var errMalformedRecord = errors.New("malformed log record")
+
+func decodeRecord(v interface{}) ([]*CommonField, *Content, error) {
+	record, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, nil, errMalformedRecord
+	}
+	headers, ok := record["headers"].(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: headers missing", errMalformedRecord)
+	}
+	level, ok := headers["level"].(int64)
+	if !ok || level < int64(DebugLog) || level > int64(FatalLog) {
+		return nil, nil, fmt.Errorf("%w: invalid level %v", errMalformedRecord, headers["level"])
+	}
+	line, _ := headers["line"].(int64)
+	traceFlags, _ := headers["trace_flags"].(int64)
+	logTime, _ := headers["time"].(time.Time)
+	content := &Content{
+		Headers: MessageHeader{
+			Level:      Severity(level),
+			TraceFlags: byte(traceFlags),
+			Time:       logTime,
+			Line:       int(line),
+		},
+	}
+	content.Headers.TraceID, _ = headers["trace_id"].(string)
+	content.Headers.SpanID, _ = headers["span_id"].(string)
+	content.Headers.File, _ = headers["file"].(string)
+	content.Message, _ = record["message"].(string)
+
+	pairs, err := decodePairs(record["fields"])
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, pair := range pairs {
+		content.Fields = append(content.Fields, String(pair[0], pair[1]))
+	}
+	pairs, err = decodePairs(record["common_fields"])
+	if err != nil {
+		return nil, nil, err
+	}
+	var commonFields []*CommonField
+	for _, pair := range pairs {
+		commonFields = append(commonFields, NewCommonField(pair[0], pair[1]))
+	}
+	return commonFields, content, nil
+}
+
+// decodePairs decode [{key: value}, ...] to ordered key value pairs.
+func decodePairs(v interface{}) ([][2]string, error) {
+	if v == nil {
+		return nil, nil
+	}
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: fields should be array", errMalformedRecord)
+	}
+	pairs := make([][2]string, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: field should be map", errMalformedRecord)
+		}
+		for key, value := range m {
+			pairs = append(pairs, [2]string{key, fmt.Sprintf("%v", value)})
+		}
+	}
+	return pairs, nil
+}
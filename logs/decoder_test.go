@@ -0,0 +1,73 @@
+package logs
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder_Decode(t *testing.T) {
+	testCases := []struct {
+		Formatter  Formatter
+		NewDecoder func(r io.Reader) *Decoder
+	}{
+		{Formatter: NewMsgPackFormatter(), NewDecoder: NewMsgPackDecoder},
+		{Formatter: NewCBORFormatter(), NewDecoder: NewCBORDecoder},
+	}
+	commonFields := []*CommonField{NewCommonField("instance", "testMachine"), NewCommonField("language", "Go")}
+	contents := []*Content{
+		mockContent(),
+		func() *Content {
+			content := mockContent()
+			content.Headers.Level = FatalLog
+			content.Headers.SpanID = "00f067aa0ba902b7"
+			content.Headers.TraceFlags = 1
+			content.Headers.Time = time.Date(2020, 11, 20, 8, 30, 1, 123456789, time.UTC)
+			content.Headers.Line = 70000
+			content.Message = string(bytes.Repeat([]byte("long message "), 100))
+			content.Fields = []Field{String("category", "Go"), String("account_id", "123"), String("empty", "")}
+			return content
+		}(),
+	}
+
+	for _, testCase := range testCases {
+		buf := bytes.Buffer{}
+		for _, content := range contents {
+			buf.Write(testCase.Formatter.Format(commonFields, content))
+		}
+		decoder := testCase.NewDecoder(&buf)
+		for _, content := range contents {
+			decodedCommonFields, decodedContent, err := decoder.Decode()
+			assert.Nil(t, err)
+			assert.Equal(t, commonFields, decodedCommonFields)
+			assert.Equal(t, content, decodedContent)
+		}
+		_, _, err := decoder.Decode()
+		assert.Equal(t, io.EOF, err)
+	}
+}
+
+func TestDecoder_Decode_error(t *testing.T) {
+	row := NewMsgPackFormatter().Format(nil, mockContent())
+	testCases := []struct {
+		Input    []byte
+		Expected error
+		Message  string
+	}{
+		{Input: row[:2], Expected: io.ErrUnexpectedEOF, Message: "truncated length"},
+		{Input: row[:4], Expected: io.ErrUnexpectedEOF, Message: "truncated payload"},
+		{Input: frame([]byte{0x01}), Expected: errMalformedRecord, Message: "not a map"},
+	}
+	for _, testCase := range testCases {
+		_, _, err := NewMsgPackDecoder(bytes.NewReader(testCase.Input)).Decode()
+		assert.Equal(t, testCase.Expected, err, testCase.Message)
+	}
+
+	_, _, err := NewCBORDecoder(bytes.NewReader(frame([]byte{0xa1, 0x67}))).Decode()
+	assert.NotNil(t, err)
+	_, _, err = NewMsgPackDecoder(bytes.NewReader(frame([]byte{0x81, 0xa7, 'h', 'e', 'a', 'd', 'e', 'r', 's', 0x01}))).Decode()
+	assert.Contains(t, err.Error(), "headers missing")
+}
@@ -8,9 +8,10 @@ func defaultOptions() options {
 	return options{
 		TraceIDIdentifier: TraceIDIdentifier,
 		formatter:         defaultFormatter(),
-		commonFields:      []*commonField{},
+		commonFields:      []*CommonField{},
 		addDirHeader:      false,
 		maxLogChanNum:     1000,
+		contextExtractors: []ContextExtractor{contextFieldsExtractor},
 	}
 }
 
@@ -30,11 +31,11 @@ func defaultFormatter() Formatter {
 
 var osHostname = os.Hostname
 
-func defaultCommonFields() []*commonField {
-	commonFields := make([]*commonField, 0)
+func defaultCommonFields() []*CommonField {
+	commonFields := make([]*CommonField, 0)
 	hostName, err := osHostname()
 	if err == nil {
-		commonFields = append(commonFields, &commonField{
+		commonFields = append(commonFields, &CommonField{
 			Key:   "HostName",
 			Value: hostName,
 		})
@@ -13,6 +13,7 @@ func TestDefaultOptions(t *testing.T) {
 	assert.Equal(t, TraceIDIdentifier, options.TraceIDIdentifier)
 	assert.Equal(t, defaultFormatter(), options.formatter)
 	assert.Equal(t, false, options.addDirHeader)
+	assert.Equal(t, 1, len(options.contextExtractors))
 }
 
 func TestDefaultLogOutputs(t *testing.T) {
@@ -36,19 +37,19 @@ func TestDefaultFormatter(t *testing.T) {
 func TestDefaultCommonFields(t *testing.T) {
 	testCases := []struct {
 		MockOsHostname func() (string, error)
-		Expected       []*commonField
+		Expected       []*CommonField
 	}{
 		{
 			MockOsHostname: func() (string, error) {
 				return "", errors.New("error occur")
 			},
-			Expected: []*commonField{},
+			Expected: []*CommonField{},
 		},
 		{
 			MockOsHostname: func() (string, error) {
 				return "test-host", nil
 			},
-			Expected: []*commonField{{Key: "HostName", Value: "test-host"}},
+			Expected: []*CommonField{{Key: "HostName", Value: "test-host"}},
 		},
 	}
 	for _, testCase := range testCases {
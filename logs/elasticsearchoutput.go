@@ -0,0 +1,204 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ElasticsearchConfig config of NewElasticsearchOutput
+type ElasticsearchConfig struct {
+	// HTTPConfig URL is cluster address such as http://localhost:9200, /_bulk is appended when it is not the bulk API.
+	// MaxRetries, MinBackoff and MaxBackoff are also used to retry failed docs.
+	HTTPConfig
+	// IndexPrefix prefix of index name, default is logs-
+	IndexPrefix string
+	// IndexDateLayout time layout of MessageHeader.Time in UTC appended to IndexPrefix, default is 2006.01.02.
+	// Such as logs-2020.11.20.
+	IndexDateLayout string
+}
+
+// NewElasticsearchOutput create a output indexes rows to Elasticsearch or OpenSearch with bulk API in batches.
+// Rows are JSON docs with @timestamp, level, message, trace_id, span_id, file, line, common fields, and fields object.
+// Docs failed with 429 or 5xx status in bulk response are retried alone, other failed docs are reported by Flush.
+func NewElasticsearchOutput(levels []Severity, config ElasticsearchConfig) (Output, error) {
+	if config.URL != "" && !strings.HasSuffix(config.URL, "/_bulk") {
+		config.URL = strings.TrimSuffix(config.URL, "/") + "/_bulk"
+	}
+	client, err := newHTTPClient(config.HTTPConfig)
+	if err != nil {
+		return nil, err
+	}
+	if config.IndexPrefix == "" {
+		config.IndexPrefix = "logs-"
+	}
+	if config.IndexDateLayout == "" {
+		config.IndexDateLayout = "2006.01.02"
+	}
+	o := &elasticsearchOutput{
+		Levels: levels,
+		config: config,
+		client: client,
+	}
+	o.batcher = client.newBatcher(o.send)
+	return o, nil
+}
+
+type elasticsearchOutput struct {
+	Levels  []Severity
+	config  ElasticsearchConfig
+	client  *httpClient
+	batcher *batcher
+}
+
+// elasticsearchDoc action and source lines of a doc in bulk body
+type elasticsearchDoc struct {
+	action []byte
+	source []byte
+}
+
+func (o *elasticsearchOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+// Write index a formatted row as message of a doc with info level and current time
+func (o *elasticsearchOutput) Write(p []byte) (int, error) {
+	if err := o.WriteContent(nil, &Content{Headers: MessageHeader{Level: InfoLog, Time: timeNow()}, Message: strings.TrimSuffix(string(p), "\n")}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (o *elasticsearchOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	source, err := json.Marshal(elasticsearchSource(commonFields, content))
+	if err != nil {
+		return fmt.Errorf("marshal Elasticsearch doc error: %s", err)
+	}
+	action, _ := json.Marshal(map[string]map[string]string{
+		"create": {"_index": o.config.IndexPrefix + content.Headers.Time.UTC().Format(o.config.IndexDateLayout)},
+	})
+	return o.batcher.add(&elasticsearchDoc{action: action, source: source}, len(action)+len(source)+2)
+}
+
+func elasticsearchSource(commonFields []*CommonField, content *Content) map[string]interface{} {
+	source := make(map[string]interface{}, len(commonFields)+8)
+	for _, field := range commonFields {
+		source[field.Key] = field.Value
+	}
+	headers := content.Headers
+	source["@timestamp"] = headers.Time.UTC().Format(time.RFC3339Nano)
+	source["level"] = severityName[headers.Level]
+	source["message"] = content.Message
+	if headers.TraceID != "" {
+		source["trace_id"] = headers.TraceID
+	}
+	if headers.SpanID != "" {
+		source["span_id"] = headers.SpanID
+	}
+	if headers.File != "" {
+		source["file"] = headers.File
+		source["line"] = headers.Line
+	}
+	if len(content.Fields) > 0 {
+		fields := make(map[string]string, len(content.Fields))
+		for _, field := range content.Fields {
+			fields[field.Key()] = field.Value()
+		}
+		source["fields"] = fields
+	}
+	return source
+}
+
+func (o *elasticsearchOutput) Flush() error {
+	return o.batcher.flush()
+}
+
+// Close index current batch and stop interval indexing
+func (o *elasticsearchOutput) Close() error {
+	return o.batcher.close()
+}
+
+// elasticsearchBulkResponse bulk API response, items are in order of request docs
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+func (o *elasticsearchOutput) send(items []interface{}) error {
+	docs := make([]*elasticsearchDoc, 0, len(items))
+	for _, item := range items {
+		docs = append(docs, item.(*elasticsearchDoc))
+	}
+
+	var dropped int
+	var firstError string
+	var backoff time.Duration
+	for retry := 0; ; retry++ {
+		var body bytes.Buffer
+		for _, doc := range docs {
+			body.Write(doc.action)
+			body.WriteByte('\n')
+			body.Write(doc.source)
+			body.WriteByte('\n')
+		}
+		respBody, err := o.client.post(body.Bytes(), "application/x-ndjson", nil)
+		if err != nil {
+			return err
+		}
+		if respBody == nil {
+			return fmt.Errorf("parse Elasticsearch bulk response error: body is empty or can not be read")
+		}
+		resp := &elasticsearchBulkResponse{}
+		if err = json.Unmarshal(respBody, resp); err != nil {
+			return fmt.Errorf("parse Elasticsearch bulk response error: %s", err)
+		}
+		// docs without result item are not known to be indexed
+		if missing := len(docs) - len(resp.Items); missing > 0 {
+			dropped += missing
+			if firstError == "" {
+				firstError = fmt.Sprintf("%d docs missing in bulk response", missing)
+			}
+		}
+		if !resp.Errors {
+			break
+		}
+
+		var failed []*elasticsearchDoc
+		for i, item := range resp.Items {
+			if i >= len(docs) {
+				break
+			}
+			for _, result := range item {
+				if result.Status < 300 {
+					continue
+				}
+				if (result.Status == 429 || result.Status >= 500) && retry < o.client.config.MaxRetries {
+					failed = append(failed, docs[i])
+					continue
+				}
+				dropped++
+				if firstError == "" {
+					firstError = fmt.Sprintf("status %d %s: %s", result.Status, result.Error.Type, result.Error.Reason)
+				}
+			}
+		}
+		if len(failed) == 0 {
+			break
+		}
+		docs = failed
+		backoff = nextBackoff(backoff, o.client.config.MinBackoff, o.client.config.MaxBackoff)
+		timeSleep(jitter(backoff))
+	}
+	if dropped > 0 {
+		return fmt.Errorf("index %d docs to %s error, first error: %s", dropped, o.client.config.URL, firstError)
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testBulkServer fake bulk API, docs with message "rejected" fail with 429 the first time they are indexed,
+// docs with message "invalid" fail with 400. It returns indexed docs by index.
+func testBulkServer(t *testing.T) (*httptest.Server, func() (map[string][]map[string]interface{}, int)) {
+	var mu sync.Mutex
+	indexed := map[string][]map[string]interface{}{}
+	rejected := map[string]bool{}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/_bulk", r.URL.Path)
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		var items []string
+		hasErrors := false
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			assert.Nil(t, json.Unmarshal(scanner.Bytes(), &action))
+			assert.True(t, scanner.Scan())
+			var doc map[string]interface{}
+			assert.Nil(t, json.Unmarshal(scanner.Bytes(), &doc))
+			message := doc["message"].(string)
+			switch {
+			case message == "invalid":
+				hasErrors = true
+				items = append(items, `{"create":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}`)
+			case message == "rejected" && !rejected[message]:
+				rejected[message] = true
+				hasErrors = true
+				items = append(items, `{"create":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue is full"}}}`)
+			default:
+				index := action["create"]["_index"]
+				indexed[index] = append(indexed[index], doc)
+				items = append(items, `{"create":{"status":201}}`)
+			}
+		}
+		fmt.Fprintf(w, `{"took":1,"errors":%t,"items":[%s]}`, hasErrors, strings.Join(items, ","))
+	}))
+	return server, func() (map[string][]map[string]interface{}, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return indexed, requests
+	}
+}
+
+func TestElasticsearchOutput(t *testing.T) {
+	defer func() {
+		timeSleep = time.Sleep
+	}()
+	timeSleep = func(d time.Duration) {}
+	server, indexed := testBulkServer(t)
+	defer server.Close()
+
+	output, err := NewElasticsearchOutput(AllSeverities, ElasticsearchConfig{HTTPConfig: HTTPConfig{URL: server.URL + "/", BatchInterval: time.Hour}})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	day1 := time.Date(2020, 11, 20, 23, 59, 59, 5, time.UTC)
+	day2 := time.Date(2020, 11, 21, 8, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	contentOutput := output.(ContentOutput)
+	contentOutput.WriteContent([]*CommonField{NewCommonField("service", "order")}, &Content{
+		Headers: MessageHeader{Level: ErrorLog, TraceID: "trace", SpanID: "span", Time: day1, File: "a.go", Line: 12},
+		Message: "first",
+		Fields:  []Field{String("order_id", "10")},
+	})
+	contentOutput.WriteContent(nil, &Content{Headers: MessageHeader{Time: day2}, Message: "rejected"})
+	contentOutput.WriteContent(nil, &Content{Headers: MessageHeader{Time: day2}, Message: "invalid"})
+	contentOutput.WriteContent(nil, &Content{Headers: MessageHeader{Time: day2}, Message: "last"})
+
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "index 1 docs to "+server.URL+"/_bulk error, first error: status 400 mapper_parsing_exception: failed to parse", err.Error())
+	}
+	docs, requests := indexed()
+	// rejected doc is retried alone
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, []map[string]interface{}{{
+		"@timestamp": "2020-11-20T23:59:59.000000005Z",
+		"level":      "ERROR",
+		"message":    "first",
+		"trace_id":   "trace",
+		"span_id":    "span",
+		"file":       "a.go",
+		"line":       float64(12),
+		"service":    "order",
+		"fields":     map[string]interface{}{"order_id": "10"},
+	}}, docs["logs-2020.11.20"])
+	// index date is in UTC
+	if assert.Equal(t, 2, len(docs["logs-2020.11.21"])) {
+		assert.Equal(t, "last", docs["logs-2020.11.21"][0]["message"])
+		assert.Equal(t, "rejected", docs["logs-2020.11.21"][1]["message"])
+		assert.Equal(t, "DEBUG", docs["logs-2020.11.21"][0]["level"])
+	}
+}
+
+func TestElasticsearchOutput_index(t *testing.T) {
+	server, indexed := testBulkServer(t)
+	defer server.Close()
+
+	output, err := NewElasticsearchOutput(AllSeverities, ElasticsearchConfig{
+		HTTPConfig:      HTTPConfig{URL: server.URL + "/_bulk", BatchInterval: time.Hour},
+		IndexPrefix:     "app-",
+		IndexDateLayout: "2006.01",
+	})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	l := NewLogging(WithOutput(output))
+	l.Info(context.Background(), "from logger")
+	assert.Nil(t, l.Sync())
+	output.Write([]byte("raw row\n"))
+	assert.Nil(t, output.Flush())
+
+	docs, _ := indexed()
+	rows := docs["app-"+time.Now().UTC().Format("2006.01")]
+	if assert.Equal(t, 2, len(rows)) {
+		assert.Equal(t, "from logger", rows[0]["message"])
+		assert.Equal(t, "INFO", rows[0]["level"])
+		assert.Equal(t, "elasticsearchoutput_test.go", rows[0]["file"])
+		assert.Equal(t, "raw row", rows[1]["message"])
+		assert.Equal(t, "INFO", rows[1]["level"])
+	}
+}
+
+func TestElasticsearchOutput_error(t *testing.T) {
+	_, err := NewElasticsearchOutput(AllSeverities, ElasticsearchConfig{})
+	assert.NotNil(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+	output, err := NewElasticsearchOutput(AllSeverities, ElasticsearchConfig{HTTPConfig: HTTPConfig{URL: server.URL, BatchInterval: time.Hour}})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	output.Write([]byte("row\n"))
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "parse Elasticsearch bulk response error"), err.Error())
+	}
+
+	// docs without result item are failed
+	partial := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"took":1,"errors":false,"items":[{"create":{"status":201}}]}`))
+	}))
+	defer partial.Close()
+	output, err = NewElasticsearchOutput(AllSeverities, ElasticsearchConfig{HTTPConfig: HTTPConfig{URL: partial.URL, BatchInterval: time.Hour}})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	output.Write([]byte("row 1\n"))
+	output.Write([]byte("row 2\n"))
+	output.Write([]byte("row 3\n"))
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "index 2 docs to "+partial.URL+"/_bulk error, first error: 2 docs missing in bulk response", err.Error())
+	}
+
+	// 2xx response whose body can not be read is not retried
+	var requests int32
+	truncated := testTruncatedBodyServer(&requests)
+	defer truncated.Close()
+	output, err = NewElasticsearchOutput(AllSeverities, ElasticsearchConfig{HTTPConfig: HTTPConfig{URL: truncated.URL, BatchInterval: time.Hour}})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	output.Write([]byte("row\n"))
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "parse Elasticsearch bulk response error: body is empty or can not be read", err.Error())
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
@@ -17,6 +17,7 @@ func main() {
 	//custom use
 	customString()
 	//customJSON()
+	//customConsole()
 }
 
 func directly() {
@@ -27,10 +28,11 @@ func directly() {
 	}
 	logs.SetOutputs(fileOutput, logs.NewStdOutOutput([]logs.Severity{logs.DebugLog, logs.InfoLog, logs.ErrorLog}))
 	//logs.SetDirHeader(true)
-	//logs.SetCommonFields([]*logs.commonField{{Key: "instance", Value: "test_instance"}})
+	//logs.SetCommonFields([]*logs.CommonField{{Key: "instance", Value: "test_instance"}})
 	//logs.SetCommonFields(nil)
 
-	ctx := context.WithValue(context.Background(), "trace_id", "22222222222222")
+	ctx := context.WithValue(context.Background(), logs.TraceIDIdentifier, "22222222222222")
+	ctx = logs.ContextWithFields(ctx, logs.String("request_id", "r-1")) //every row logged with ctx contains request_id
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -65,7 +67,7 @@ func deepError() error {
 }
 
 func customString() {
-	ctx := context.WithValue(context.Background(), "trace_id", "aaaaaaaaa123")
+	ctx := context.WithValue(context.Background(), logs.TraceIDIdentifier, "aaaaaaaaa123")
 	formatter := logs.WithFormatter(
 		logs.NewStringFormatter(logs.DefaultStringFormatTemplate, time.RFC3339Nano, true), //format log to a string like `[{LEVEL} {TIME} {FILE}:{LINE}] {DATA}`
 	)
@@ -81,7 +83,7 @@ func customString() {
 }
 
 func customJSON() {
-	ctx := context.WithValue(context.Background(), "trace_id", "aaaaaaaaa123")
+	ctx := context.WithValue(context.Background(), logs.TraceIDIdentifier, "aaaaaaaaa123")
 	formatter := logs.WithFormatter(
 		logs.NewJSONFormatter(), //format log to json
 	)
@@ -98,3 +100,14 @@ func customJSON() {
 	}()
 	l.Debug(ctx, "custom debug", logs.String("category", "debug category"))
 }
+
+func customConsole() {
+	ctx := context.WithValue(context.Background(), logs.TraceIDIdentifier, "aaaaaaaaa123")
+	formatter := logs.WithFormatter(
+		logs.NewConsoleFormatter("15:04:05.000", false), //colorized when stdout is a terminal, set NO_COLOR or FORCE_COLOR to override
+	)
+	l := logs.NewLogging(formatter, logs.WithOutput(logs.NewStdOutOutput(logs.AllSeverities)))
+	defer l.Sync()
+	l.Info(ctx, "custom console", logs.String("category", "console category"))
+	l.Error(ctx, "get user info failed", logs.Err(generateError()), logs.String("stack", "main.deepError()\nmain.generateError()"))
+}
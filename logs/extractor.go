@@ -0,0 +1,87 @@
+package logs
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// TraceParentIdentifier context key of W3C trace context traceparent header value, such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01
+const TraceParentIdentifier ContextKey = "traceparent"
+
+// TraceInfo trace infos extracted from context
+type TraceInfo struct {
+	TraceID    string
+	SpanID     string
+	TraceFlags byte
+}
+
+// ContextExtractor extract trace infos and additional fields from context.
+// Non-empty trace infos are set to MessageHeader, fields are appended to the log row.
+type ContextExtractor func(ctx context.Context) (TraceInfo, []Field)
+
+// extract run context extractors, fill trace infos to header and return extracted fields.
+func (l *logging) extract(ctx context.Context, header *MessageHeader) []Field {
+	var fields []Field
+	for _, extractor := range l.opts().contextExtractors {
+		info, extracted := extractor(ctx)
+		if info.TraceID != "" {
+			header.TraceID = info.TraceID
+			header.TraceFlags = info.TraceFlags
+		}
+		if info.SpanID != "" {
+			header.SpanID = info.SpanID
+		}
+		fields = append(fields, extracted...)
+	}
+	return fields
+}
+
+// TraceParentExtractor extract W3C trace context from traceparent string value stored under TraceParentIdentifier.
+func TraceParentExtractor(ctx context.Context) (TraceInfo, []Field) {
+	traceParent, _ := ctx.Value(TraceParentIdentifier).(string)
+	if traceParent == "" {
+		return TraceInfo{}, nil
+	}
+	info, err := ParseTraceParent(traceParent)
+	if err != nil {
+		return TraceInfo{}, nil
+	}
+	return info, nil
+}
+
+var errInvalidTraceParent = errors.New("invalid traceparent")
+
+// ParseTraceParent parse W3C trace context traceparent header value `version-trace_id-parent_id-trace_flags`.
+func ParseTraceParent(traceParent string) (TraceInfo, error) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return TraceInfo{}, errInvalidTraceParent
+	}
+	if !isHex(parts[0]) || !isHex(parts[1]) || !isHex(parts[2]) || !isHex(parts[3]) {
+		return TraceInfo{}, errInvalidTraceParent
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return TraceInfo{}, errInvalidTraceParent
+	}
+	if parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return TraceInfo{}, errInvalidTraceParent
+	}
+	flags, _ := hex.DecodeString(parts[3])
+	return TraceInfo{
+		TraceID:    parts[1],
+		SpanID:     parts[2],
+		TraceFlags: flags[0],
+	}, nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
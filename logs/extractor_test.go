@@ -0,0 +1,80 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected TraceInfo
+		Error    bool
+	}{
+		{
+			Input:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			Expected: TraceInfo{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", TraceFlags: 1},
+		},
+		{
+			Input:    "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future",
+			Expected: TraceInfo{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", TraceFlags: 0},
+		},
+		{Input: "", Error: true},
+		{Input: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", Error: true},
+		{Input: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", Error: true},
+		{Input: "00-00000000000000000000000000000000-00f067aa0ba902b7-01", Error: true},
+		{Input: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", Error: true},
+		{Input: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", Error: true},
+		{Input: "00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01", Error: true},
+	}
+	for _, testCase := range testCases {
+		info, err := ParseTraceParent(testCase.Input)
+		if testCase.Error {
+			assert.NotNil(t, err, testCase.Input)
+		} else {
+			assert.Nil(t, err, testCase.Input)
+			assert.Equal(t, testCase.Expected, info, testCase.Input)
+		}
+	}
+}
+
+func TestTraceParentExtractor(t *testing.T) {
+	testCases := []struct {
+		Input    context.Context
+		Expected TraceInfo
+	}{
+		{Input: context.Background(), Expected: TraceInfo{}},
+		{Input: context.WithValue(context.Background(), TraceParentIdentifier, "invalid"), Expected: TraceInfo{}},
+		{
+			Input:    context.WithValue(context.Background(), TraceParentIdentifier, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"),
+			Expected: TraceInfo{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", TraceFlags: 1},
+		},
+	}
+	for _, testCase := range testCases {
+		info, fields := TraceParentExtractor(testCase.Input)
+		assert.Equal(t, testCase.Expected, info)
+		assert.Nil(t, fields)
+	}
+}
+
+func TestLoggingT_extract(t *testing.T) {
+	outputCollects := bytes.Buffer{}
+	l := NewLogging(
+		WithFormatter(NewStringFormatter("{TRACE_ID} {SPAN_ID} {TRACE_FLAGS} {MESSAGE} {FIELDS}", defaultTimeHeaderFormat(), false)),
+		WithOutput(NewOutPut(AllSeverities, &outputCollects)),
+		WithContextExtractor(TraceParentExtractor),
+		WithContextExtractor(func(ctx context.Context) (TraceInfo, []Field) {
+			return TraceInfo{SpanID: "override_span"}, []Field{String("tenant", "feehi")}
+		}),
+	)
+	ctx := context.WithValue(context.Background(), TraceIDIdentifier, "plain_trace_id")
+	l.Info(ctx, "no traceparent", String("k", "v"))
+	ctx = context.WithValue(ctx, TraceParentIdentifier, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	l.Info(ctx, "with traceparent")
+	l.Sync()
+	assert.Equal(t, "plain_trace_id override_span 00 no traceparent {k:v,tenant:feehi}\n"+
+		"4bf92f3577b34da6a3ce929d0e0e4736 override_span 01 with traceparent {tenant:feehi}\n", outputCollects.String())
+}
@@ -0,0 +1,354 @@
+package logs
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// FluentMode how entries are carried in forward protocol messages
+type FluentMode int
+
+const (
+	// ForwardMode messages are [tag, [[time, record], ...], option]
+	ForwardMode FluentMode = iota
+	// PackedForwardMode messages are [tag, bin of concatenated [time, record] entries, option]
+	PackedForwardMode
+)
+
+// DefaultFluentTag default tag template of NewFluentOutput
+const DefaultFluentTag = "logs.{LEVEL}"
+
+// FluentConfig config of NewFluentOutput
+type FluentConfig struct {
+	// Network one of tcp, tcp4, tcp6, unix, default is tcp
+	Network string
+	// Address such as 127.0.0.1:24224 or /var/run/fluent.sock
+	Address string
+	// TLS connect with TLS when it is not nil
+	TLS *tls.Config
+	// Tag template of entry tag, {LEVEL} is replaced with lower case severity name and {key} with value of
+	// common field key. Default is DefaultFluentTag, such as logs.info.
+	Tag string
+	// Mode default is ForwardMode
+	Mode FluentMode
+	// RequireAck send chunk id with every message and wait for server ack of it, messages not acked are resent,
+	// so entries are delivered at least once.
+	RequireAck bool
+	// AckTimeout max time to wait for an ack, default is 10s
+	AckTimeout time.Duration
+	// DialTimeout default is 5s
+	DialTimeout time.Duration
+	// WriteTimeout default is 5s
+	WriteTimeout time.Duration
+	// BatchCount max entries of a batch, default is DefaultHTTPBatchCount
+	BatchCount int
+	// BatchBytes max bytes of a batch, default is DefaultHTTPBatchBytes
+	BatchBytes int
+	// BatchInterval max time an entry waits to be sent, default is DefaultHTTPBatchInterval
+	BatchInterval time.Duration
+	// MaxRetries retries of a message on connection error or ack timeout, default is 3, negative is no retry
+	MaxRetries int
+	// MinBackoff first reconnect delay, doubled for every retry up to MaxBackoff, default is 100ms
+	MinBackoff time.Duration
+	// MaxBackoff default is 10s
+	MaxBackoff time.Duration
+}
+
+// NewFluentOutput create a output sends entries to Fluentd or Fluent Bit with forward protocol in batches.
+// Records have level, message, trace_id, span_id, file, line, common fields and fields map, entries of a batch
+// are grouped by tag. Connection is dialed on first send and redialed with backoff after it fails.
+func NewFluentOutput(levels []Severity, config FluentConfig) (Output, error) {
+	switch config.Network {
+	case "":
+		config.Network = "tcp"
+	case "tcp", "tcp4", "tcp6", "unix":
+	default:
+		return nil, fmt.Errorf("unknown log fluent output network %q, should be one of tcp, unix", config.Network)
+	}
+	if config.Address == "" {
+		return nil, fmt.Errorf("log fluent output address is empty")
+	}
+	if config.Tag == "" {
+		config.Tag = DefaultFluentTag
+	}
+	if config.AckTimeout <= 0 {
+		config.AckTimeout = 10 * time.Second
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 5 * time.Second
+	}
+	if config.BatchCount <= 0 {
+		config.BatchCount = DefaultHTTPBatchCount
+	}
+	if config.BatchBytes <= 0 {
+		config.BatchBytes = DefaultHTTPBatchBytes
+	}
+	if config.BatchInterval <= 0 {
+		config.BatchInterval = DefaultHTTPBatchInterval
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = 10 * time.Second
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+	o := &fluentOutput{Levels: levels, config: config}
+	o.batcher = newBatcher(config.BatchCount, config.BatchBytes, config.BatchInterval, o.send)
+	return o, nil
+}
+
+type fluentOutput struct {
+	Levels  []Severity
+	config  FluentConfig
+	batcher *batcher
+	// conn is only used by send and Close, which are serialized by batcher
+	conn net.Conn
+}
+
+// fluentEntry msgpack encoded [time, record] of tag
+type fluentEntry struct {
+	tag   string
+	entry []byte
+}
+
+func (o *fluentOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+// Write send a formatted row as message with info level and current time
+func (o *fluentOutput) Write(p []byte) (int, error) {
+	if err := o.WriteContent(nil, &Content{Headers: MessageHeader{Level: InfoLog, Time: timeNow()}, Message: strings.TrimSuffix(string(p), "\n")}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (o *fluentOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	e := &msgpackEncoder{buf: make([]byte, 0, 256)}
+	e.arrayHeader(2)
+	e.eventTime(content.Headers.Time)
+	encodeFluentRecord(e, commonFields, content)
+	entry := &fluentEntry{tag: o.tag(commonFields, content.Headers.Level), entry: e.bytes()}
+	return o.batcher.add(entry, len(entry.entry))
+}
+
+func (o *fluentOutput) tag(commonFields []*CommonField, level Severity) string {
+	tag := strings.Replace(o.config.Tag, "{LEVEL}", strings.ToLower(severityName[level]), -1)
+	for _, field := range commonFields {
+		tag = strings.Replace(tag, "{"+field.Key+"}", field.Value, -1)
+	}
+	return tag
+}
+
+func encodeFluentRecord(e *msgpackEncoder, commonFields []*CommonField, content *Content) {
+	headers := content.Headers
+	n := len(commonFields) + 2
+	if headers.TraceID != "" {
+		n++
+	}
+	if headers.SpanID != "" {
+		n++
+	}
+	if headers.File != "" {
+		n += 2
+	}
+	if len(content.Fields) > 0 {
+		n++
+	}
+	e.mapHeader(n)
+	for _, field := range commonFields {
+		e.str(field.Key)
+		e.str(field.Value)
+	}
+	e.str("level")
+	e.str(severityName[headers.Level])
+	e.str("message")
+	e.str(content.Message)
+	if headers.TraceID != "" {
+		e.str("trace_id")
+		e.str(headers.TraceID)
+	}
+	if headers.SpanID != "" {
+		e.str("span_id")
+		e.str(headers.SpanID)
+	}
+	if headers.File != "" {
+		e.str("file")
+		e.str(headers.File)
+		e.str("line")
+		e.int(int64(headers.Line))
+	}
+	if len(content.Fields) > 0 {
+		e.str("fields")
+		e.mapHeader(len(content.Fields))
+		for _, field := range content.Fields {
+			e.str(field.Key())
+			e.str(field.Value())
+		}
+	}
+}
+
+// eventTime encode t with forward protocol EventTime: fixext8 | 0 | seconds uint32 | nanoseconds uint32
+func (e *msgpackEncoder) eventTime(t time.Time) {
+	e.buf = append(e.buf, 0xd7, 0x00)
+	e.buf = appendUint32(e.buf, uint32(t.Unix()))
+	e.buf = appendUint32(e.buf, uint32(t.Nanosecond()))
+}
+
+func (o *fluentOutput) Flush() error {
+	return o.batcher.flush()
+}
+
+// Close send current batch and close connection
+func (o *fluentOutput) Close() error {
+	err := o.batcher.close()
+	if o.conn != nil {
+		o.conn.Close()
+		o.conn = nil
+	}
+	return err
+}
+
+func (o *fluentOutput) send(items []interface{}) error {
+	var tags []string
+	entries := map[string][][]byte{}
+	for _, item := range items {
+		entry := item.(*fluentEntry)
+		if _, ok := entries[entry.tag]; !ok {
+			tags = append(tags, entry.tag)
+		}
+		entries[entry.tag] = append(entries[entry.tag], entry.entry)
+	}
+	// entries of other tags are still sent when a message fails
+	var messages []string
+	for _, tag := range tags {
+		if err := o.sendMessage(tag, entries[tag]); err != nil {
+			messages = append(messages, err.Error())
+		}
+	}
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// sendMessage send entries of tag in a message and wait for ack when it is required,
+// the message is resent with the same chunk id after reconnecting so server can dedupe it.
+func (o *fluentOutput) sendMessage(tag string, entries [][]byte) error {
+	message, chunk, err := o.encodeMessage(tag, entries)
+	if err != nil {
+		return err
+	}
+	var backoff time.Duration
+	for retry := 0; ; retry++ {
+		err = o.write(message, chunk)
+		if err == nil {
+			return nil
+		}
+		if o.conn != nil {
+			o.conn.Close()
+			o.conn = nil
+		}
+		if retry >= o.config.MaxRetries {
+			return fmt.Errorf("send %d entries to fluent %s://%s error: %s", len(entries), o.config.Network, o.config.Address, err)
+		}
+		backoff = nextBackoff(backoff, o.config.MinBackoff, o.config.MaxBackoff)
+		timeSleep(jitter(backoff))
+	}
+}
+
+func (o *fluentOutput) encodeMessage(tag string, entries [][]byte) ([]byte, string, error) {
+	size := 0
+	for _, entry := range entries {
+		size += len(entry)
+	}
+	e := &msgpackEncoder{buf: make([]byte, 0, size+len(tag)+64)}
+	e.arrayHeader(3)
+	e.str(tag)
+	if o.config.Mode == PackedForwardMode {
+		e.binHeader(size)
+		for _, entry := range entries {
+			e.buf = append(e.buf, entry...)
+		}
+	} else {
+		e.arrayHeader(len(entries))
+		for _, entry := range entries {
+			e.buf = append(e.buf, entry...)
+		}
+	}
+
+	var chunk string
+	if o.config.RequireAck {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return nil, "", fmt.Errorf("generate fluent chunk id error: %s", err)
+		}
+		chunk = base64.StdEncoding.EncodeToString(id)
+		e.mapHeader(2)
+		e.str("chunk")
+		e.str(chunk)
+	} else {
+		e.mapHeader(1)
+	}
+	e.str("size")
+	e.int(int64(len(entries)))
+	return e.bytes(), chunk, nil
+}
+
+func (o *fluentOutput) write(message []byte, chunk string) error {
+	if o.conn == nil {
+		dialer := &net.Dialer{Timeout: o.config.DialTimeout}
+		var err error
+		if o.config.TLS != nil {
+			o.conn, err = tls.DialWithDialer(dialer, o.config.Network, o.config.Address, o.config.TLS)
+		} else {
+			o.conn, err = dialer.Dial(o.config.Network, o.config.Address)
+		}
+		if err != nil {
+			return fmt.Errorf("dial: %s", err)
+		}
+	}
+	o.conn.SetWriteDeadline(timeNow().Add(o.config.WriteTimeout))
+	if _, err := o.conn.Write(message); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+	return o.readAck(chunk)
+}
+
+// readAck read response {"ack": chunk} of a message
+func (o *fluentOutput) readAck(chunk string) error {
+	o.conn.SetReadDeadline(timeNow().Add(o.config.AckTimeout))
+	var buf []byte
+	p := make([]byte, 256)
+	for {
+		n, err := o.conn.Read(p)
+		buf = append(buf, p[:n]...)
+		if response, decodeErr := unmarshalMsgPack(buf); decodeErr == nil {
+			if m, ok := response.(map[string]interface{}); ok && m["ack"] == chunk {
+				return nil
+			}
+			return fmt.Errorf("unexpected ack %v, expected chunk %s", response, chunk)
+		}
+		if err != nil {
+			return fmt.Errorf("read ack: %s", err)
+		}
+	}
+}
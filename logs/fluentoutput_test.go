@@ -0,0 +1,268 @@
+package logs
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testFluentMessage a received forward protocol message, entries are decoded [time, record] of both modes
+type testFluentMessage struct {
+	Tag     string
+	Entries [][]interface{}
+	Option  map[string]interface{}
+}
+
+// testForwardServer forward protocol listener acks messages with chunk, a connection is closed without ack
+// after reading a message when drop returns true for index of the connection and tag of the message, drop may
+// be nil. It returns received messages.
+func testForwardServer(t *testing.T, drop func(conn int, tag string) bool) (string, func() []testFluentMessage, func()) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var messages []testFluentMessage
+	handle := func(conn net.Conn, i int) {
+		defer conn.Close()
+		var buf []byte
+		p := make([]byte, 4096)
+		for {
+			n, err := conn.Read(p)
+			if err != nil {
+				return
+			}
+			buf = append(buf, p[:n]...)
+			for len(buf) > 0 {
+				d := &msgpackDecoder{buf: buf}
+				v, err := d.value()
+				if err != nil {
+					break
+				}
+				buf = buf[d.off:]
+				message := testDecodeFluentMessage(t, v.([]interface{}))
+				mu.Lock()
+				messages = append(messages, message)
+				mu.Unlock()
+				if drop != nil && drop(i, message.Tag) {
+					return
+				}
+				if chunk, ok := message.Option["chunk"].(string); ok {
+					e := &msgpackEncoder{}
+					e.mapHeader(1)
+					e.str("ack")
+					e.str(chunk)
+					conn.Write(e.bytes())
+				}
+			}
+		}
+	}
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handle(conn, i)
+		}
+	}()
+	received := func() []testFluentMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]testFluentMessage(nil), messages...)
+	}
+	return listener.Addr().String(), received, func() {
+		listener.Close()
+	}
+}
+
+// testWaitFluentMessages wait until n messages are received, messages sent without ack are read asynchronously
+func testWaitFluentMessages(messages func() []testFluentMessage, n int) []testFluentMessage {
+	deadline := time.Now().Add(5 * time.Second)
+	for len(messages()) < n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return messages()
+}
+
+func testDecodeFluentMessage(t *testing.T, message []interface{}) testFluentMessage {
+	decoded := testFluentMessage{Tag: message[0].(string), Option: message[2].(map[string]interface{})}
+	switch entries := message[1].(type) {
+	case []interface{}:
+		for _, entry := range entries {
+			decoded.Entries = append(decoded.Entries, entry.([]interface{}))
+		}
+	case []byte:
+		for len(entries) > 0 {
+			d := &msgpackDecoder{buf: entries}
+			entry, err := d.value()
+			if !assert.Nil(t, err) {
+				break
+			}
+			decoded.Entries = append(decoded.Entries, entry.([]interface{}))
+			entries = entries[d.off:]
+		}
+	}
+	return decoded
+}
+
+func TestFluentOutput(t *testing.T) {
+	testCases := []struct {
+		Input    FluentMode
+		Expected string
+	}{
+		{Input: ForwardMode, Expected: "forward"},
+		{Input: PackedForwardMode, Expected: "packed forward"},
+	}
+	for _, testCase := range testCases {
+		address, messages, stop := testForwardServer(t, nil)
+		output, err := NewFluentOutput(AllSeverities, FluentConfig{Address: address, Tag: "{service}.{LEVEL}", Mode: testCase.Input, BatchInterval: time.Hour})
+		assert.Nil(t, err)
+		contentOutput := output.(ContentOutput)
+		commonFields := []*CommonField{NewCommonField("service", "order")}
+		contentOutput.WriteContent(commonFields, &Content{
+			Headers: MessageHeader{Level: ErrorLog, Time: time.Unix(1605884645, 7), TraceID: "trace", SpanID: "span", File: "a.go", Line: 12},
+			Message: "error 1",
+			Fields:  []Field{String("order_id", "10")},
+		})
+		contentOutput.WriteContent(commonFields, &Content{Headers: MessageHeader{Level: InfoLog, Time: time.Unix(1605884645, 0)}, Message: "info 1"})
+		contentOutput.WriteContent(commonFields, &Content{Headers: MessageHeader{Level: ErrorLog, Time: time.Unix(1605884646, 0)}, Message: "error 2"})
+		assert.Nil(t, output.Flush(), testCase.Expected)
+		assert.Nil(t, output.(interface{ Close() error }).Close())
+		received := testWaitFluentMessages(messages, 2)
+		stop()
+
+		if !assert.Equal(t, 2, len(received), testCase.Expected) {
+			continue
+		}
+		// entries are grouped by tag
+		assert.Equal(t, "order.error", received[0].Tag)
+		assert.Equal(t, map[string]interface{}{"size": int64(2)}, received[0].Option)
+		if assert.Equal(t, 2, len(received[0].Entries)) {
+			eventTime := received[0].Entries[0][0].([]byte)
+			assert.Equal(t, uint32(1605884645), binary.BigEndian.Uint32(eventTime))
+			assert.Equal(t, uint32(7), binary.BigEndian.Uint32(eventTime[4:]))
+			assert.Equal(t, map[string]interface{}{
+				"service":  "order",
+				"level":    "ERROR",
+				"message":  "error 1",
+				"trace_id": "trace",
+				"span_id":  "span",
+				"file":     "a.go",
+				"line":     int64(12),
+				"fields":   map[string]interface{}{"order_id": "10"},
+			}, received[0].Entries[0][1])
+			assert.Equal(t, "error 2", received[0].Entries[1][1].(map[string]interface{})["message"])
+		}
+		assert.Equal(t, "order.info", received[1].Tag)
+		if assert.Equal(t, 1, len(received[1].Entries)) {
+			assert.Equal(t, map[string]interface{}{"service": "order", "level": "INFO", "message": "info 1"}, received[1].Entries[0][1])
+		}
+	}
+}
+
+func TestFluentOutput_ack(t *testing.T) {
+	defer func() {
+		timeSleep = time.Sleep
+	}()
+	timeSleep = func(d time.Duration) {}
+	address, messages, stop := testForwardServer(t, func(conn int, tag string) bool {
+		return conn == 0
+	})
+	defer stop()
+	output, err := NewFluentOutput(AllSeverities, FluentConfig{Address: address, RequireAck: true, Mode: PackedForwardMode, BatchInterval: time.Hour})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	l := NewLogging(WithOutput(output))
+	l.Warning(context.Background(), "from logger")
+	assert.Nil(t, l.Sync())
+
+	// message is resent with the same chunk on a new connection since first one is not acked
+	received := messages()
+	if assert.Equal(t, 2, len(received)) {
+		chunk := received[0].Option["chunk"].(string)
+		assert.Equal(t, 24, len(chunk))
+		assert.Equal(t, received[0], received[1])
+		assert.Equal(t, "logs.warning", received[1].Tag)
+		record := received[1].Entries[0][1].(map[string]interface{})
+		assert.Equal(t, "from logger", record["message"])
+		assert.Equal(t, "fluentoutput_test.go", record["file"])
+	}
+
+	output.Write([]byte("raw row\n"))
+	assert.Nil(t, output.Flush())
+	received = messages()
+	if assert.Equal(t, 3, len(received)) {
+		assert.Equal(t, "raw row", received[2].Entries[0][1].(map[string]interface{})["message"])
+		assert.NotEqual(t, received[0].Option["chunk"], received[2].Option["chunk"])
+	}
+}
+
+func TestFluentOutput_tagError(t *testing.T) {
+	defer func() {
+		timeSleep = time.Sleep
+	}()
+	timeSleep = func(d time.Duration) {}
+	address, messages, stop := testForwardServer(t, func(conn int, tag string) bool {
+		return tag == "logs.error"
+	})
+	defer stop()
+	output, err := NewFluentOutput(AllSeverities, FluentConfig{Address: address, RequireAck: true, MaxRetries: 1, BatchInterval: time.Hour})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	contentOutput := output.(ContentOutput)
+	contentOutput.WriteContent(nil, &Content{Headers: MessageHeader{Level: ErrorLog, Time: time.Unix(1605884645, 0)}, Message: "error"})
+	contentOutput.WriteContent(nil, &Content{Headers: MessageHeader{Level: InfoLog, Time: time.Unix(1605884645, 0)}, Message: "info"})
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Equal(t, 1, strings.Count(err.Error(), "send 1 entries to fluent tcp://"+address+" error"), err.Error())
+	}
+
+	// entries of next tag are sent after a tag fails
+	var tags []string
+	for _, message := range messages() {
+		tags = append(tags, message.Tag)
+	}
+	assert.Equal(t, []string{"logs.error", "logs.error", "logs.info"}, tags)
+}
+
+func TestFluentOutput_error(t *testing.T) {
+	testCases := []struct {
+		Input    FluentConfig
+		Expected string
+	}{
+		{Input: FluentConfig{Network: "udp", Address: "127.0.0.1:24224"}, Expected: `unknown log fluent output network "udp", should be one of tcp, unix`},
+		{Input: FluentConfig{}, Expected: "log fluent output address is empty"},
+	}
+	for _, testCase := range testCases {
+		_, err := NewFluentOutput(AllSeverities, testCase.Input)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, testCase.Expected, err.Error())
+		}
+	}
+
+	defer func() {
+		timeSleep = time.Sleep
+	}()
+	timeSleep = func(d time.Duration) {}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	output, err := NewFluentOutput(AllSeverities, FluentConfig{Address: address, MaxRetries: 1, BatchInterval: time.Hour})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	output.Write([]byte("row\n"))
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "send 1 entries to fluent tcp://"+address+" error: dial")
+	}
+}
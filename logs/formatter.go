@@ -9,12 +9,15 @@ import (
 
 // Formatter format log to a row message
 type Formatter interface {
-	Format(commonFields []*commonField, row *Content) []byte
+	Format(commonFields []*CommonField, row *Content) []byte
 }
 
 // DefaultStringFormatTemplate default StringFormatter template
 const DefaultStringFormatTemplate = "[{COMMON_FIELDS} {LEVEL} {TRACE_ID} {TIME} {FILE}:{LINE}] {MESSAGE} {FIELDS}"
 
+// DefaultSyslogFormatTemplate default StringFormatter template of syslog output, time and host are added by syslog
+const DefaultSyslogFormatTemplate = "[{LEVEL} {TRACE_ID} {FILE}:{LINE}] {MESSAGE} {FIELDS}"
+
 // NewStringFormatter create a StringFormatter
 func NewStringFormatter(template string, timeFormat string, toUTCTime bool) *StringFormatter {
 	return &StringFormatter{
@@ -32,7 +35,7 @@ type StringFormatter struct {
 }
 
 //Format format log to a string
-func (f StringFormatter) Format(commonFields []*commonField, message *Content) []byte {
+func (f StringFormatter) Format(commonFields []*CommonField, message *Content) []byte {
 	var s string
 	if len(commonFields) > 0 {
 		commonFieldsStr := ""
@@ -45,6 +48,8 @@ func (f StringFormatter) Format(commonFields []*commonField, message *Content) [
 	}
 	s = strings.Replace(s, "{LEVEL}", severityName[message.Headers.Level], -1)
 	s = strings.Replace(s, "{TRACE_ID}", message.Headers.TraceID, -1)
+	s = strings.Replace(s, "{SPAN_ID}", message.Headers.SpanID, -1)
+	s = strings.Replace(s, "{TRACE_FLAGS}", fmt.Sprintf("%02x", message.Headers.TraceFlags), -1)
 	s = strings.Replace(s, "{TIME}", message.Headers.Time.Format(f.TimeFormat), -1)
 	s = strings.Replace(s, "{LINE}", strconv.Itoa(message.Headers.Line), -1)
 	s = strings.Replace(s, "{FILE}", message.Headers.File, -1)
@@ -86,10 +91,10 @@ type JSONFormatter struct {
 var jsonMarshal = json.Marshal
 
 // Format format log to JSON row
-func (f JSONFormatter) Format(commonFields []*commonField, content *Content) []byte {
+func (f JSONFormatter) Format(commonFields []*CommonField, content *Content) []byte {
 	var record = struct {
 		*Content
-		CommonFields []*commonField `json:"common_fields"`
+		CommonFields []*CommonField `json:"common_fields"`
 	}{
 		Content:      content,
 		CommonFields: commonFields,
@@ -24,7 +24,7 @@ func TestStringFormatter_Format(t *testing.T) {
 			StringFormatter *StringFormatter
 		}
 		Input struct {
-			CommonField []*commonField
+			CommonField []*CommonField
 			Content     *Content
 		}
 		Expected string
@@ -36,7 +36,7 @@ func TestStringFormatter_Format(t *testing.T) {
 				StringFormatter: NewStringFormatter(DefaultStringFormatTemplate, defaultTimeHeaderFormat(), false),
 			},
 			Input: struct {
-				CommonField []*commonField
+				CommonField []*CommonField
 				Content     *Content
 			}{CommonField: nil, Content: &Content{
 				Headers: MessageHeader{
@@ -57,9 +57,9 @@ func TestStringFormatter_Format(t *testing.T) {
 				StringFormatter: NewStringFormatter(DefaultStringFormatTemplate, defaultTimeHeaderFormat(), false),
 			},
 			Input: struct {
-				CommonField []*commonField
+				CommonField []*CommonField
 				Content     *Content
-			}{CommonField: []*commonField{
+			}{CommonField: []*CommonField{
 				{Key: "instance", Value: "testMachine"},
 				{Key: "language", Value: "Go"},
 			}, Content: &Content{
@@ -81,9 +81,9 @@ func TestStringFormatter_Format(t *testing.T) {
 				StringFormatter: NewStringFormatter(DefaultStringFormatTemplate, defaultTimeHeaderFormat(), false),
 			},
 			Input: struct {
-				CommonField []*commonField
+				CommonField []*CommonField
 				Content     *Content
-			}{CommonField: []*commonField{
+			}{CommonField: []*CommonField{
 				{Key: "instance", Value: "testMachine"},
 				{Key: "language", Value: "Go"},
 			}, Content: &Content{
@@ -165,6 +165,9 @@ func TestJsonFormatter_Format(t *testing.T) {
 			}{Error: true, Message: nil},
 		},
 	}
+	defer func() {
+		jsonMarshal = json.Marshal
+	}()
 	JSONFormatter := mockJSONFormatter()
 	for k, testCase := range testCases {
 		if k != 1 {
@@ -214,3 +217,18 @@ func mockContent() *Content {
 		Fields:  nil,
 	}
 }
+
+func BenchmarkJSONFormatter_Format(b *testing.B) {
+	benchmarkFormatter(b, NewJSONFormatter())
+}
+
+func benchmarkFormatter(b *testing.B, formatter Formatter) {
+	commonFields := []*CommonField{NewCommonField("HostName", "testMachine")}
+	content := mockContent()
+	content.Fields = []Field{String("category", "Go"), String("account_id", "123"), Err(errors.New("not found"))}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		formatter.Format(commonFields, content)
+	}
+}
@@ -0,0 +1,233 @@
+package logs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Compression how payloads are compressed
+type Compression int
+
+const (
+	// GzipCompression compress with gzip
+	GzipCompression Compression = iota
+	// ZlibCompression compress with zlib
+	ZlibCompression
+	// NoCompression send payloads as they are
+	NoCompression
+)
+
+const (
+	// DefaultGELFChunkSize default max bytes of a GELF UDP datagram, which fits in WAN MTU
+	DefaultGELFChunkSize = 1420
+	// gelfChunkHeaderSize magic bytes, message id, sequence number and sequence count
+	gelfChunkHeaderSize = 12
+	// gelfMaxChunks max chunks of a message, Graylog discards messages with more chunks
+	gelfMaxChunks = 128
+)
+
+// gelfLevel syslog severity of Severity
+var gelfLevel = []int{
+	DebugLog:   7,
+	InfoLog:    6,
+	WarningLog: 4,
+	ErrorLog:   3,
+	FatalLog:   2,
+}
+
+var gelfInvalidFieldChars = regexp.MustCompile(`[^\w.\-]`)
+
+// GELFConfig config of NewGELFOutput
+type GELFConfig struct {
+	// NetworkConfig Network is one of udp, udp4, udp6, tcp, tcp4, tcp6. Framing is ignored, messages are
+	// null byte delimited over TCP.
+	NetworkConfig
+	// Host host field of messages, default is os.Hostname()
+	Host string
+	// Compression compression of UDP messages, default is GzipCompression. TCP messages are not compressed.
+	Compression Compression
+	// ChunkSize max bytes of a UDP datagram, larger messages are chunked, default is DefaultGELFChunkSize
+	ChunkSize int
+}
+
+// NewGELFOutput create a output sends GELF 1.1 messages to Graylog over UDP or TCP.
+// Level, time, file and line of MessageHeader are mapped to level, timestamp, _file and _line, trace id and
+// span id to _trace_id and _span_id, common fields and fields are additional fields prefixed with "_".
+// UDP messages larger than ChunkSize are chunked, messages need more than 128 chunks are dropped with error.
+func NewGELFOutput(levels []Severity, config GELFConfig) (Output, error) {
+	udp := false
+	switch config.Network {
+	case "udp", "udp4", "udp6":
+		udp = true
+	case "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("unknown log GELF output network %q, should be one of udp, tcp", config.Network)
+	}
+	if config.Host == "" {
+		config.Host, _ = osHostname()
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = DefaultGELFChunkSize
+	}
+	if config.ChunkSize <= gelfChunkHeaderSize {
+		return nil, fmt.Errorf("log GELF output chunk size %d is too small", config.ChunkSize)
+	}
+	config.Framing = NullFraming
+	network, err := NewNetworkOutput(levels, config.NetworkConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &gelfOutput{
+		Levels:  levels,
+		config:  config,
+		udp:     udp,
+		network: network.(*networkOutput),
+	}, nil
+}
+
+type gelfOutput struct {
+	Levels  []Severity
+	config  GELFConfig
+	udp     bool
+	network *networkOutput
+}
+
+func (o *gelfOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+// Write send a formatted row as short_message with info level and current time
+func (o *gelfOutput) Write(p []byte) (int, error) {
+	err := o.WriteContent(nil, &Content{Headers: MessageHeader{Level: InfoLog, Time: timeNow()}, Message: strings.TrimSuffix(string(p), "\n")})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (o *gelfOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	message, err := json.Marshal(gelfMessage(o.config.Host, commonFields, content))
+	if err != nil {
+		return fmt.Errorf("marshal GELF message error: %s", err)
+	}
+	if !o.udp {
+		return o.network.add(o.network.frame(message))
+	}
+	if message, err = compress(o.config.Compression, message); err != nil {
+		return fmt.Errorf("compress GELF message error: %s", err)
+	}
+	chunks, err := gelfChunks(message, o.config.ChunkSize)
+	if err != nil {
+		return err
+	}
+	return o.network.add(chunks...)
+}
+
+func gelfMessage(host string, commonFields []*CommonField, content *Content) map[string]interface{} {
+	message := make(map[string]interface{}, len(commonFields)+len(content.Fields)+8)
+	for _, field := range commonFields {
+		message[gelfFieldName(field.Key)] = field.Value
+	}
+	for _, field := range content.Fields {
+		message[gelfFieldName(field.Key())] = field.Value()
+	}
+	headers := content.Headers
+	message["version"] = "1.1"
+	message["host"] = host
+	message["short_message"] = content.Message
+	message["timestamp"] = float64(headers.Time.UnixNano()/1e6) / 1e3
+	message["level"] = gelfLevel[headers.Level]
+	if headers.File != "" {
+		message["_file"] = headers.File
+		message["_line"] = headers.Line
+	}
+	if headers.TraceID != "" {
+		message["_trace_id"] = headers.TraceID
+	}
+	if headers.SpanID != "" {
+		message["_span_id"] = headers.SpanID
+	}
+	return message
+}
+
+// gelfFieldName additional field name of key, invalid chars are replaced with "_", and _id is reserved.
+func gelfFieldName(key string) string {
+	name := "_" + gelfInvalidFieldChars.ReplaceAllString(key, "_")
+	if name == "_id" {
+		return "__id"
+	}
+	return name
+}
+
+func compress(compression Compression, p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	var writer interface {
+		Write([]byte) (int, error)
+		Close() error
+	}
+	switch compression {
+	case GzipCompression:
+		writer = gzip.NewWriter(&buf)
+	case ZlibCompression:
+		writer = zlib.NewWriter(&buf)
+	default:
+		return p, nil
+	}
+	if _, err := writer.Write(p); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gelfChunks split message into datagrams of at most chunkSize bytes, each chunk is prefixed with
+// magic bytes 0x1e 0x0f, 8 bytes message id, sequence number and sequence count.
+func gelfChunks(message []byte, chunkSize int) ([][]byte, error) {
+	if len(message) <= chunkSize {
+		return [][]byte{message}, nil
+	}
+	payloadSize := chunkSize - gelfChunkHeaderSize
+	count := (len(message) + payloadSize - 1) / payloadSize
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("GELF message of %d bytes needs %d chunks, more than %d", len(message), count, gelfMaxChunks)
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("generate GELF message id error: %s", err)
+	}
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		payload := message[i*payloadSize:]
+		if len(payload) > payloadSize {
+			payload = payload[:payloadSize]
+		}
+		chunk := make([]byte, 0, gelfChunkHeaderSize+len(payload))
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunks = append(chunks, append(chunk, payload...))
+	}
+	return chunks, nil
+}
+
+func (o *gelfOutput) Flush() error {
+	return o.network.Flush()
+}
+
+// Close send buffered messages and close connection
+func (o *gelfOutput) Close() error {
+	return o.network.Close()
+}
+
+// HealthCheck check connection of TCP, UDP is always available
+func (o *gelfOutput) HealthCheck() error {
+	return o.network.HealthCheck()
+}
@@ -0,0 +1,212 @@
+package logs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testGELFUDPServer receive datagrams and reassemble chunked messages, messages are decompressed by magic bytes
+func testGELFUDPServer(t *testing.T) (string, <-chan map[string]interface{}, func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := make(chan map[string]interface{}, 100)
+	go func() {
+		chunks := map[string][][]byte{}
+		buf := make([]byte, 65536)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			datagram := append([]byte(nil), buf[:n]...)
+			if bytes.HasPrefix(datagram, []byte{0x1e, 0x0f}) {
+				id, sequence, count := string(datagram[2:10]), int(datagram[10]), int(datagram[11])
+				if chunks[id] == nil {
+					chunks[id] = make([][]byte, count)
+				}
+				chunks[id][sequence] = datagram[12:]
+				complete := true
+				for _, chunk := range chunks[id] {
+					complete = complete && chunk != nil
+				}
+				if !complete {
+					continue
+				}
+				datagram = bytes.Join(chunks[id], nil)
+				delete(chunks, id)
+			}
+			reader := bytes.NewReader(datagram)
+			var body []byte
+			switch {
+			case bytes.HasPrefix(datagram, []byte{0x1f, 0x8b}):
+				gzipReader, err := gzip.NewReader(reader)
+				assert.Nil(t, err)
+				body, err = ioutil.ReadAll(gzipReader)
+				assert.Nil(t, err)
+			case datagram[0] == 0x78:
+				zlibReader, err := zlib.NewReader(reader)
+				assert.Nil(t, err)
+				body, err = ioutil.ReadAll(zlibReader)
+				assert.Nil(t, err)
+			default:
+				body = datagram
+			}
+			var message map[string]interface{}
+			assert.Nil(t, json.Unmarshal(body, &message))
+			messages <- message
+		}
+	}()
+	return conn.LocalAddr().String(), messages, func() {
+		conn.Close()
+	}
+}
+
+func testReceiveGELF(t *testing.T, messages <-chan map[string]interface{}) map[string]interface{} {
+	select {
+	case message := <-messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+	return nil
+}
+
+func TestGELFOutput_udp(t *testing.T) {
+	address, messages, stop := testGELFUDPServer(t)
+	defer stop()
+	testCases := []struct {
+		Input    GELFConfig
+		Expected string
+	}{
+		{Input: GELFConfig{NetworkConfig: NetworkConfig{Network: "udp", Address: address}, Host: "a1"}, Expected: "gzip"},
+		{Input: GELFConfig{NetworkConfig: NetworkConfig{Network: "udp", Address: address}, Host: "a1", Compression: ZlibCompression}, Expected: "zlib"},
+		{Input: GELFConfig{NetworkConfig: NetworkConfig{Network: "udp", Address: address}, Host: "a1", Compression: NoCompression, ChunkSize: 100}, Expected: "chunked"},
+	}
+	for _, testCase := range testCases {
+		output, err := NewGELFOutput(AllSeverities, testCase.Input)
+		assert.Nil(t, err)
+		err = output.(ContentOutput).WriteContent([]*CommonField{NewCommonField("service name", "order")}, &Content{
+			Headers: MessageHeader{Level: WarningLog, Time: time.Unix(1605884645, 123456789), TraceID: "trace", SpanID: "span", File: "a.go", Line: 12},
+			Message: testCase.Expected,
+			Fields:  []Field{String("id", "10"), String("order_id", strings.Repeat("1", 300))},
+		})
+		assert.Nil(t, err)
+		assert.Nil(t, output.Flush())
+		assert.Equal(t, map[string]interface{}{
+			"version":       "1.1",
+			"host":          "a1",
+			"short_message": testCase.Expected,
+			"timestamp":     1605884645.123,
+			"level":         float64(4),
+			"_file":         "a.go",
+			"_line":         float64(12),
+			"_trace_id":     "trace",
+			"_span_id":      "span",
+			"_service_name": "order",
+			"__id":          "10",
+			"_order_id":     strings.Repeat("1", 300),
+		}, testReceiveGELF(t, messages), testCase.Expected)
+		assert.Nil(t, output.(interface{ Close() error }).Close())
+	}
+}
+
+func TestGELFOutput_tcp(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	messages := make(chan string, 100)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			message, err := reader.ReadString(0)
+			if err != nil {
+				return
+			}
+			messages <- message
+		}
+	}()
+
+	output, err := NewGELFOutput(AllSeverities, GELFConfig{NetworkConfig: NetworkConfig{Network: "tcp", Address: listener.Addr().String()}, Host: "a1"})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	l := NewLogging(WithOutput(output))
+	l.Error(context.Background(), "first")
+	l.Info(context.Background(), "second")
+	assert.Nil(t, l.Sync())
+
+	for _, expected := range []string{"first", "second"} {
+		message := testReceive(t, messages)
+		assert.True(t, strings.HasSuffix(message, "\x00"))
+		var fields map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(strings.TrimSuffix(message, "\x00")), &fields))
+		assert.Equal(t, expected, fields["short_message"])
+		assert.Equal(t, "gelfoutput_test.go", fields["_file"])
+	}
+}
+
+func TestGELFOutput_error(t *testing.T) {
+	_, err := NewGELFOutput(AllSeverities, GELFConfig{NetworkConfig: NetworkConfig{Network: "unix", Address: "/tmp/gelf.sock"}})
+	assert.NotNil(t, err)
+	_, err = NewGELFOutput(AllSeverities, GELFConfig{NetworkConfig: NetworkConfig{Network: "udp", Address: "127.0.0.1:12201"}, ChunkSize: 12})
+	assert.NotNil(t, err)
+
+	output, err := NewGELFOutput(AllSeverities, GELFConfig{NetworkConfig: NetworkConfig{Network: "udp", Address: "127.0.0.1:12201"}, Compression: NoCompression, ChunkSize: 13})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	_, err = output.Write([]byte(strings.Repeat("a", 200)))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "more than 128")
+	}
+}
+
+func TestGELFChunks(t *testing.T) {
+	message := []byte(strings.Repeat("a", 25))
+	chunks, err := gelfChunks(message, 22)
+	assert.Nil(t, err)
+	if assert.Equal(t, 3, len(chunks)) {
+		for i, chunk := range chunks {
+			assert.Equal(t, []byte{0x1e, 0x0f}, chunk[:2])
+			assert.Equal(t, chunks[0][2:10], chunk[2:10])
+			assert.Equal(t, []byte{byte(i), 3}, chunk[10:12])
+		}
+		assert.Equal(t, 5, len(chunks[2][12:]))
+	}
+	chunks, err = gelfChunks(message, 25)
+	assert.Nil(t, err)
+	assert.Equal(t, [][]byte{message}, chunks)
+}
+
+func TestGELFFieldName(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: "order_id", Expected: "_order_id"},
+		{Input: "service.name", Expected: "_service.name"},
+		{Input: "user name/id", Expected: "_user_name_id"},
+		{Input: "id", Expected: "__id"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, gelfFieldName(testCase.Input))
+	}
+}
@@ -0,0 +1,110 @@
+package grpclog
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor create a client interceptor sends trace id in ctx and logs every unary RPC.
+func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		start := timeNow()
+		p := &peer.Peer{}
+		o.logPayload(ctx, "grpc request payload", method, req)
+		err := invoker(o.outgoingTrace(ctx), method, req, reply, cc, append(callOpts, grpc.Peer(p))...)
+		if err == nil {
+			o.logPayload(ctx, "grpc response payload", method, reply)
+		}
+		o.logRPC(ctx, "grpc client call", method, start, status.Code(err), p, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor create a client interceptor sends trace id in ctx and logs every streaming RPC
+// when it finishes: RecvMsg returns an error or io.EOF, RecvMsg of a client streaming RPC returns the
+// response, or ctx is done before that, such as the caller abandons the stream and cancels ctx.
+func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := timeNow()
+		p := &peer.Peer{}
+		cs, err := streamer(o.outgoingTrace(ctx), desc, cc, method, append(callOpts, grpc.Peer(p))...)
+		if err != nil {
+			o.logRPC(ctx, "grpc client stream", method, start, status.Code(err), p, err)
+			return nil, err
+		}
+		s := &clientStream{ClientStream: cs, ctx: ctx, desc: desc, options: o, method: method, start: start, peer: p, done: make(chan struct{})}
+		go s.watch()
+		return s, nil
+	}
+}
+
+// clientStream log payloads and the stream result once it finishes.
+type clientStream struct {
+	grpc.ClientStream
+	ctx     context.Context
+	desc    *grpc.StreamDesc
+	options options
+	method  string
+	start   time.Time
+	peer    *peer.Peer
+	once    sync.Once
+	// done is closed once the stream is logged
+	done chan struct{}
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		s.options.logPayload(s.ctx, "grpc request payload", s.method, m)
+	} else if err != io.EOF {
+		s.finish(err, s.peer)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch err {
+	case nil:
+		s.options.logPayload(s.ctx, "grpc response payload", s.method, m)
+		if !s.desc.ServerStreams {
+			s.finish(nil, s.peer)
+		}
+	case io.EOF:
+		s.finish(nil, s.peer)
+	default:
+		s.finish(err, s.peer)
+	}
+	return err
+}
+
+// finish log the stream once, p is nil when peer may be being set by grpc concurrently
+func (s *clientStream) finish(err error, p *peer.Peer) {
+	s.once.Do(func() {
+		code := codes.OK
+		if err != nil {
+			code = status.Code(err)
+		}
+		s.options.logRPC(s.ctx, "grpc client stream", s.method, s.start, code, p, err)
+		close(s.done)
+	})
+}
+
+// watch log the stream when ctx is done before RecvMsg reports its result, such as the caller abandons the
+// stream and cancels ctx. Peer is not logged, since grpc sets it while finishing the canceled stream.
+func (s *clientStream) watch() {
+	select {
+	case <-s.done:
+	case <-s.ctx.Done():
+		s.finish(status.FromContextError(s.ctx.Err()).Err(), nil)
+	}
+}
@@ -0,0 +1,120 @@
+package grpclog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/feehi.io/gopkg/logs"
+	"github.com/feehi.io/gopkg/logs/logstest"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	env := newTestEnv(t, WithPayloadLogging(1024))
+	defer env.close()
+
+	ctx := context.WithValue(context.Background(), logs.TraceIDIdentifier, "client_trace_id")
+	_, err := env.client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: "fail"})
+	assert.NotNil(t, err)
+	assert.Equal(t, "client_trace_id", <-env.server.traceIDs)
+
+	rows := testRows(t, env.clientLogger, env.clientLogs)
+	if assert.Equal(t, 2, len(rows)) {
+		assert.Equal(t, "grpc request payload", rows[0].Message)
+		assert.Contains(t, rows[0].field("payload"), "fail")
+		assert.Equal(t, "grpc client call", rows[1].Message)
+		assert.Equal(t, "client_trace_id", rows[1].Headers.TraceID)
+		assert.Equal(t, "client.go", rows[1].Headers.File)
+		assert.Equal(t, "NotFound", rows[1].field("code"))
+		assert.Equal(t, "bufconn", rows[1].field("peer"))
+		assert.Contains(t, rows[1].field("error"), "unknown service")
+	}
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.close()
+	testCases := []struct {
+		Input    string
+		Expected struct {
+			Code  string
+			Level logs.Severity
+		}
+	}{
+		{Input: "db", Expected: struct {
+			Code  string
+			Level logs.Severity
+		}{Code: "OK", Level: logs.InfoLog}},
+		{Input: "fail", Expected: struct {
+			Code  string
+			Level logs.Severity
+		}{Code: "Unavailable", Level: logs.WarningLog}},
+	}
+	for _, testCase := range testCases {
+		ctx := context.WithValue(context.Background(), logs.TraceIDIdentifier, "stream_trace_id")
+		stream, err := env.client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: testCase.Input})
+		assert.Nil(t, err)
+		for {
+			if _, err := stream.Recv(); err != nil {
+				break
+			}
+		}
+		stream.Recv()
+		assert.Equal(t, "stream_trace_id", <-env.server.traceIDs)
+
+		rows := testRows(t, env.clientLogger, env.clientLogs)
+		if assert.Equal(t, 1, len(rows)) {
+			assert.Equal(t, "grpc client stream", rows[0].Message)
+			assert.Equal(t, testCase.Expected.Level, rows[0].Headers.Level)
+			assert.Equal(t, testCase.Expected.Code, rows[0].field("code"))
+			assert.Equal(t, "stream_trace_id", rows[0].Headers.TraceID)
+		}
+	}
+}
+
+func TestStreamClientInterceptor_clientStreams(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.close()
+	stream, err := env.conn.NewStream(context.Background(), &uploadStreamDesc, "/test.Upload/Upload")
+	assert.Nil(t, err)
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, stream.SendMsg(&grpc_health_v1.HealthCheckRequest{Service: "db"}))
+	}
+	// same as CloseAndRecv of generated client
+	assert.Nil(t, stream.CloseSend())
+	assert.Nil(t, stream.RecvMsg(&grpc_health_v1.HealthCheckResponse{}))
+
+	rows := testRows(t, env.clientLogger, env.clientLogs)
+	if assert.Equal(t, 1, len(rows)) {
+		assert.Equal(t, "grpc client stream", rows[0].Message)
+		assert.Equal(t, "/test.Upload/Upload", rows[0].field("method"))
+		assert.Equal(t, "OK", rows[0].field("code"))
+	}
+}
+
+func TestStreamClientInterceptor_abandoned(t *testing.T) {
+	logger, observed := logstest.NewObservedLogger()
+	env := newTestEnv(t, WithLogger(logger))
+	defer env.close()
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := env.client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{Service: "db"})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.Nil(t, err)
+	cancel()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for observed.FilterMessage("grpc client stream").Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		logger.Sync()
+	}
+	time.Sleep(10 * time.Millisecond)
+	logger.Sync()
+	rows := observed.FilterMessage("grpc client stream")
+	if assert.Equal(t, 1, rows.Len()) {
+		code, _ := rows[0].Field("code")
+		assert.Equal(t, "Canceled", code)
+	}
+}
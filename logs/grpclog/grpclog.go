@@ -0,0 +1,218 @@
+// Package grpclog provides gRPC interceptors propagate trace id and write one log row per RPC.
+//
+//	server := grpc.NewServer(
+//		grpc.UnaryInterceptor(grpclog.UnaryServerInterceptor(grpclog.WithLogger(l))),
+//		grpc.StreamInterceptor(grpclog.StreamServerInterceptor(grpclog.WithLogger(l))),
+//	)
+//	conn, err := grpc.Dial(target,
+//		grpc.WithUnaryInterceptor(grpclog.UnaryClientInterceptor()),
+//		grpc.WithStreamInterceptor(grpclog.StreamClientInterceptor()),
+//	)
+//
+// Server interceptors read trace id from traceparent or x-request-id(configurable) metadata, or generate one,
+// and put it into context under logs.TraceIDIdentifier. Client interceptors send trace id in ctx to server.
+package grpclog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/feehi.io/gopkg/logs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// DefaultTraceMetadataKey default metadata key carries trace id
+const DefaultTraceMetadataKey = "x-request-id"
+
+// TraceParentMetadataKey W3C trace context metadata key
+const TraceParentMetadataKey = "traceparent"
+
+// maxTraceIDLength max length of trace id read from incoming metadata
+const maxTraceIDLength = 128
+
+// Option create interceptors can pass option values.
+type Option func(*options)
+
+type options struct {
+	logger       logs.Logger
+	traceKey     string
+	generator    func() string
+	payloadLimit int
+	severityCode func(code codes.Code) logs.Severity
+}
+
+// WithLogger set logger RPC rows write to. Default is logs.Default().
+func WithLogger(logger logs.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithTraceMetadataKey set metadata key trace id read from and sent with. Default is x-request-id.
+func WithTraceMetadataKey(key string) Option {
+	return func(o *options) {
+		o.traceKey = key
+	}
+}
+
+// WithTraceIDGenerator set trace id generator used when incoming RPC carries no trace id.
+func WithTraceIDGenerator(generator func() string) Option {
+	return func(o *options) {
+		o.generator = generator
+	}
+}
+
+// WithPayloadLogging log request and response messages at DEBUG, each payload is cut to at most limit bytes.
+// Default is disabled.
+func WithPayloadLogging(limit int) Option {
+	return func(o *options) {
+		o.payloadLimit = limit
+	}
+}
+
+// WithSeverity set how RPC row severity derived from status code. Default is SeverityForCode.
+func WithSeverity(severity func(code codes.Code) logs.Severity) Option {
+	return func(o *options) {
+		o.severityCode = severity
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		logger:       logs.Default(),
+		traceKey:     DefaultTraceMetadataKey,
+		generator:    newTraceID,
+		severityCode: SeverityForCode,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// SeverityForCode map client caused codes to INFO, server side temporary failures to WARNING and
+// server bugs(Unknown, Unimplemented, Internal, DataLoss) to ERROR.
+func SeverityForCode(code codes.Code) logs.Severity {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.Unauthenticated:
+		return logs.InfoLog
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted, codes.FailedPrecondition,
+		codes.Aborted, codes.OutOfRange, codes.Unavailable:
+		return logs.WarningLog
+	}
+	return logs.ErrorLog
+}
+
+var randRead = rand.Read
+
+func newTraceID() string {
+	b := make([]byte, 16)
+	if _, err := randRead(b); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format("20060102150405.000000")))[:32]
+	}
+	return hex.EncodeToString(b)
+}
+
+var timeNow = time.Now
+
+// incomingTrace resolve trace id from incoming metadata and put it in context.
+func (o options) incomingTrace(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(TraceParentMetadataKey); len(values) > 0 {
+		if info, err := logs.ParseTraceParent(values[0]); err == nil {
+			ctx = context.WithValue(ctx, logs.TraceParentIdentifier, values[0])
+			return context.WithValue(ctx, logs.TraceIDIdentifier, info.TraceID), info.TraceID
+		}
+	}
+	traceID := ""
+	if values := md.Get(o.traceKey); len(values) > 0 {
+		traceID = values[0]
+	}
+	if !validTraceID(traceID) {
+		traceID = o.generator()
+	}
+	return context.WithValue(ctx, logs.TraceIDIdentifier, traceID), traceID
+}
+
+// validTraceID whether id from client can be logged and echoed, it should be printable ASCII without space
+// and at most maxTraceIDLength bytes.
+func validTraceID(id string) bool {
+	if id == "" || len(id) > maxTraceIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// outgoingTrace append trace id(and traceparent) in ctx to outgoing metadata.
+func (o options) outgoingTrace(ctx context.Context) context.Context {
+	var pairs []string
+	if traceID, _ := ctx.Value(logs.TraceIDIdentifier).(string); traceID != "" {
+		pairs = append(pairs, o.traceKey, traceID)
+	}
+	if traceParent, _ := ctx.Value(logs.TraceParentIdentifier).(string); traceParent != "" {
+		pairs = append(pairs, TraceParentMetadataKey, traceParent)
+	}
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+// logRPC write one row for a finished RPC.
+func (o options) logRPC(ctx context.Context, message string, method string, start time.Time, code codes.Code, p *peer.Peer, err error) {
+	fields := []logs.Field{
+		logs.String("method", method),
+		logs.String("code", code.String()),
+		logs.String("duration", timeNow().Sub(start).String()),
+	}
+	if p != nil && p.Addr != nil {
+		fields = append(fields, logs.String("peer", p.Addr.String()))
+	}
+	if err != nil {
+		fields = append(fields, logs.Err(err))
+	}
+	o.logger.LogDepth(ctx, o.severityCode(code), 1, message, fields...)
+}
+
+// logPayload write message payload at DEBUG when payload logging enabled.
+func (o options) logPayload(ctx context.Context, message string, method string, payload interface{}) {
+	if o.payloadLimit <= 0 {
+		return
+	}
+	o.logger.LogDepth(ctx, logs.DebugLog, 1, message, logs.String("method", method), logs.String("payload", payloadString(payload, o.payloadLimit)))
+}
+
+func payloadString(payload interface{}, limit int) string {
+	var s string
+	switch v := payload.(type) {
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			s = fmt.Sprintf("%+v", v)
+		} else {
+			s = string(b)
+		}
+	}
+	if len(s) > limit {
+		// cut on a rune boundary, so rows stay valid UTF-8
+		for limit > 0 && !utf8.RuneStart(s[limit]) {
+			limit--
+		}
+		return fmt.Sprintf("%s...(%d bytes truncated)", s[:limit], len(s)-limit)
+	}
+	return s
+}
@@ -0,0 +1,238 @@
+package grpclog
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/feehi.io/gopkg/logs"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthServer stub health service, service name "fail" returns NotFound, "internal" returns Internal.
+type healthServer struct {
+	traceIDs chan interface{}
+}
+
+func (s *healthServer) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	s.traceIDs <- ctx.Value(logs.TraceIDIdentifier)
+	switch req.Service {
+	case "fail":
+		return nil, status.Error(codes.NotFound, "unknown service")
+	case "internal":
+		return nil, status.Error(codes.Internal, "broken")
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
+}
+
+func (s *healthServer) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	s.traceIDs <- stream.Context().Value(logs.TraceIDIdentifier)
+	if req.Service == "fail" {
+		return status.Error(codes.Unavailable, "not ready")
+	}
+	for i := 0; i < 2; i++ {
+		if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadStreamDesc client streaming RPC, server receives requests until io.EOF and responds once
+var uploadStreamDesc = grpc.StreamDesc{
+	StreamName:    "Upload",
+	ClientStreams: true,
+	Handler: func(srv interface{}, stream grpc.ServerStream) error {
+		for {
+			err := stream.RecvMsg(&grpc_health_v1.HealthCheckRequest{})
+			if err == io.EOF {
+				return stream.SendMsg(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	},
+}
+
+type testEnv struct {
+	conn         *grpc.ClientConn
+	client       grpc_health_v1.HealthClient
+	server       *healthServer
+	serverLogger logs.Logger
+	serverLogs   *bytes.Buffer
+	clientLogger logs.Logger
+	clientLogs   *bytes.Buffer
+	close        func()
+}
+
+func testLogger() (logs.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	return logs.NewLogging(
+		logs.WithFormatter(logs.NewJSONFormatter()),
+		logs.WithOutput(logs.NewOutPut(logs.AllSeverities, buf)),
+	), buf
+}
+
+func newTestEnv(t *testing.T, opts ...Option) *testEnv {
+	env := &testEnv{server: &healthServer{traceIDs: make(chan interface{}, 10)}}
+	env.serverLogger, env.serverLogs = testLogger()
+	env.clientLogger, env.clientLogs = testLogger()
+
+	listener := bufconn.Listen(1 << 20)
+	serverOpts := append([]Option{WithLogger(env.serverLogger), WithTraceIDGenerator(func() string { return "generated_id" })}, opts...)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor(serverOpts...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(serverOpts...)),
+	)
+	grpc_health_v1.RegisterHealthServer(server, env.server)
+	server.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "test.Upload",
+		HandlerType: (*interface{})(nil),
+		Streams:     []grpc.StreamDesc{uploadStreamDesc},
+	}, env.server)
+	go server.Serve(listener)
+
+	clientOpts := append([]Option{WithLogger(env.clientLogger)}, opts...)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(clientOpts...)),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(clientOpts...)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.conn = conn
+	env.client = grpc_health_v1.NewHealthClient(conn)
+	env.close = func() {
+		conn.Close()
+		server.Stop()
+	}
+	return env
+}
+
+type testRow struct {
+	logs.Content
+	Fields []map[string]string `json:"fields"`
+}
+
+func (r testRow) field(key string) string {
+	for _, field := range r.Fields {
+		if v, ok := field[key]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func testRows(t *testing.T, logger logs.Logger, buf *bytes.Buffer) []testRow {
+	logger.Sync()
+	var rows []testRow
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		row := testRow{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &row))
+		rows = append(rows, row)
+	}
+	buf.Reset()
+	return rows
+}
+
+func TestSeverityForCode(t *testing.T) {
+	testCases := []struct {
+		Input    codes.Code
+		Expected logs.Severity
+	}{
+		{Input: codes.OK, Expected: logs.InfoLog},
+		{Input: codes.NotFound, Expected: logs.InfoLog},
+		{Input: codes.Unavailable, Expected: logs.WarningLog},
+		{Input: codes.DeadlineExceeded, Expected: logs.WarningLog},
+		{Input: codes.Internal, Expected: logs.ErrorLog},
+		{Input: codes.Unknown, Expected: logs.ErrorLog},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, SeverityForCode(testCase.Input))
+	}
+}
+
+func TestPayloadString(t *testing.T) {
+	testCases := []struct {
+		Input    interface{}
+		Limit    int
+		Expected string
+	}{
+		{Input: map[string]int{"a": 1}, Limit: 100, Expected: `{"a":1}`},
+		{Input: map[string]int{"abcdef": 1}, Limit: 5, Expected: `{"abc...(7 bytes truncated)`},
+		{Input: errors.New("not stringer"), Limit: 100, Expected: `{}`},
+		{Input: "日志abc", Limit: 5, Expected: `"日...(7 bytes truncated)`},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, payloadString(testCase.Input, testCase.Limit))
+	}
+
+	s := payloadString(make(chan int), 2)
+	assert.Contains(t, s, "0x...(")
+	s = payloadString(&grpc_health_v1.HealthCheckRequest{Service: strings.Repeat("a", 100)}, 10)
+	assert.True(t, strings.HasPrefix(s, "service:"))
+	assert.Contains(t, s, "bytes truncated")
+}
+
+func TestValidTraceID(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected bool
+	}{
+		{Input: "request_id-1.2:3", Expected: true},
+		{Input: strings.Repeat("a", 128), Expected: true},
+		{Input: strings.Repeat("a", 129), Expected: false},
+		{Input: "", Expected: false},
+		{Input: "a b", Expected: false},
+		{Input: "a\tb", Expected: false},
+		{Input: "日志", Expected: false},
+		{Input: "a\x7f", Expected: false},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, validTraceID(testCase.Input), testCase.Input)
+	}
+}
+
+func TestNewTraceID(t *testing.T) {
+	defer func() {
+		randRead = rand.Read
+	}()
+	assert.Equal(t, 32, len(newTraceID()))
+	randRead = func(b []byte) (int, error) {
+		return 0, errors.New("no entropy")
+	}
+	assert.Equal(t, 32, len(newTraceID()))
+}
+
+func TestOutgoingTrace(t *testing.T) {
+	o := newOptions(nil)
+	ctx := o.outgoingTrace(context.Background())
+	_, ok := metadata.FromOutgoingContext(ctx)
+	assert.False(t, ok)
+
+	ctx = context.WithValue(context.Background(), logs.TraceIDIdentifier, "trace_1")
+	ctx = context.WithValue(ctx, logs.TraceParentIdentifier, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	md, _ := metadata.FromOutgoingContext(o.outgoingTrace(ctx))
+	assert.Equal(t, []string{"trace_1"}, md.Get(DefaultTraceMetadataKey))
+	assert.Equal(t, []string{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, md.Get(TraceParentMetadataKey))
+}
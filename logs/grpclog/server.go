@@ -0,0 +1,70 @@
+package grpclog
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor create a server interceptor propagates trace id and logs every unary RPC.
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := timeNow()
+		ctx, traceID := o.incomingTrace(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(o.traceKey, traceID))
+		o.logPayload(ctx, "grpc request payload", info.FullMethod, req)
+		resp, err := handler(ctx, req)
+		if err == nil {
+			o.logPayload(ctx, "grpc response payload", info.FullMethod, resp)
+		}
+		p, _ := peer.FromContext(ctx)
+		o.logRPC(ctx, "grpc server call", info.FullMethod, start, status.Code(err), p, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor create a server interceptor propagates trace id and logs every streaming RPC.
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(opts)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := timeNow()
+		ctx, traceID := o.incomingTrace(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(o.traceKey, traceID))
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx, options: o, method: info.FullMethod})
+		p, _ := peer.FromContext(ctx)
+		o.logRPC(ctx, "grpc server stream", info.FullMethod, start, status.Code(err), p, err)
+		return err
+	}
+}
+
+// serverStream carry trace context and log messages payload.
+type serverStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	options options
+	method  string
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.options.logPayload(s.ctx, "grpc response payload", s.method, m)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.options.logPayload(s.ctx, "grpc request payload", s.method, m)
+	}
+	return err
+}
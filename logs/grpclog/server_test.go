@@ -0,0 +1,120 @@
+package grpclog
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/feehi.io/gopkg/logs"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.close()
+	testCases := []struct {
+		Input struct {
+			Service  string
+			Metadata metadata.MD
+		}
+		Expected struct {
+			TraceID string
+			Code    string
+			Level   logs.Severity
+		}
+	}{
+		{
+			Input: struct {
+				Service  string
+				Metadata metadata.MD
+			}{Service: "", Metadata: metadata.MD{}},
+			Expected: struct {
+				TraceID string
+				Code    string
+				Level   logs.Severity
+			}{TraceID: "generated_id", Code: "OK", Level: logs.InfoLog},
+		},
+		{
+			Input: struct {
+				Service  string
+				Metadata metadata.MD
+			}{Service: "fail", Metadata: metadata.Pairs(DefaultTraceMetadataKey, "incoming_id")},
+			Expected: struct {
+				TraceID string
+				Code    string
+				Level   logs.Severity
+			}{TraceID: "incoming_id", Code: "NotFound", Level: logs.InfoLog},
+		},
+		{
+			Input: struct {
+				Service  string
+				Metadata metadata.MD
+			}{Service: "", Metadata: metadata.Pairs(DefaultTraceMetadataKey, strings.Repeat("a", 129))},
+			Expected: struct {
+				TraceID string
+				Code    string
+				Level   logs.Severity
+			}{TraceID: "generated_id", Code: "OK", Level: logs.InfoLog},
+		},
+		{
+			Input: struct {
+				Service  string
+				Metadata metadata.MD
+			}{Service: "internal", Metadata: metadata.Pairs(TraceParentMetadataKey, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")},
+			Expected: struct {
+				TraceID string
+				Code    string
+				Level   logs.Severity
+			}{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", Code: "Internal", Level: logs.ErrorLog},
+		},
+	}
+	for _, testCase := range testCases {
+		var header metadata.MD
+		ctx := metadata.NewOutgoingContext(context.Background(), testCase.Input.Metadata)
+		env.client.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: testCase.Input.Service}, grpc.Header(&header))
+		assert.Equal(t, testCase.Expected.TraceID, <-env.server.traceIDs)
+		assert.Equal(t, []string{testCase.Expected.TraceID}, header.Get(DefaultTraceMetadataKey))
+
+		rows := testRows(t, env.serverLogger, env.serverLogs)
+		if assert.Equal(t, 1, len(rows)) {
+			assert.Equal(t, "grpc server call", rows[0].Message)
+			assert.Equal(t, testCase.Expected.Level, rows[0].Headers.Level)
+			assert.Equal(t, testCase.Expected.TraceID, rows[0].Headers.TraceID)
+			assert.Equal(t, "server.go", rows[0].Headers.File)
+			assert.Equal(t, "/grpc.health.v1.Health/Check", rows[0].field("method"))
+			assert.Equal(t, testCase.Expected.Code, rows[0].field("code"))
+			assert.Equal(t, "bufconn", rows[0].field("peer"))
+			assert.NotEmpty(t, rows[0].field("duration"))
+		}
+		testRows(t, env.clientLogger, env.clientLogs)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	env := newTestEnv(t, WithPayloadLogging(64))
+	defer env.close()
+
+	stream, err := env.client.Watch(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "db"})
+	assert.Nil(t, err)
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, "generated_id", <-env.server.traceIDs)
+
+	rows := testRows(t, env.serverLogger, env.serverLogs)
+	if assert.Equal(t, 4, len(rows)) {
+		assert.Equal(t, "grpc request payload", rows[0].Message)
+		assert.Equal(t, logs.DebugLog, rows[0].Headers.Level)
+		assert.Contains(t, rows[0].field("payload"), "db")
+		assert.Equal(t, "grpc response payload", rows[1].Message)
+		assert.Contains(t, rows[1].field("payload"), "SERVING")
+		assert.Equal(t, "grpc server stream", rows[3].Message)
+		assert.Equal(t, "OK", rows[3].field("code"))
+		assert.Equal(t, "generated_id", rows[3].Headers.TraceID)
+	}
+}
@@ -0,0 +1,245 @@
+// Package httplog provides net/http middleware propagates trace id and writes access log.
+//
+//	handler := httplog.Middleware(mux, httplog.WithLogger(l))
+//
+// Trace id is read from traceparent header, then X-Request-ID(configurable) header, or generated,
+// it is put into request context under logs.TraceIDIdentifier and echoed in response header.
+// A header value longer than 128 bytes or containing characters other than printable ASCII is ignored.
+package httplog
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/feehi.io/gopkg/logs"
+)
+
+// DefaultTraceHeader default request/response header carries trace id
+const DefaultTraceHeader = "X-Request-ID"
+
+// TraceParentHeader W3C trace context header
+const TraceParentHeader = "traceparent"
+
+// maxTraceIDLength max length of trace id read from request header
+const maxTraceIDLength = 128
+
+// Option create Middleware can pass option values.
+type Option func(*options)
+
+type options struct {
+	logger         logs.Logger
+	traceHeader    string
+	generator      func() string
+	disableAccess  bool
+	severityStatus func(status int) logs.Severity
+}
+
+// WithLogger set logger access log write to. Default is logs.Default().
+func WithLogger(logger logs.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithTraceHeader set header name trace id read from and echoed to. Default is X-Request-ID.
+func WithTraceHeader(header string) Option {
+	return func(o *options) {
+		o.traceHeader = header
+	}
+}
+
+// WithTraceIDGenerator set trace id generator used when request carries no trace id.
+// Default generate 32 hex characters random id.
+func WithTraceIDGenerator(generator func() string) Option {
+	return func(o *options) {
+		o.generator = generator
+	}
+}
+
+// WithAccessLog whether write access log row for every request. Default is true.
+func WithAccessLog(enable bool) Option {
+	return func(o *options) {
+		o.disableAccess = !enable
+	}
+}
+
+// WithSeverity set how access log severity derived from response status code. Default is SeverityForStatus.
+func WithSeverity(severity func(status int) logs.Severity) Option {
+	return func(o *options) {
+		o.severityStatus = severity
+	}
+}
+
+func defaultOptions() options {
+	return options{
+		logger:         logs.Default(),
+		traceHeader:    DefaultTraceHeader,
+		generator:      NewTraceID,
+		severityStatus: SeverityForStatus,
+	}
+}
+
+// SeverityForStatus map 5xx to ERROR, 4xx to WARNING, others to INFO.
+func SeverityForStatus(status int) logs.Severity {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return logs.ErrorLog
+	case status >= http.StatusBadRequest:
+		return logs.WarningLog
+	}
+	return logs.InfoLog
+}
+
+var randRead = rand.Read
+
+// NewTraceID generate 32 hex characters random trace id, which is also a valid W3C trace id.
+func NewTraceID() string {
+	b := make([]byte, 16)
+	if _, err := randRead(b); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format("20060102150405.000000")))[:32]
+	}
+	return hex.EncodeToString(b)
+}
+
+var timeNow = time.Now
+
+// Middleware wrap next, propagate trace id and write one access log row per request.
+func Middleware(next http.Handler, opts ...Option) http.Handler {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := timeNow()
+		ctx, traceID := o.traceContext(r)
+		if o.traceHeader != "" {
+			w.Header().Set(o.traceHeader, traceID)
+		}
+		sw := &statusWriter{ResponseWriter: w}
+		r = r.WithContext(ctx)
+		if o.disableAccess {
+			next.ServeHTTP(sw, r)
+			return
+		}
+
+		defer func() {
+			status := sw.statusCode()
+			recovered := recover()
+			if recovered != nil {
+				status = http.StatusInternalServerError
+			}
+			fields := []logs.Field{
+				logs.String("method", r.Method),
+				logs.String("path", r.URL.Path),
+				logs.Any("status", status),
+				logs.Any("bytes", sw.bytes),
+				logs.String("duration", timeNow().Sub(start).String()),
+				logs.String("remote_addr", r.RemoteAddr),
+			}
+			if recovered != nil {
+				fields = append(fields, logs.Any("panic", recovered))
+			}
+			o.logger.LogDepth(ctx, o.severityStatus(status), 0, "http request", fields...)
+			if recovered != nil {
+				panic(recovered)
+			}
+		}()
+		next.ServeHTTP(sw, r)
+	})
+}
+
+// traceContext resolve request trace id and put it in context.
+func (o options) traceContext(r *http.Request) (context.Context, string) {
+	ctx := r.Context()
+	if traceParent := r.Header.Get(TraceParentHeader); traceParent != "" {
+		if info, err := logs.ParseTraceParent(traceParent); err == nil {
+			ctx = context.WithValue(ctx, logs.TraceParentIdentifier, traceParent)
+			return context.WithValue(ctx, logs.TraceIDIdentifier, info.TraceID), info.TraceID
+		}
+	}
+	traceID := ""
+	if o.traceHeader != "" {
+		traceID = r.Header.Get(o.traceHeader)
+	}
+	if !validTraceID(traceID) {
+		traceID = o.generator()
+	}
+	return context.WithValue(ctx, logs.TraceIDIdentifier, traceID), traceID
+}
+
+// validTraceID whether id from client can be logged and echoed, it should be printable ASCII without space
+// and at most maxTraceIDLength bytes.
+func validTraceID(id string) bool {
+	if id == "" || len(id) > maxTraceIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// statusWriter record response status code and body size.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += n
+	return n, err
+}
+
+func (w *statusWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Flush implements http.Flusher when the underlying writer does.
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker when the underlying writer does.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("http.Hijacker is not implemented by underlying response writer")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap return the underlying response writer, used by http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
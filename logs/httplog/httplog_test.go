@@ -0,0 +1,272 @@
+package httplog
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/feehi.io/gopkg/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+func testLogger() (logs.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	return logs.NewLogging(
+		logs.WithFormatter(logs.NewJSONFormatter()),
+		logs.WithOutput(logs.NewOutPut(logs.AllSeverities, buf)),
+	), buf
+}
+
+func testRows(t *testing.T, buf *bytes.Buffer) []logs.Content {
+	var rows []logs.Content
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		row := struct {
+			logs.Content
+			Fields []map[string]string `json:"fields"`
+		}{}
+		assert.Nil(t, json.Unmarshal([]byte(line), &row))
+		for _, field := range row.Fields {
+			for k, v := range field {
+				row.Content.Fields = append(row.Content.Fields, logs.String(k, v))
+			}
+		}
+		rows = append(rows, row.Content)
+	}
+	return rows
+}
+
+func testField(content logs.Content, key string) string {
+	for _, field := range content.Fields {
+		if field.Key() == key {
+			return field.Value()
+		}
+	}
+	return ""
+}
+
+func TestMiddleware(t *testing.T) {
+	testCases := []struct {
+		Input struct {
+			Headers map[string]string
+			Status  int
+			Options []Option
+		}
+		Expected struct {
+			TraceID       string
+			ResponseTrace string
+			Level         logs.Severity
+		}
+		Message string
+	}{
+		{
+			Input: struct {
+				Headers map[string]string
+				Status  int
+				Options []Option
+			}{Headers: map[string]string{}, Status: http.StatusOK},
+			Expected: struct {
+				TraceID       string
+				ResponseTrace string
+				Level         logs.Severity
+			}{TraceID: "generated_id", ResponseTrace: "generated_id", Level: logs.InfoLog},
+			Message: "generate trace id",
+		},
+		{
+			Input: struct {
+				Headers map[string]string
+				Status  int
+				Options []Option
+			}{Headers: map[string]string{"X-Request-ID": "request_id_1"}, Status: http.StatusNotFound},
+			Expected: struct {
+				TraceID       string
+				ResponseTrace string
+				Level         logs.Severity
+			}{TraceID: "request_id_1", ResponseTrace: "request_id_1", Level: logs.WarningLog},
+			Message: "read X-Request-ID",
+		},
+		{
+			Input: struct {
+				Headers map[string]string
+				Status  int
+				Options []Option
+			}{Headers: map[string]string{
+				"X-Request-ID": "request_id_1",
+				"traceparent":  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			}, Status: http.StatusBadGateway},
+			Expected: struct {
+				TraceID       string
+				ResponseTrace string
+				Level         logs.Severity
+			}{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ResponseTrace: "4bf92f3577b34da6a3ce929d0e0e4736", Level: logs.ErrorLog},
+			Message: "traceparent takes precedence",
+		},
+		{
+			Input: struct {
+				Headers map[string]string
+				Status  int
+				Options []Option
+			}{Headers: map[string]string{"traceparent": "broken", "X-Trace": "custom_id"}, Status: http.StatusCreated, Options: []Option{WithTraceHeader("X-Trace")}},
+			Expected: struct {
+				TraceID       string
+				ResponseTrace string
+				Level         logs.Severity
+			}{TraceID: "custom_id", ResponseTrace: "custom_id", Level: logs.InfoLog},
+			Message: "custom header and invalid traceparent",
+		},
+		{
+			Input: struct {
+				Headers map[string]string
+				Status  int
+				Options []Option
+			}{Headers: map[string]string{"X-Request-ID": strings.Repeat("a", 129)}, Status: http.StatusOK},
+			Expected: struct {
+				TraceID       string
+				ResponseTrace string
+				Level         logs.Severity
+			}{TraceID: "generated_id", ResponseTrace: "generated_id", Level: logs.InfoLog},
+			Message: "too long X-Request-ID is replaced",
+		},
+		{
+			Input: struct {
+				Headers map[string]string
+				Status  int
+				Options []Option
+			}{Headers: map[string]string{"X-Request-ID": "id\tforged=1"}, Status: http.StatusOK},
+			Expected: struct {
+				TraceID       string
+				ResponseTrace string
+				Level         logs.Severity
+			}{TraceID: "generated_id", ResponseTrace: "generated_id", Level: logs.InfoLog},
+			Message: "non printable X-Request-ID is replaced",
+		},
+	}
+	for _, testCase := range testCases {
+		logger, buf := testLogger()
+		var handlerTraceID interface{}
+		status := testCase.Input.Status
+		handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlerTraceID = r.Context().Value(logs.TraceIDIdentifier)
+			w.WriteHeader(status)
+			w.Write([]byte("hello"))
+		}), append([]Option{WithLogger(logger), WithTraceIDGenerator(func() string { return "generated_id" })}, testCase.Input.Options...)...)
+
+		r := httptest.NewRequest(http.MethodPost, "/users/1?debug=1", nil)
+		for k, v := range testCase.Input.Headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		logger.Sync()
+
+		traceHeader := DefaultTraceHeader
+		if testCase.Input.Headers["X-Trace"] != "" {
+			traceHeader = "X-Trace"
+		}
+		assert.Equal(t, testCase.Expected.TraceID, handlerTraceID, testCase.Message)
+		assert.Equal(t, testCase.Expected.ResponseTrace, w.Header().Get(traceHeader), testCase.Message)
+		rows := testRows(t, buf)
+		if assert.Equal(t, 1, len(rows), testCase.Message) {
+			assert.Equal(t, testCase.Expected.Level, rows[0].Headers.Level, testCase.Message)
+			assert.Equal(t, testCase.Expected.TraceID, rows[0].Headers.TraceID, testCase.Message)
+			assert.Equal(t, "httplog.go", rows[0].Headers.File, testCase.Message)
+			assert.Equal(t, "POST", testField(rows[0], "method"), testCase.Message)
+			assert.Equal(t, "/users/1", testField(rows[0], "path"), testCase.Message)
+			assert.Equal(t, strconv.Itoa(status), testField(rows[0], "status"), testCase.Message)
+			assert.Equal(t, "5", testField(rows[0], "bytes"), testCase.Message)
+			assert.Equal(t, "192.0.2.1:1234", testField(rows[0], "remote_addr"), testCase.Message)
+			assert.NotEmpty(t, testField(rows[0], "duration"), testCase.Message)
+		}
+	}
+}
+
+func TestMiddleware_panic(t *testing.T) {
+	logger, buf := testLogger()
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(errors.New("handler panic"))
+	}), WithLogger(logger))
+
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+	logger.Sync()
+	rows := testRows(t, buf)
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, logs.ErrorLog, rows[0].Headers.Level)
+	assert.Equal(t, "500", testField(rows[0], "status"))
+	assert.Equal(t, "handler panic", testField(rows[0], "panic"))
+}
+
+func TestMiddleware_disableAccessLog(t *testing.T) {
+	logger, buf := testLogger()
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.Info(r.Context(), "in handler")
+	}), WithLogger(logger), WithAccessLog(false))
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	logger.Sync()
+	rows := testRows(t, buf)
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, "in handler", rows[0].Message)
+	assert.Equal(t, resp.Header.Get(DefaultTraceHeader), rows[0].Headers.TraceID)
+	assert.Equal(t, 32, len(rows[0].Headers.TraceID))
+}
+
+func TestSeverityForStatus(t *testing.T) {
+	testCases := []struct {
+		Input    int
+		Expected logs.Severity
+	}{
+		{Input: http.StatusOK, Expected: logs.InfoLog},
+		{Input: http.StatusFound, Expected: logs.InfoLog},
+		{Input: http.StatusBadRequest, Expected: logs.WarningLog},
+		{Input: http.StatusInternalServerError, Expected: logs.ErrorLog},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, SeverityForStatus(testCase.Input))
+	}
+}
+
+func TestValidTraceID(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected bool
+	}{
+		{Input: "request_id-1.2:3", Expected: true},
+		{Input: strings.Repeat("a", 128), Expected: true},
+		{Input: strings.Repeat("a", 129), Expected: false},
+		{Input: "", Expected: false},
+		{Input: "a b", Expected: false},
+		{Input: "a\nb", Expected: false},
+		{Input: "日志", Expected: false},
+		{Input: "a\x7f", Expected: false},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, validTraceID(testCase.Input), testCase.Input)
+	}
+}
+
+func TestNewTraceID(t *testing.T) {
+	defer func() {
+		randRead = rand.Read
+	}()
+	id := NewTraceID()
+	assert.Equal(t, 32, len(id))
+	assert.NotEqual(t, id, NewTraceID())
+	randRead = func(b []byte) (int, error) {
+		return 0, errors.New("no entropy")
+	}
+	assert.Equal(t, 32, len(NewTraceID()))
+}
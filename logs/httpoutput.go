@@ -0,0 +1,301 @@
+package logs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPBodyFormat body format of HTTP output batches
+type HTTPBodyFormat int
+
+const (
+	// NDJSONBody rows separated by "\n", content type is application/x-ndjson
+	NDJSONBody HTTPBodyFormat = iota
+	// JSONArrayBody rows are elements of a JSON array, content type is application/json
+	JSONArrayBody
+)
+
+const (
+	// DefaultHTTPBatchCount default max rows of a batch
+	DefaultHTTPBatchCount = 100
+	// DefaultHTTPBatchBytes default max bytes of a batch, 1MB
+	DefaultHTTPBatchBytes = 1 << 20
+	// DefaultHTTPBatchInterval default interval batches are sent even they are not full
+	DefaultHTTPBatchInterval = time.Second
+)
+
+// HTTPConfig config of outputs push batches to HTTP API
+type HTTPConfig struct {
+	// URL batches are POST to
+	URL string
+	// Headers added to every request
+	Headers map[string]string
+	// Username and Password basic auth, used when Username is not empty
+	Username string
+	Password string
+	// BearerToken sent as Authorization: Bearer token when it is not empty
+	BearerToken string
+	// Gzip whether compress body with gzip
+	Gzip bool
+	// BatchCount max rows of a batch, default is DefaultHTTPBatchCount
+	BatchCount int
+	// BatchBytes max bytes of a batch before compression, default is DefaultHTTPBatchBytes
+	BatchBytes int
+	// BatchInterval max time a row waits to be sent, default is DefaultHTTPBatchInterval
+	BatchInterval time.Duration
+	// MaxRetries retries of a batch on network error, 5xx and 429, default is 3, negative is no retry
+	MaxRetries int
+	// MinBackoff first retry delay, doubled for every retry up to MaxBackoff, default is 100ms.
+	// Retry-After of response is used instead when it is set, it is limited to MaxBackoff since
+	// the delay holds back later batches.
+	MinBackoff time.Duration
+	// MaxBackoff default is 10s
+	MaxBackoff time.Duration
+	// Timeout timeout of a request, default is 10s
+	Timeout time.Duration
+	// Client used to send requests, nil creates one with Timeout
+	Client *http.Client
+}
+
+// HTTPOutputConfig config of NewHTTPOutput
+type HTTPOutputConfig struct {
+	HTTPConfig
+	// Body format of batches, default is NDJSONBody
+	Body HTTPBodyFormat
+	// Formatter format rows, default is JSON formatter
+	Formatter Formatter
+}
+
+var timeSleep = time.Sleep
+
+// NewHTTPOutput create a output POST rows to URL in batches by count, bytes or interval.
+// Batches are sent by a background goroutine and retried with backoff on network error, 5xx and 429,
+// full batches are dropped when sending falls behind. Flush sends current batch at once
+// and returns errors of batches failed and count of rows dropped since last Flush.
+func NewHTTPOutput(levels []Severity, config HTTPOutputConfig) (Output, error) {
+	client, err := newHTTPClient(config.HTTPConfig)
+	if err != nil {
+		return nil, err
+	}
+	if config.Formatter == nil {
+		config.Formatter = NewJSONFormatter()
+	}
+	o := &httpOutput{
+		Levels:    levels,
+		formatter: config.Formatter,
+		body:      config.Body,
+		client:    client,
+	}
+	o.batcher = client.newBatcher(o.send)
+	return o, nil
+}
+
+type httpOutput struct {
+	Levels    []Severity
+	formatter Formatter
+	body      HTTPBodyFormat
+	client    *httpClient
+	batcher   *batcher
+}
+
+func (o *httpOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+// Write add a formatted row to batch
+func (o *httpOutput) Write(p []byte) (int, error) {
+	row := make([]byte, len(p))
+	copy(row, p)
+	if err := o.batcher.add(row, len(row)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (o *httpOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	row := o.formatter.Format(commonFields, content)
+	return o.batcher.add(row, len(row))
+}
+
+func (o *httpOutput) Flush() error {
+	return o.batcher.flush()
+}
+
+// Close send current batch and stop interval sending
+func (o *httpOutput) Close() error {
+	return o.batcher.close()
+}
+
+func (o *httpOutput) send(items []interface{}) error {
+	var buf bytes.Buffer
+	contentType := "application/x-ndjson"
+	if o.body == JSONArrayBody {
+		contentType = "application/json"
+		buf.WriteByte('[')
+	}
+	for i, item := range items {
+		row := bytes.TrimRight(item.([]byte), "\n")
+		if o.body == JSONArrayBody {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(row)
+			continue
+		}
+		buf.Write(row)
+		buf.WriteByte('\n')
+	}
+	if o.body == JSONArrayBody {
+		buf.WriteByte(']')
+	}
+	_, err := o.client.post(buf.Bytes(), contentType, nil)
+	return err
+}
+
+// httpClient POST batches with auth, gzip and retries of HTTPConfig
+type httpClient struct {
+	config HTTPConfig
+	client *http.Client
+}
+
+func newHTTPClient(config HTTPConfig) (*httpClient, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("log HTTP output URL is empty")
+	}
+	if config.BatchCount <= 0 {
+		config.BatchCount = DefaultHTTPBatchCount
+	}
+	if config.BatchBytes <= 0 {
+		config.BatchBytes = DefaultHTTPBatchBytes
+	}
+	if config.BatchInterval <= 0 {
+		config.BatchInterval = DefaultHTTPBatchInterval
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = 3
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = 10 * time.Second
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+	return &httpClient{config: config, client: client}, nil
+}
+
+func (c *httpClient) newBatcher(send func(items []interface{}) error) *batcher {
+	return newBatcher(c.config.BatchCount, c.config.BatchBytes, c.config.BatchInterval, send)
+}
+
+// post POST body and return response body of 2xx response, it is nil when the body can not be read,
+// since logs are accepted and must not be retried. Request is retried on network error, 5xx and 429,
+// other responses return error at once. headers are added after HTTPConfig.Headers.
+func (c *httpClient) post(body []byte, contentType string, headers map[string]string) ([]byte, error) {
+	encoding := ""
+	if c.config.Gzip {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		writer.Write(body)
+		writer.Close()
+		body = buf.Bytes()
+		encoding = "gzip"
+	}
+
+	var backoff time.Duration
+	for retry := 0; ; retry++ {
+		respBody, retryAfter, err := c.do(body, contentType, encoding, headers)
+		if err == nil {
+			return respBody, nil
+		}
+		if retryAfter < 0 || retry >= c.config.MaxRetries {
+			return nil, fmt.Errorf("post logs to %s error: %s", c.config.URL, err)
+		}
+		backoff = nextBackoff(backoff, c.config.MinBackoff, c.config.MaxBackoff)
+		if retryAfter == 0 {
+			retryAfter = jitter(backoff)
+		} else if retryAfter > c.config.MaxBackoff {
+			retryAfter = c.config.MaxBackoff
+		}
+		timeSleep(retryAfter)
+	}
+}
+
+// do send a request, retryAfter is negative when request should not be retried,
+// 0 when it should be retried with backoff, or delay asked by Retry-After.
+func (c *httpClient) do(body []byte, contentType string, encoding string, headers map[string]string) ([]byte, time.Duration, error) {
+	req, err := http.NewRequest(http.MethodPost, c.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, -1, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	if c.config.Username != "" {
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+	if c.config.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.BearerToken)
+	}
+	for key, value := range c.config.Headers {
+		req.Header.Set(key, value)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if err != nil {
+			return nil, 0, nil
+		}
+		return respBody, 0, nil
+	}
+	err = fmt.Errorf("status %d: %s", resp.StatusCode, bytes.TrimSpace(truncate(respBody, 256)))
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+		return nil, -1, err
+	}
+	return nil, retryAfter(resp.Header.Get("Retry-After")), err
+}
+
+// retryAfter parse Retry-After in seconds or HTTP date, 0 is returned when it is empty or invalid.
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := t.Sub(timeNow()); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func truncate(b []byte, n int) []byte {
+	if len(b) > n {
+		return b[:n]
+	}
+	return b
+}
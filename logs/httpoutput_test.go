@@ -0,0 +1,282 @@
+package logs
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	mrand "math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testHTTPRequest struct {
+	Header http.Header
+	Path   string
+	Body   string
+}
+
+// testHTTPServer record requests and respond with statuses in order, 200 when they are used up
+func testHTTPServer(t *testing.T, statuses ...int) (*httptest.Server, func() []testHTTPRequest) {
+	var mu sync.Mutex
+	var requests []testHTTPRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.Nil(t, err)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			reader, err := gzip.NewReader(strings.NewReader(string(body)))
+			assert.Nil(t, err)
+			body, err = ioutil.ReadAll(reader)
+			assert.Nil(t, err)
+		}
+		mu.Lock()
+		requests = append(requests, testHTTPRequest{Header: r.Header, Path: r.URL.Path, Body: string(body)})
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		mu.Unlock()
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "2")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(http.StatusText(status)))
+	}))
+	return server, func() []testHTTPRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]testHTTPRequest(nil), requests...)
+	}
+}
+
+func TestNewHTTPOutput_error(t *testing.T) {
+	_, err := NewHTTPOutput(AllSeverities, HTTPOutputConfig{})
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "log HTTP output URL is empty", err.Error())
+	}
+}
+
+func TestHTTPOutput_batch(t *testing.T) {
+	server, requests := testHTTPServer(t)
+	defer server.Close()
+
+	output, err := NewHTTPOutput(AllSeverities, HTTPOutputConfig{
+		HTTPConfig: HTTPConfig{
+			URL:           server.URL,
+			Headers:       map[string]string{"X-Source": "order"},
+			BearerToken:   "token",
+			Gzip:          true,
+			BatchCount:    2,
+			BatchInterval: time.Hour,
+		},
+		Formatter: NewStringFormatter("{MESSAGE}", "", false),
+	})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	l := NewLogging(WithOutput(output))
+	for _, message := range []string{"row 1", "row 2", "row 3"} {
+		l.Info(context.Background(), message)
+	}
+	assert.Nil(t, l.Sync())
+
+	rows := requests()
+	if assert.Equal(t, 2, len(rows)) {
+		assert.Equal(t, "row 1\nrow 2\n", rows[0].Body)
+		assert.Equal(t, "row 3\n", rows[1].Body)
+		assert.Equal(t, "application/x-ndjson", rows[0].Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer token", rows[0].Header.Get("Authorization"))
+		assert.Equal(t, "order", rows[0].Header.Get("X-Source"))
+	}
+}
+
+func TestHTTPOutput_jsonArray(t *testing.T) {
+	server, requests := testHTTPServer(t)
+	defer server.Close()
+
+	output, err := NewHTTPOutput(AllSeverities, HTTPOutputConfig{
+		HTTPConfig: HTTPConfig{URL: server.URL, Username: "user", Password: "secret", BatchInterval: 10 * time.Millisecond},
+		Body:       JSONArrayBody,
+	})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	l := NewLogging(WithOutput(output), WithCommonField("service", "order"))
+	l.Info(context.Background(), "row 1", String("key", "value"))
+	l.Info(context.Background(), "row 2")
+
+	// sent by interval without Sync
+	for i := 0; i < 500 && len(requests()) == 0; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	rows := requests()
+	if assert.Equal(t, 1, len(rows)) {
+		var body []map[string]interface{}
+		assert.Nil(t, json.Unmarshal([]byte(rows[0].Body), &body))
+		if assert.Equal(t, 2, len(body)) {
+			assert.Equal(t, "row 1", body[0]["message"])
+			assert.Equal(t, []interface{}{map[string]interface{}{"key": "value"}}, body[0]["fields"])
+			assert.Equal(t, []interface{}{map[string]interface{}{"service": "order"}}, body[1]["common_fields"])
+		}
+		assert.Equal(t, "application/json", rows[0].Header.Get("Content-Type"))
+		username, password, ok := (&http.Request{Header: rows[0].Header}).BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", username)
+		assert.Equal(t, "secret", password)
+	}
+}
+
+func TestHTTPOutput_batchBytes(t *testing.T) {
+	server, requests := testHTTPServer(t)
+	defer server.Close()
+
+	output, err := NewHTTPOutput(AllSeverities, HTTPOutputConfig{HTTPConfig: HTTPConfig{URL: server.URL, BatchBytes: 10, BatchInterval: time.Hour}})
+	assert.Nil(t, err)
+	output.Write([]byte("123456\n"))
+	output.Write([]byte("7890\n"))
+	output.Write([]byte("abc\n"))
+	assert.Nil(t, output.(interface{ Close() error }).Close())
+
+	var bodies []string
+	for _, request := range requests() {
+		bodies = append(bodies, request.Body)
+	}
+	assert.Equal(t, []string{"123456\n", "7890\nabc\n"}, bodies)
+}
+
+func TestHTTPOutput_retry(t *testing.T) {
+	var sleeps []time.Duration
+	defer func() {
+		timeSleep = time.Sleep
+		randInt63n = mrand.Int63n
+	}()
+	timeSleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+	}
+	randInt63n = func(n int64) int64 {
+		return n - 1
+	}
+
+	testCases := []struct {
+		Statuses []int
+		Requests int
+		Sleeps   []time.Duration
+		Error    string
+	}{
+		{Statuses: []int{500, 503}, Requests: 3, Sleeps: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}},
+		{Statuses: []int{429}, Requests: 2, Sleeps: []time.Duration{2 * time.Second}},
+		{Statuses: []int{500, 500, 500, 500}, Requests: 4, Sleeps: []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond}, Error: "status 500: Internal Server Error"},
+		{Statuses: []int{400}, Requests: 1, Error: "status 400: Bad Request"},
+	}
+	for _, testCase := range testCases {
+		sleeps = nil
+		server, requests := testHTTPServer(t, testCase.Statuses...)
+		output, err := NewHTTPOutput(AllSeverities, HTTPOutputConfig{HTTPConfig: HTTPConfig{URL: server.URL, BatchInterval: time.Hour}})
+		assert.Nil(t, err)
+		output.Write([]byte("row\n"))
+		err = output.Flush()
+		if testCase.Error == "" {
+			assert.Nil(t, err)
+		} else if assert.NotNil(t, err) {
+			assert.Equal(t, "post logs to "+server.URL+" error: "+testCase.Error, err.Error())
+		}
+		assert.Equal(t, testCase.Requests, len(requests()))
+		assert.Equal(t, testCase.Sleeps, sleeps)
+		output.(interface{ Close() error }).Close()
+		server.Close()
+	}
+}
+
+func TestHTTPOutput_retryAfterLimit(t *testing.T) {
+	var sleeps []time.Duration
+	defer func() {
+		timeSleep = time.Sleep
+	}()
+	timeSleep = func(d time.Duration) {
+		sleeps = append(sleeps, d)
+	}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	output, err := NewHTTPOutput(AllSeverities, HTTPOutputConfig{HTTPConfig: HTTPConfig{URL: server.URL, BatchInterval: time.Hour}})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	output.Write([]byte("row\n"))
+	assert.Nil(t, output.Flush())
+	assert.Equal(t, 2, requests)
+	assert.Equal(t, []time.Duration{10 * time.Second}, sleeps)
+}
+
+// testTruncatedBodyServer respond 200 with a body shorter than its Content-Length, so reading it fails
+func testTruncatedBodyServer(requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("{"))
+	}))
+}
+
+func TestHTTPOutput_bodyReadError(t *testing.T) {
+	var requests int32
+	server := testTruncatedBodyServer(&requests)
+	defer server.Close()
+
+	output, err := NewHTTPOutput(AllSeverities, HTTPOutputConfig{HTTPConfig: HTTPConfig{URL: server.URL, BatchInterval: time.Hour}})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	output.Write([]byte("row\n"))
+	// rows are accepted by 2xx response, they are not retried
+	assert.Nil(t, output.Flush())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestHTTPOutput_batchError(t *testing.T) {
+	server, _ := testHTTPServer(t, 400)
+	defer server.Close()
+
+	output, err := NewHTTPOutput(AllSeverities, HTTPOutputConfig{HTTPConfig: HTTPConfig{URL: server.URL, BatchCount: 1, BatchInterval: time.Hour}})
+	assert.Nil(t, err)
+	// batch is sent by Write, its error is reported by next Flush
+	output.Write([]byte("row\n"))
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "status 400")
+	}
+	assert.Nil(t, output.Flush())
+}
+
+func TestRetryAfter(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	cur := time.Date(2020, 11, 20, 15, 4, 5, 0, time.UTC)
+	timeNow = func() time.Time {
+		return cur
+	}
+	testCases := []struct {
+		Input    string
+		Expected time.Duration
+	}{
+		{Input: "", Expected: 0},
+		{Input: "3", Expected: 3 * time.Second},
+		{Input: "-1", Expected: 0},
+		{Input: "Fri, 20 Nov 2020 15:04:15 GMT", Expected: 10 * time.Second},
+		{Input: "Fri, 20 Nov 2020 15:04:00 GMT", Expected: 0},
+		{Input: "soon", Expected: 0},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, retryAfter(testCase.Input), testCase.Input)
+	}
+}
@@ -1,5 +1,10 @@
 package logs
 
+import (
+	"fmt"
+	"strings"
+)
+
 //Severity log level
 type Severity int32
 
@@ -32,3 +37,17 @@ var severityName = []string{
 	ErrorLog:   "ERROR",
 	FatalLog:   "FATAL",
 }
+
+// ParseSeverity parse level name(debug, info, warning, error, fatal) to Severity, case insensitive, warn is accepted for warning.
+func ParseSeverity(name string) (Severity, error) {
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	if upper == "WARN" {
+		return WarningLog, nil
+	}
+	for s, n := range severityName {
+		if n == upper {
+			return Severity(s), nil
+		}
+	}
+	return DebugLog, fmt.Errorf("unknown log level %q, should be one of debug, info, warning, error, fatal", name)
+}
@@ -0,0 +1,33 @@
+package logs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSeverity(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected Severity
+		Error    bool
+	}{
+		{Input: "debug", Expected: DebugLog},
+		{Input: "INFO", Expected: InfoLog},
+		{Input: " Warning ", Expected: WarningLog},
+		{Input: "warn", Expected: WarningLog},
+		{Input: "error", Expected: ErrorLog},
+		{Input: "fatal", Expected: FatalLog},
+		{Input: "verbose", Error: true},
+		{Input: "", Error: true},
+	}
+	for _, testCase := range testCases {
+		s, err := ParseSeverity(testCase.Input)
+		if testCase.Error {
+			assert.NotNil(t, err, testCase.Input)
+			continue
+		}
+		assert.Nil(t, err)
+		assert.Equal(t, testCase.Expected, s)
+	}
+}
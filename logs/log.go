@@ -2,87 +2,159 @@ package logs
 
 import (
 	"context"
+	"os"
+	"sync/atomic"
+	"time"
 )
 
-var log *logging
+// globalLog package level log instance, stored *logging is swapped by ReplaceGlobal.
+var globalLog atomic.Value
 
 func init() {
-	log = NewLogging()
+	globalLog.Store(NewLogging())
+}
+
+// log return package level log instance
+func log() *logging {
+	return globalLog.Load().(*logging)
+}
+
+// ReplaceGlobal replace package level log instance with l, return a function restores the previous one.
+// It panics when l is nil, and the package level instance is kept.
+// Rows the previous instance received before the replacement are flushed to its outputs before ReplaceGlobal returns,
+// rows logged concurrently by goroutines which got the previous instance before the replacement may be written later.
+func ReplaceGlobal(l *logging) (restore func()) {
+	if l == nil {
+		panic("logs: ReplaceGlobal with nil log instance")
+	}
+	prev := globalLog.Load().(*logging)
+	globalLog.Store(l)
+	prev.Sync()
+	return func() {
+		ReplaceGlobal(prev)
+	}
 }
 
 // SetOutputs set log outputs.
 // Default is std output(os.Stdout).
 // If you does not want to output log, can set it to nil.
+// Rows logged before are written to previous outputs and flushed, previous outputs are not closed.
 func SetOutputs(outputs ...Output) {
-	log.options.outputs = outputs
+	log().update(func(o *options) {
+		o.outputs = outputs
+	})
 }
 
 // SetCommonFields set global message fields.
 // Default is HostName(os.Hostname()). If you want no common fields, just set it to nil.
 // Often used for Cluster to identify which machine generate that log.
-func SetCommonFields(commonFields ...*commonField) {
-	log.options.commonFields = commonFields
+func SetCommonFields(commonFields ...*CommonField) {
+	log().update(func(o *options) {
+		o.commonFields = commonFields
+	})
+}
+
+// SetFormatter set log formatter.
+// Default is StringFormatter with DefaultStringFormatTemplate.
+func SetFormatter(formatter Formatter) {
+	log().update(func(o *options) {
+		o.formatter = formatter
+	})
 }
 
 // SetDirHeader set if need print file log directory in log message. Such as example/a.go or only print a.go.
 // Default is false.
 func SetDirHeader(dir bool) {
-	log.options.addDirHeader = dir
+	log().update(func(o *options) {
+		o.addDirHeader = dir
+	})
+}
+
+// SetVModule set per caller file min levels of package level log instance, such as "storage/*=debug,http=warning".
+// Empty spec removes them, see ParseVModule.
+func SetVModule(spec string) error {
+	return log().SetVModule(spec)
+}
+
+// Reload replace package level log instance config, see NewLoggingFromConfig.
+// Rows logged before Reload are written to old outputs, then old outputs are flushed and closed.
+func Reload(config *Config) error {
+	return log().Reload(config)
+}
+
+// WatchConfigFile reload package level log instance config from filename when it changes, checked every interval.
+func WatchConfigFile(filename string, interval time.Duration) (stop func()) {
+	return watchConfigFile(filename, interval, Reload)
+}
+
+// ReloadOnSignal reload package level log instance config from filename when one of signals is received, default is SIGHUP.
+func ReloadOnSignal(filename string, signals ...os.Signal) (stop func()) {
+	return reloadOnSignal(filename, signals, Reload)
 }
 
 // Sync sync log to outputs.
 // Because we use buffered writer, so only buffer exceed a value they will really write to storage.
 // When Sync called, will trigger all outputs write buffer to storage.
 func Sync() []error {
-	return log.Sync()
+	return log().Sync()
 }
 
 // Debug record debug log
 func Debug(ctx context.Context, message string, fields ...Field) {
-	log.DebugDepth(ctx, 1, message, fields...)
+	log().DebugDepth(ctx, 1, message, fields...)
 }
 
 // DebugDepth record debug log with assigned code file depth
 func DebugDepth(ctx context.Context, depth int, message string, fields ...Field) {
-	log.DebugDepth(ctx, depth, message, fields...)
+	log().DebugDepth(ctx, depth, message, fields...)
 }
 
 // Info record info log
 func Info(ctx context.Context, message string, fields ...Field) {
-	log.InfoDepth(ctx, 1, message, fields...)
+	log().InfoDepth(ctx, 1, message, fields...)
 }
 
 // InfoDepth record info log with assigned code file depth
 func InfoDepth(ctx context.Context, depth int, message string, fields ...Field) {
-	log.InfoDepth(ctx, depth, message, fields...)
+	log().InfoDepth(ctx, depth, message, fields...)
 }
 
 // Warning record warning log
 func Warning(ctx context.Context, message string, fields ...Field) {
-	log.WarningDepth(ctx, 1, message, fields...)
+	log().WarningDepth(ctx, 1, message, fields...)
 }
 
 // WarningDepth record warning log with assigned code file depth
 func WarningDepth(ctx context.Context, depth int, message string, fields ...Field) {
-	log.WarningDepth(ctx, depth, message, fields...)
+	log().WarningDepth(ctx, depth, message, fields...)
 }
 
 // Error record error log
 func Error(ctx context.Context, message string, fields ...Field) {
-	log.ErrorDepth(ctx, 1, message, fields...)
+	log().ErrorDepth(ctx, 1, message, fields...)
 }
 
 // ErrorDepth record error log with assigned code file depth
 func ErrorDepth(ctx context.Context, depth int, message string, fields ...Field) {
-	log.ErrorDepth(ctx, depth, message, fields...)
+	log().ErrorDepth(ctx, depth, message, fields...)
 }
 
 // Fatal record fatal log
 func Fatal(ctx context.Context, message string, fields ...Field) {
-	log.FatalDepth(ctx, 1, message, fields...)
+	log().FatalDepth(ctx, 1, message, fields...)
 }
 
 // FatalDepth record fatal log with assigned code file depth
 func FatalDepth(ctx context.Context, depth int, message string, fields ...Field) {
-	log.FatalDepth(ctx, depth, message, fields...)
+	log().FatalDepth(ctx, depth, message, fields...)
+}
+
+// Log record log with assigned severity
+func Log(ctx context.Context, s Severity, message string, fields ...Field) {
+	log().LogDepth(ctx, s, 1, message, fields...)
+}
+
+// LogDepth record log with assigned severity and code file depth
+func LogDepth(ctx context.Context, s Severity, depth int, message string, fields ...Field) {
+	log().LogDepth(ctx, s, depth, message, fields...)
 }
@@ -4,6 +4,10 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -12,7 +16,7 @@ import (
 var curFile = "log_test.go"
 
 func TestInit(t *testing.T) {
-	assert.NotNil(t, log)
+	assert.NotNil(t, log())
 }
 
 func TestSetOutputs(t *testing.T) {
@@ -22,20 +26,136 @@ func TestSetOutputs(t *testing.T) {
 	}
 	SetOutputs(stdOutputs...)
 
-	assert.Equal(t, log.options.outputs, stdOutputs)
+	assert.Equal(t, log().opts().outputs, stdOutputs)
 }
 
 func TestSetCommonFields(t *testing.T) {
 	commonField := NewCommonField("instance", "machineA")
 	SetCommonFields(commonField)
-	assert.Equal(t, log.options.commonFields[0], commonField)
+	assert.Equal(t, log().opts().commonFields[0], commonField)
 }
 
 func TestSetDirHeader(t *testing.T) {
 	SetDirHeader(true)
-	assert.Equal(t, log.options.addDirHeader, true)
+	assert.Equal(t, log().opts().addDirHeader, true)
 	SetDirHeader(false)
-	assert.Equal(t, log.options.addDirHeader, false)
+	assert.Equal(t, log().opts().addDirHeader, false)
+}
+
+func TestSetFormatter(t *testing.T) {
+	defer testInitLogging()
+	testInitLogging()
+	Info(context.Background(), "before formatter")
+	SetFormatter(NewStringFormatter("{LEVEL} {MESSAGE}", "", false))
+	Info(context.Background(), "after formatter")
+	Sync()
+	// rows logged before SetFormatter are formatted with previous formatter
+	assert.Contains(t, outputCollects.String(), curFile)
+	assert.Contains(t, outputCollects.String(), "] before formatter")
+	assert.Contains(t, outputCollects.String(), "\nINFO after formatter\n")
+}
+
+func TestSetOutputs_pending(t *testing.T) {
+	defer testInitLogging()
+	testInitLogging()
+	next := bytes.Buffer{}
+	for i := 0; i < 10; i++ {
+		Info(context.Background(), "previous output")
+	}
+	SetOutputs(NewOutPut(AllSeverities, &next))
+	// previous output is flushed by SetOutputs
+	assert.Equal(t, 10, strings.Count(outputCollects.String(), "previous output"))
+	Info(context.Background(), "next output")
+	Sync()
+	assert.NotContains(t, next.String(), "previous output")
+	assert.Contains(t, next.String(), "next output")
+}
+
+func TestReplaceGlobal(t *testing.T) {
+	defer testInitLogging()
+	testInitLogging()
+	prev := log()
+	Info(context.Background(), "previous instance")
+
+	replaced := bytes.Buffer{}
+	l := NewLogging(WithOutput(NewOutPut(AllSeverities, &replaced)))
+	restore := ReplaceGlobal(l)
+	assert.Equal(t, l, log())
+	// rows of previous instance are flushed by ReplaceGlobal
+	assert.Contains(t, outputCollects.String(), "previous instance")
+
+	Info(context.Background(), "replaced instance")
+	Default().Info(context.Background(), "replaced default")
+	Sync()
+	assert.Contains(t, replaced.String(), "replaced instance")
+	assert.Contains(t, replaced.String(), "replaced default")
+	assert.Contains(t, replaced.String(), curFile)
+
+	restore()
+	assert.Equal(t, prev, log())
+
+	assert.Panics(t, func() {
+		ReplaceGlobal(nil)
+	})
+	assert.Equal(t, prev, log())
+}
+
+func TestGlobalSetters_race(t *testing.T) {
+	defer testInitLogging()
+	testInitLogging()
+	buffers := []*bytes.Buffer{{}, {}}
+	outputs := []Output{NewOutPut(AllSeverities, buffers[0]), NewOutPut(AllSeverities, buffers[1])}
+	SetOutputs(outputs[0])
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				Info(context.Background(), "race row")
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 20; j++ {
+			SetOutputs(outputs[j%2])
+			SetCommonFields(NewCommonField("round", strconv.Itoa(j)))
+			SetDirHeader(j%2 == 0)
+			SetFormatter(NewStringFormatter(DefaultStringFormatTemplate, defaultTimeHeaderFormat(), j%2 == 0))
+		}
+	}()
+	wg.Wait()
+	Sync()
+
+	// every row is written exactly once, to one of the outputs
+	assert.Equal(t, 800, strings.Count(buffers[0].String()+buffers[1].String(), "race row"))
+}
+
+func TestReplaceGlobal_race(t *testing.T) {
+	defer testInitLogging()
+	testInitLogging()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				Info(context.Background(), "race row")
+				Default().Warning(context.Background(), "race row")
+			}
+		}()
+	}
+	for j := 0; j < 10; j++ {
+		restore := ReplaceGlobal(NewLogging(WithOutput(NewOutPut(AllSeverities, ioutil.Discard))))
+		if j%2 == 0 {
+			restore()
+		}
+	}
+	wg.Wait()
+	Sync()
 }
 
 func TestSync(t *testing.T) {
@@ -59,7 +179,7 @@ func TestSync(t *testing.T) {
 		},
 	}
 	for _, testCase := range testCases {
-		log.options.outputs = testCase.Mock.Outputs
+		SetOutputs(testCase.Mock.Outputs...)
 		errs := Sync()
 		if testCase.Expected == nil {
 			assert.Nil(t, errs)
@@ -171,7 +291,26 @@ func TestFatalDepth(t *testing.T) {
 }
 
 func testInitLogging() {
-	log = NewLogging()
+	globalLog.Store(NewLogging())
 	outputCollects = bytes.Buffer{}
 	SetOutputs(NewOutPut(AllSeverities, &outputCollects))
 }
+
+func TestLog(t *testing.T) {
+	testInitLogging()
+	Log(context.Background(), WarningLog, "test Log", String("category", "test category"))
+	Sync()
+	assert.Contains(t, outputCollects.String(), curFile)
+	assert.Contains(t, outputCollects.String(), "WARNING")
+	assert.Contains(t, outputCollects.String(), "test Log")
+	assert.Contains(t, outputCollects.String(), "test category")
+}
+
+func TestLogDepth(t *testing.T) {
+	testInitLogging()
+	LogDepth(context.Background(), ErrorLog, 1, "test LogDepth")
+	Sync()
+	assert.Contains(t, outputCollects.String(), curFile)
+	assert.Contains(t, outputCollects.String(), "ERROR")
+	assert.Contains(t, outputCollects.String(), "test LogDepth")
+}
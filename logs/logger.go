@@ -0,0 +1,83 @@
+package logs
+
+import (
+	"context"
+)
+
+// Logger is the log instance interface, implemented by NewLogging returned instance and Default().
+// Often used by integrations(http middleware, sql driver...) accept a log instance.
+type Logger interface {
+	Debug(ctx context.Context, message string, fields ...Field)
+	DebugDepth(ctx context.Context, depth int, message string, fields ...Field)
+	Info(ctx context.Context, message string, fields ...Field)
+	InfoDepth(ctx context.Context, depth int, message string, fields ...Field)
+	Warning(ctx context.Context, message string, fields ...Field)
+	WarningDepth(ctx context.Context, depth int, message string, fields ...Field)
+	Error(ctx context.Context, message string, fields ...Field)
+	ErrorDepth(ctx context.Context, depth int, message string, fields ...Field)
+	Fatal(ctx context.Context, message string, fields ...Field)
+	FatalDepth(ctx context.Context, depth int, message string, fields ...Field)
+	Log(ctx context.Context, s Severity, message string, fields ...Field)
+	LogDepth(ctx context.Context, s Severity, depth int, message string, fields ...Field)
+	Sync() []error
+}
+
+// Default return a Logger writes to package level log instance(the one SetOutputs, SetCommonFields... configure).
+func Default() Logger {
+	return globalLogger{}
+}
+
+// globalLogger forward to package level log instance, depth increased by one for itself.
+type globalLogger struct{}
+
+func (globalLogger) Debug(ctx context.Context, message string, fields ...Field) {
+	log().DebugDepth(ctx, 1, message, fields...)
+}
+
+func (globalLogger) DebugDepth(ctx context.Context, depth int, message string, fields ...Field) {
+	log().DebugDepth(ctx, depth+1, message, fields...)
+}
+
+func (globalLogger) Info(ctx context.Context, message string, fields ...Field) {
+	log().InfoDepth(ctx, 1, message, fields...)
+}
+
+func (globalLogger) InfoDepth(ctx context.Context, depth int, message string, fields ...Field) {
+	log().InfoDepth(ctx, depth+1, message, fields...)
+}
+
+func (globalLogger) Warning(ctx context.Context, message string, fields ...Field) {
+	log().WarningDepth(ctx, 1, message, fields...)
+}
+
+func (globalLogger) WarningDepth(ctx context.Context, depth int, message string, fields ...Field) {
+	log().WarningDepth(ctx, depth+1, message, fields...)
+}
+
+func (globalLogger) Error(ctx context.Context, message string, fields ...Field) {
+	log().ErrorDepth(ctx, 1, message, fields...)
+}
+
+func (globalLogger) ErrorDepth(ctx context.Context, depth int, message string, fields ...Field) {
+	log().ErrorDepth(ctx, depth+1, message, fields...)
+}
+
+func (globalLogger) Fatal(ctx context.Context, message string, fields ...Field) {
+	log().FatalDepth(ctx, 1, message, fields...)
+}
+
+func (globalLogger) FatalDepth(ctx context.Context, depth int, message string, fields ...Field) {
+	log().FatalDepth(ctx, depth+1, message, fields...)
+}
+
+func (globalLogger) Log(ctx context.Context, s Severity, message string, fields ...Field) {
+	log().LogDepth(ctx, s, 1, message, fields...)
+}
+
+func (globalLogger) LogDepth(ctx context.Context, s Severity, depth int, message string, fields ...Field) {
+	log().LogDepth(ctx, s, depth+1, message, fields...)
+}
+
+func (globalLogger) Sync() []error {
+	return log().Sync()
+}
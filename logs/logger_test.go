@@ -0,0 +1,36 @@
+package logs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefault(t *testing.T) {
+	var l Logger = Default()
+	testCases := []struct {
+		Log      func()
+		Expected string
+	}{
+		{Log: func() { l.Debug(context.Background(), "test default Debug") }, Expected: "DEBUG"},
+		{Log: func() { l.DebugDepth(context.Background(), 0, "test default DebugDepth") }, Expected: "DEBUG"},
+		{Log: func() { l.Info(context.Background(), "test default Info") }, Expected: "INFO"},
+		{Log: func() { l.InfoDepth(context.Background(), 0, "test default InfoDepth") }, Expected: "INFO"},
+		{Log: func() { l.Warning(context.Background(), "test default Warning") }, Expected: "WARNING"},
+		{Log: func() { l.WarningDepth(context.Background(), 0, "test default WarningDepth") }, Expected: "WARNING"},
+		{Log: func() { l.Error(context.Background(), "test default Error") }, Expected: "ERROR"},
+		{Log: func() { l.ErrorDepth(context.Background(), 0, "test default ErrorDepth") }, Expected: "ERROR"},
+		{Log: func() { l.Fatal(context.Background(), "test default Fatal") }, Expected: "FATAL"},
+		{Log: func() { l.FatalDepth(context.Background(), 0, "test default FatalDepth") }, Expected: "FATAL"},
+		{Log: func() { l.Log(context.Background(), InfoLog, "test default Log") }, Expected: "INFO"},
+		{Log: func() { l.LogDepth(context.Background(), WarningLog, 0, "test default LogDepth") }, Expected: "WARNING"},
+	}
+	for _, testCase := range testCases {
+		testInitLogging()
+		testCase.Log()
+		assert.Nil(t, l.Sync())
+		assert.Contains(t, outputCollects.String(), "logger_test.go")
+		assert.Contains(t, outputCollects.String(), testCase.Expected)
+	}
+}
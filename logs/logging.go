@@ -5,11 +5,26 @@ import (
 	"fmt"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // NewLogging create log instance
 func NewLogging(opts ...Option) *logging {
+	options := newOptions(opts...)
+	l := &logging{}
+	l.snapshot.Store(options)
+	l.notifySyncChan = make(chan struct{}, 0)
+	l.syncFinishChan = make(chan []error, 0)
+	l.swapFinishChan = make(chan []error, 0)
+	l.contentChan = make(chan *Content, options.maxLogChanNum)
+	go l.write()
+	return l
+}
+
+// newOptions apply opts to default options, fill default outputs and common fields when they are empty.
+func newOptions(opts ...Option) *options {
 	options := defaultOptions()
 	for _, opt := range opts {
 		opt(&options)
@@ -20,14 +35,7 @@ func NewLogging(opts ...Option) *logging {
 	if options.commonFields != nil && len(options.commonFields) == 0 {
 		options.commonFields = defaultCommonFields()
 	}
-	l := &logging{
-		options: options,
-	}
-	l.notifySyncChan = make(chan struct{}, 0)
-	l.syncFinishChan = make(chan []error, 0)
-	l.contentChan = make(chan *Content, l.options.maxLogChanNum)
-	go l.write()
-	return l
+	return &options
 }
 
 type options struct {
@@ -36,22 +44,47 @@ type options struct {
 	addDirHeader      bool
 	outputs           []Output
 	formatter         Formatter
-	commonFields      []*commonField
+	commonFields      []*CommonField
 	maxLogChanNum     int
+	contextExtractors []ContextExtractor
+	minLevel          Severity
+	vModule           *VModule
 }
 
 type logging struct {
-	options        options
+	// snapshot current *options, it is never modified after stored, changes store a new copy.
+	snapshot       atomic.Value
 	contentChan    chan *Content
 	notifySyncChan chan struct{}
 	syncFinishChan chan []error
+	// swapMu serialize swaps, pendingSwap is passed to write goroutine by swapContent in contentChan.
+	swapMu         sync.Mutex
+	pendingSwap    *swap
+	swapFinishChan chan []error
+}
+
+// opts return current options snapshot, it must not be modified.
+func (l *logging) opts() *options {
+	return l.snapshot.Load().(*options)
 }
 
 var runtimeCaller = runtime.Caller
 var timeNow = time.Now
 
-func (l *logging) header(ctx context.Context, s Severity, depth int) MessageHeader {
-	_, file, line, ok := runtimeCaller(4 + depth)
+// header create MessageHeader of caller, false is returned when vmodule or min level filters out the caller.
+func (l *logging) header(ctx context.Context, s Severity, depth int) (MessageHeader, bool) {
+	pc, file, line, ok := runtimeCaller(4 + depth)
+	if !ok {
+		file = ""
+	}
+	if !l.opts().levelEnabled(s, pc, file) {
+		return MessageHeader{}, false
+	}
+	return l.callerHeader(ctx, s, file, line, ok), true
+}
+
+// callerHeader create MessageHeader with caller file and line.
+func (l *logging) callerHeader(ctx context.Context, s Severity, file string, line int, ok bool) MessageHeader {
 	if !ok {
 		file = "???"
 		line = 1
@@ -59,14 +92,18 @@ func (l *logging) header(ctx context.Context, s Severity, depth int) MessageHead
 		if slash := strings.LastIndex(file, "/"); slash >= 0 {
 			path := file
 			file = path[slash+1:]
-			if l.options.addDirHeader {
+			if l.opts().addDirHeader {
 				if dirsep := strings.LastIndex(path[:slash], "/"); dirsep >= 0 {
 					file = path[dirsep+1:]
 				}
 			}
 		}
 	}
-	traceID, _ := ctx.Value(l.options.TraceIDIdentifier).(string)
+	traceIDIdentifier := l.opts().TraceIDIdentifier
+	traceID, _ := ctx.Value(traceIDIdentifier).(string)
+	if traceID == "" {
+		checkContextKey(ctx, traceIDIdentifier)
+	}
 	return MessageHeader{
 		Level:   s,
 		TraceID: traceID,
@@ -116,23 +153,51 @@ func (l *logging) FatalDepth(ctx context.Context, depth int, message string, fie
 	l.printDepth(ctx, FatalLog, depth, message, fields...)
 }
 
+func (l *logging) Log(ctx context.Context, s Severity, message string, fields ...Field) {
+	l.print(ctx, s, message, fields...)
+}
+
+func (l *logging) LogDepth(ctx context.Context, s Severity, depth int, message string, fields ...Field) {
+	l.printDepth(ctx, s, depth, message, fields...)
+}
+
 func (l *logging) print(ctx context.Context, s Severity, message string, fields ...Field) {
 	l.printDepth(ctx, s, 1, message, fields...)
 }
 
 func (l *logging) printDepth(ctx context.Context, s Severity, depth int, message string, fields ...Field) {
+	if s < l.opts().lowestLevel() {
+		return
+	}
 	l.output(ctx, s, depth, message, fields...)
 }
 
 func (l *logging) output(ctx context.Context, s Severity, depth int, message string, fields ...Field) {
+	headers, ok := l.header(ctx, s, depth)
+	if !ok {
+		return
+	}
 	content := &Content{
-		Headers: l.header(ctx, s, depth),
+		Headers: headers,
 		Message: message,
 		Fields:  fields,
 	}
+	l.send(ctx, content)
+}
 
-	for _, output := range l.options.outputs { //exists one output this log level, should send to channel
-		if output.IsLevelNeedRecord(s) {
+// send add context extracted infos to content, then send it to channel if any output need it.
+func (l *logging) send(ctx context.Context, content *Content) {
+	options := l.opts()
+	if content.Headers.Level < options.lowestLevel() {
+		return
+	}
+	if extracted := l.extract(ctx, &content.Headers); len(extracted) > 0 {
+		fields := content.Fields
+		content.Fields = append(append(make([]Field, 0, len(fields)+len(extracted)), fields...), extracted...)
+	}
+
+	for _, output := range options.outputs { //exists one output this log level, should send to channel
+		if output.IsLevelNeedRecord(content.Headers.Level) {
 			l.contentChan <- content
 			break
 		} else {
@@ -156,16 +221,9 @@ func (l *logging) write() {
 				if !ok {
 					break
 				}
-				l.writeLog(content)
-			}
-			var errs []error
-			for _, output := range l.options.outputs {
-				err := output.Flush()
-				if err != nil {
-					errs = append(errs, err)
-				}
+				l.handle(content)
 			}
-			l.syncFinishChan <- errs
+			l.syncFinishChan <- flushOutputs(l.opts().outputs)
 			if !ok {
 				fmt.Printf("channel been closed unexpected \n")
 				return
@@ -175,19 +233,40 @@ func (l *logging) write() {
 				fmt.Printf("channel been closed unexpected \n")
 				return
 			}
-			l.writeLog(content)
+			l.handle(content)
+		}
+	}
+}
+
+// handle write content, or apply pending swap when content is swapContent.
+func (l *logging) handle(content *Content) {
+	if content == swapContent {
+		l.applySwap(l.pendingSwap)
+		return
+	}
+	l.writeLog(content)
+}
+
+// flushOutputs flush outputs and return their errors
+func flushOutputs(outputs []Output) []error {
+	var errs []error
+	for _, output := range outputs {
+		err := output.Flush()
+		if err != nil {
+			errs = append(errs, err)
 		}
 	}
+	return errs
 }
 
 func (l *logging) writeLog(content *Content) {
-	buf := l.options.formatter.Format(l.options.commonFields, content)
-	for _, output := range l.options.outputs {
+	options := l.opts()
+	format := lazyFormat(options.formatter, options.commonFields, content)
+	for _, output := range options.outputs {
 		if !output.IsLevelNeedRecord(content.Headers.Level) {
 			continue
 		}
-		_, err := output.Write(buf)
-		if err != nil {
+		if err := writeOutput(output, options.commonFields, content, format); err != nil {
 			fmt.Printf("write to log error %s \n", err)
 		}
 	}
@@ -35,6 +35,7 @@ func TestNewLogging(t *testing.T) {
 func TestLoggingT_header(t *testing.T) {
 	defer func() {
 		runtimeCaller = runtime.Caller
+		timeNow = time.Now
 	}()
 	l := NewLogging()
 	testCases := []struct {
@@ -117,9 +118,10 @@ func TestLoggingT_header(t *testing.T) {
 			return cur
 		}
 		runtimeCaller = testCase.Mock.RuntimeCaller
-		l.options.addDirHeader = testCase.Mock.AddDirHeader
+		l.update(func(o *options) { o.addDirHeader = testCase.Mock.AddDirHeader })
 		testCase.Expected.Time = cur
-		messageHeader := l.header(testCase.Input.Ctx, testCase.Input.S, 0)
+		messageHeader, ok := l.header(testCase.Input.Ctx, testCase.Input.S, 0)
+		assert.True(t, ok)
 		assert.Equal(t, testCase.Expected, messageHeader)
 	}
 }
@@ -224,6 +226,25 @@ func TestLoggingT_FatalDepth(t *testing.T) {
 	assert.Contains(t, outputCollects.String(), "test FatalDepth")
 }
 
+func TestLoggingT_Log(t *testing.T) {
+	l := testNewLogging()
+	l.Log(context.Background(), WarningLog, "test Log", String("category", "test category"))
+	l.Sync()
+	assert.Contains(t, outputCollects.String(), curFileName)
+	assert.Contains(t, outputCollects.String(), "WARNING")
+	assert.Contains(t, outputCollects.String(), "test Log")
+	assert.Contains(t, outputCollects.String(), "test category")
+}
+
+func TestLoggingT_LogDepth(t *testing.T) {
+	l := testNewLogging()
+	l.LogDepth(context.Background(), ErrorLog, 0, "test LogDepth")
+	l.Sync()
+	assert.Contains(t, outputCollects.String(), curFileName)
+	assert.Contains(t, outputCollects.String(), "ERROR")
+	assert.Contains(t, outputCollects.String(), "test LogDepth")
+}
+
 func TestLoggingT_print(t *testing.T) {
 	l := testNewLogging()
 	l.print(context.Background(), InfoLog, "test print", Any("category", "test_category"), String("domain", "feehi.com"))
@@ -256,6 +277,17 @@ func TestLoggingT_output(t *testing.T) {
 
 }
 
+func TestLoggingT_minLevel(t *testing.T) {
+	outputCollects := bytes.Buffer{}
+	l := NewLogging(WithOutput(NewOutPut(AllSeverities, &outputCollects)), WithMinLevel(InfoLog))
+	l.Debug(context.Background(), "test debug dropped")
+	l.send(context.Background(), &Content{Headers: MessageHeader{Level: DebugLog}, Message: "test send dropped"})
+	l.Info(context.Background(), "test info kept")
+	l.Sync()
+	assert.NotContains(t, outputCollects.String(), "dropped")
+	assert.Contains(t, outputCollects.String(), "test info kept")
+}
+
 func TestLoggingT_sync_success(t *testing.T) {
 	outputCollectsInfo := bytes.Buffer{}
 	outputCollectsDebug := bytes.Buffer{}
@@ -303,7 +335,7 @@ func TestLoggingT_write(t *testing.T) {
 			Mock: struct {
 				Logging *logging
 				After   func(l *logging)
-			}{Logging: NewLogging(), After: func(l *logging) {
+			}{Logging: testLoggingWithoutWriter(), After: func(l *logging) {
 				close(l.contentChan)
 			}},
 			Expected: struct{ STDOutput string }{STDOutput: "channel been closed unexpected \n"},
@@ -436,6 +468,13 @@ func TestLoggingT_Sync(t *testing.T) {
 	}
 }
 
+// testLoggingWithoutWriter create log instance without write goroutine, so write can be tested on its own.
+func testLoggingWithoutWriter() *logging {
+	l := &logging{contentChan: make(chan *Content, 1)}
+	l.snapshot.Store(newOptions())
+	return l
+}
+
 func testNewLogging() *logging {
 	outputCollects = bytes.Buffer{}
 	return NewLogging(WithOutput(NewOutPut(AllSeverities, &outputCollects)))
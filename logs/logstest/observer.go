@@ -0,0 +1,188 @@
+// Package logstest helps tests assert on log rows.
+//
+//	logger, observed := logstest.NewObservedLogger()
+//	doSomething(logger)
+//	logger.Sync()
+//	assert.Equal(t, 1, observed.FilterLevel(logs.ErrorLog).FilterField("user_id", "1").Len())
+package logstest
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/feehi.io/gopkg/logs"
+)
+
+// Row a recorded log row
+type Row struct {
+	logs.Content
+	CommonFields []*logs.CommonField
+}
+
+// Field get field value by key, ok is false when not exists
+func (r Row) Field(key string) (value string, ok bool) {
+	for _, field := range r.Fields {
+		if field.Key() == key {
+			return field.Value(), true
+		}
+	}
+	return "", false
+}
+
+// Rows recorded log rows, filters return a new Rows so they can be chained.
+type Rows []Row
+
+// Len return rows count
+func (rs Rows) Len() int {
+	return len(rs)
+}
+
+// Filter return rows match f
+func (rs Rows) Filter(f func(row Row) bool) Rows {
+	filtered := make(Rows, 0, len(rs))
+	for _, row := range rs {
+		if f(row) {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered
+}
+
+// FilterLevel return rows logged with one of levels
+func (rs Rows) FilterLevel(levels ...logs.Severity) Rows {
+	return rs.Filter(func(row Row) bool {
+		for _, level := range levels {
+			if row.Headers.Level == level {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// FilterMessage return rows whose message equals message
+func (rs Rows) FilterMessage(message string) Rows {
+	return rs.Filter(func(row Row) bool {
+		return row.Message == message
+	})
+}
+
+// FilterMessageSnippet return rows whose message contains snippet
+func (rs Rows) FilterMessageSnippet(snippet string) Rows {
+	return rs.Filter(func(row Row) bool {
+		return strings.Contains(row.Message, snippet)
+	})
+}
+
+// FilterField return rows have field key with value
+func (rs Rows) FilterField(key string, value string) Rows {
+	return rs.Filter(func(row Row) bool {
+		v, ok := row.Field(key)
+		return ok && v == value
+	})
+}
+
+// FilterFieldKey return rows have field key, whatever the value is
+func (rs Rows) FilterFieldKey(key string) Rows {
+	return rs.Filter(func(row Row) bool {
+		_, ok := row.Field(key)
+		return ok
+	})
+}
+
+// Messages return messages of rows in order
+func (rs Rows) Messages() []string {
+	messages := make([]string, 0, len(rs))
+	for _, row := range rs {
+		messages = append(messages, row.Message)
+	}
+	return messages
+}
+
+// NewObserver create an output records structured rows of levels instead of writing them.
+// Rows are written by log instance asynchronously, call Sync of the log instance before asserting.
+func NewObserver(levels []logs.Severity) *Observer {
+	return &Observer{Levels: levels}
+}
+
+// NewObservedLogger create a log instance writes all levels to returned observer.
+// opts are applied after the observer output, so more outputs can be added.
+func NewObservedLogger(opts ...logs.Option) (logs.Logger, *Observer) {
+	observer := NewObserver(logs.AllSeverities)
+	return logs.NewLogging(append([]logs.Option{logs.WithOutput(observer)}, opts...)...), observer
+}
+
+// Observer an output records log rows, safe for concurrent use.
+type Observer struct {
+	Levels []logs.Severity
+	mu     sync.Mutex
+	rows   Rows
+}
+
+func (o *Observer) IsLevelNeedRecord(s logs.Severity) bool {
+	for _, l := range o.Levels {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Observer) Flush() error {
+	return nil
+}
+
+// Write record formatted bytes as a row message, it is only called when observer is wrapped by another output.
+func (o *Observer) Write(p []byte) (int, error) {
+	o.record(Row{Content: logs.Content{Message: strings.TrimSuffix(string(p), "\n")}})
+	return len(p), nil
+}
+
+func (o *Observer) WriteContent(commonFields []*logs.CommonField, content *logs.Content) error {
+	o.record(Row{Content: *content, CommonFields: commonFields})
+	return nil
+}
+
+func (o *Observer) record(row Row) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.rows = append(o.rows, row)
+}
+
+// Len return recorded rows count
+func (o *Observer) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.rows)
+}
+
+// All return a copy of recorded rows
+func (o *Observer) All() Rows {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append(make(Rows, 0, len(o.rows)), o.rows...)
+}
+
+// TakeAll return recorded rows and reset observer
+func (o *Observer) TakeAll() Rows {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	rows := o.rows
+	o.rows = nil
+	return rows
+}
+
+// FilterLevel return recorded rows logged with one of levels
+func (o *Observer) FilterLevel(levels ...logs.Severity) Rows {
+	return o.All().FilterLevel(levels...)
+}
+
+// FilterMessage return recorded rows whose message equals message
+func (o *Observer) FilterMessage(message string) Rows {
+	return o.All().FilterMessage(message)
+}
+
+// FilterField return recorded rows have field key with value
+func (o *Observer) FilterField(key string, value string) Rows {
+	return o.All().FilterField(key, value)
+}
@@ -0,0 +1,57 @@
+package logstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/feehi.io/gopkg/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewObservedLogger(t *testing.T) {
+	logger, observed := NewObservedLogger(logs.WithCommonField("instance", "machineA"))
+	ctx := context.WithValue(context.Background(), logs.TraceIDIdentifier, "test_trace_id")
+	logger.Info(ctx, "user login", logs.String("user_id", "1"))
+	logger.Error(ctx, "user login failed", logs.String("user_id", "2"), logs.Err(errors.New("bad password")))
+	logger.Debug(ctx, "cache miss")
+	logger.Sync()
+
+	assert.Equal(t, 3, observed.Len())
+	rows := observed.All()
+	assert.Equal(t, []string{"user login", "user login failed", "cache miss"}, rows.Messages())
+	assert.Equal(t, "test_trace_id", rows[0].Headers.TraceID)
+	assert.Equal(t, "observer_test.go", rows[0].Headers.File)
+	assert.Equal(t, []*logs.CommonField{logs.NewCommonField("instance", "machineA")}, rows[0].CommonFields)
+	value, ok := rows[1].Field("error")
+	assert.True(t, ok)
+	assert.Equal(t, "bad password", value)
+
+	assert.Equal(t, []string{"user login failed"}, observed.FilterLevel(logs.ErrorLog).Messages())
+	assert.Equal(t, []string{"cache miss"}, observed.FilterMessage("cache miss").Messages())
+	assert.Equal(t, []string{"user login failed"}, observed.FilterField("user_id", "2").Messages())
+	assert.Equal(t, 2, rows.FilterFieldKey("user_id").Len())
+	assert.Equal(t, 2, rows.FilterMessageSnippet("login").FilterLevel(logs.InfoLog, logs.ErrorLog).Len())
+	assert.Equal(t, 0, rows.FilterLevel(logs.FatalLog).Len())
+
+	assert.Equal(t, 3, observed.TakeAll().Len())
+	assert.Equal(t, 0, observed.Len())
+	assert.Equal(t, 0, observed.TakeAll().Len())
+}
+
+func TestObserver_levels(t *testing.T) {
+	observer := NewObserver([]logs.Severity{logs.WarningLog})
+	logger := logs.NewLogging(logs.WithOutput(observer))
+	logger.Info(context.Background(), "info")
+	logger.Warning(context.Background(), "warning")
+	logger.Sync()
+	assert.Equal(t, []string{"warning"}, observer.All().Messages())
+}
+
+func TestObserver_Write(t *testing.T) {
+	observer := NewObserver(logs.AllSeverities)
+	n, err := observer.Write([]byte("formatted row\n"))
+	assert.Nil(t, err)
+	assert.Equal(t, 14, n)
+	assert.Equal(t, []string{"formatted row"}, observer.All().Messages())
+}
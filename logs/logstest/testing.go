@@ -0,0 +1,58 @@
+package logstest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/feehi.io/gopkg/logs"
+)
+
+// NewLogger create a log instance writes rows through tb.Log, so rows are only shown for failed tests(or with -v).
+// Logger is synced in tb.Cleanup, rows logged after the test finished are dropped.
+// opts are applied after the tb output, such as logs.WithFormatter(logs.NewJSONFormatter()).
+func NewLogger(tb testing.TB, opts ...logs.Option) logs.Logger {
+	output := &tbOutput{tb: tb, Levels: logs.AllSeverities}
+	l := logs.NewLogging(append([]logs.Option{logs.WithOutput(output)}, opts...)...)
+	tb.Cleanup(func() {
+		l.Sync()
+		output.close()
+	})
+	return l
+}
+
+type tbOutput struct {
+	Levels []logs.Severity
+	tb     testing.TB
+	mu     sync.Mutex
+	closed bool
+}
+
+func (o *tbOutput) IsLevelNeedRecord(s logs.Severity) bool {
+	for _, l := range o.Levels {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *tbOutput) Flush() error {
+	return nil
+}
+
+func (o *tbOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// testing panics when Log is called after test finished
+	if !o.closed {
+		o.tb.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+func (o *tbOutput) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+}
@@ -0,0 +1,45 @@
+package logstest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/feehi.io/gopkg/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeTB record Log and Cleanup calls
+type fakeTB struct {
+	testing.TB
+	logs     []string
+	cleanups []func()
+}
+
+func (tb *fakeTB) Log(args ...interface{}) {
+	tb.logs = append(tb.logs, fmt.Sprint(args...))
+}
+
+func (tb *fakeTB) Cleanup(f func()) {
+	tb.cleanups = append(tb.cleanups, f)
+}
+
+func TestNewLogger(t *testing.T) {
+	tb := &fakeTB{TB: t}
+	logger := NewLogger(tb, logs.WithFormatter(logs.NewStringFormatter("{LEVEL} {MESSAGE}", "", false)))
+	logger.Info(context.Background(), "first")
+	logger.Warning(context.Background(), "second")
+	assert.Equal(t, 1, len(tb.cleanups))
+
+	tb.cleanups[0]()
+	assert.Equal(t, []string{"INFO first", "WARNING second"}, tb.logs)
+
+	logger.Info(context.Background(), "after cleanup")
+	logger.Sync()
+	assert.Equal(t, 2, len(tb.logs))
+}
+
+func TestNewLogger_realTB(t *testing.T) {
+	logger := NewLogger(t)
+	logger.Info(context.Background(), "shown only with -v or on failure")
+}
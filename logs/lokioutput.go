@@ -0,0 +1,270 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LokiConfig config of NewLokiOutput
+type LokiConfig struct {
+	// HTTPConfig URL is push API such as http://loki:3100/loki/api/v1/push. Gzip is used for JSON push only.
+	HTTPConfig
+	// Labels keys of common fields used as stream labels, other common fields are kept in line only.
+	// Keep them low cardinality, each value creates a Loki stream.
+	Labels []string
+	// StaticLabels labels of every stream, such as job
+	StaticLabels map[string]string
+	// LevelLabel label name of severity, default is level
+	LevelLabel string
+	// TenantID sent as X-Scope-OrgID when it is not empty
+	TenantID string
+	// Protobuf whether push snappy encoded protobuf instead of JSON
+	Protobuf bool
+	// Formatter format line, default is JSON formatter
+	Formatter Formatter
+}
+
+// NewLokiOutput create a output pushes rows to Loki push API in batches.
+// Stream labels are severity, StaticLabels and common fields in Labels. Entries of a stream are sorted by
+// MessageHeader.Time, and an entry older than the last pushed entry of its stream is pushed with that time,
+// since Loki rejects out of order entries.
+func NewLokiOutput(levels []Severity, config LokiConfig) (Output, error) {
+	if config.Protobuf {
+		config.Gzip = false
+	}
+	client, err := newHTTPClient(config.HTTPConfig)
+	if err != nil {
+		return nil, err
+	}
+	if config.LevelLabel == "" {
+		config.LevelLabel = "level"
+	}
+	if config.Formatter == nil {
+		config.Formatter = NewJSONFormatter()
+	}
+	o := &lokiOutput{
+		Levels:         levels,
+		config:         config,
+		labels:         map[string]string{},
+		client:         client,
+		lastTimestamps: map[string]int64{},
+	}
+	for _, key := range config.Labels {
+		o.labels[key] = lokiLabelName(key)
+	}
+	o.batcher = client.newBatcher(o.send)
+	return o, nil
+}
+
+type lokiOutput struct {
+	Levels []Severity
+	config LokiConfig
+	// labels label name of allowed common field keys
+	labels  map[string]string
+	client  *httpClient
+	batcher *batcher
+
+	// lastTimestamps last pushed timestamp of streams, it is only used by send, which batcher never runs concurrently
+	lastTimestamps map[string]int64
+}
+
+type lokiEntry struct {
+	labels    string
+	labelSet  map[string]string
+	timestamp int64
+	line      string
+}
+
+func (o *lokiOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+// Write push a formatted row with info level label, StaticLabels and current time
+func (o *lokiOutput) Write(p []byte) (int, error) {
+	if err := o.add(o.labelSet(nil, &MessageHeader{Level: InfoLog}), timeNow().UnixNano(), strings.TrimSuffix(string(p), "\n")); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (o *lokiOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	line := strings.TrimSuffix(string(o.config.Formatter.Format(commonFields, content)), "\n")
+	return o.add(o.labelSet(commonFields, &content.Headers), content.Headers.Time.UnixNano(), line)
+}
+
+func (o *lokiOutput) labelSet(commonFields []*CommonField, headers *MessageHeader) map[string]string {
+	labelSet := make(map[string]string, len(o.config.StaticLabels)+len(o.labels)+1)
+	for key, value := range o.config.StaticLabels {
+		labelSet[lokiLabelName(key)] = value
+	}
+	for _, field := range commonFields {
+		if name, ok := o.labels[field.Key]; ok {
+			labelSet[name] = field.Value
+		}
+	}
+	if headers != nil {
+		labelSet[o.config.LevelLabel] = strings.ToLower(severityName[headers.Level])
+	}
+	return labelSet
+}
+
+func (o *lokiOutput) add(labelSet map[string]string, timestamp int64, line string) error {
+	labels := lokiLabels(labelSet)
+	return o.batcher.add(&lokiEntry{labels: labels, labelSet: labelSet, timestamp: timestamp, line: line}, len(labels)+len(line))
+}
+
+func (o *lokiOutput) Flush() error {
+	return o.batcher.flush()
+}
+
+// Close push current batch and stop interval pushing
+func (o *lokiOutput) Close() error {
+	return o.batcher.close()
+}
+
+type lokiStream struct {
+	labels   string
+	labelSet map[string]string
+	entries  []*lokiEntry
+}
+
+func (o *lokiOutput) send(items []interface{}) error {
+	var streams []*lokiStream
+	index := map[string]*lokiStream{}
+	for _, item := range items {
+		entry := item.(*lokiEntry)
+		stream, ok := index[entry.labels]
+		if !ok {
+			stream = &lokiStream{labels: entry.labels, labelSet: entry.labelSet}
+			index[entry.labels] = stream
+			streams = append(streams, stream)
+		}
+		stream.entries = append(stream.entries, entry)
+	}
+
+	for _, stream := range streams {
+		sort.SliceStable(stream.entries, func(i, j int) bool {
+			return stream.entries[i].timestamp < stream.entries[j].timestamp
+		})
+		last := o.lastTimestamps[stream.labels]
+		for _, entry := range stream.entries {
+			if entry.timestamp < last {
+				entry.timestamp = last
+			}
+			last = entry.timestamp
+		}
+	}
+
+	var body []byte
+	var contentType string
+	headers := map[string]string{}
+	if o.config.TenantID != "" {
+		headers["X-Scope-OrgID"] = o.config.TenantID
+	}
+	if o.config.Protobuf {
+		body, contentType = snappyEncode(encodeLokiProtobuf(streams)), "application/x-protobuf"
+		headers["Content-Encoding"] = "snappy"
+	} else {
+		var err error
+		if body, err = encodeLokiJSON(streams); err != nil {
+			return fmt.Errorf("encode Loki push request error: %s", err)
+		}
+		contentType = "application/json"
+	}
+	if _, err := o.client.post(body, contentType, headers); err != nil {
+		return err
+	}
+	for _, stream := range streams {
+		o.lastTimestamps[stream.labels] = stream.entries[len(stream.entries)-1].timestamp
+	}
+	return nil
+}
+
+func encodeLokiJSON(streams []*lokiStream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	request := struct {
+		Streams []jsonStream `json:"streams"`
+	}{Streams: make([]jsonStream, 0, len(streams))}
+	for _, stream := range streams {
+		values := make([][2]string, 0, len(stream.entries))
+		for _, entry := range stream.entries {
+			values = append(values, [2]string{strconv.FormatInt(entry.timestamp, 10), entry.line})
+		}
+		request.Streams = append(request.Streams, jsonStream{Stream: stream.labelSet, Values: values})
+	}
+	return json.Marshal(request)
+}
+
+// encodeLokiProtobuf encode logproto.PushRequest:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func encodeLokiProtobuf(streams []*lokiStream) []byte {
+	e := &protoEncoder{}
+	for _, stream := range streams {
+		e.message(1, func(e *protoEncoder) {
+			e.string(1, stream.labels)
+			for _, entry := range stream.entries {
+				e.message(2, func(e *protoEncoder) {
+					e.message(1, func(e *protoEncoder) {
+						e.uvarint(1, uint64(entry.timestamp/1e9))
+						e.uvarint(2, uint64(entry.timestamp%1e9))
+					})
+					e.string(2, entry.line)
+				})
+			}
+		})
+	}
+	return e.buf
+}
+
+// lokiLabelValueEscaper escape label value as LogQL string, other characters are kept as they are
+var lokiLabelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// lokiLabels format labels in Prometheus format sorted by name, such as {level="info", service="order"}
+func lokiLabels(labelSet map[string]string) string {
+	names := make([]string, 0, len(labelSet))
+	for name := range labelSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteByte('"')
+		b.WriteString(lokiLabelValueEscaper.Replace(labelSet[name]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// lokiLabelName replace characters not allowed in label name with _ and prefix leading digit with _,
+// such as service.name to service_name
+func lokiLabelName(key string) string {
+	b := make([]byte, 0, len(key)+1)
+	if key != "" && key[0] >= '0' && key[0] <= '9' {
+		b = append(b, '_')
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			b = append(b, c)
+		} else {
+			b = append(b, '_')
+		}
+	}
+	return string(b)
+}
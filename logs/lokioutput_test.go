@@ -0,0 +1,146 @@
+package logs
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testLokiOutput(t *testing.T, config LokiConfig) Output {
+	output, err := NewLokiOutput(AllSeverities, config)
+	assert.Nil(t, err)
+	return output
+}
+
+func testLokiContent(level Severity, t time.Time, message string) *Content {
+	return &Content{Headers: MessageHeader{Level: level, Time: t}, Message: message}
+}
+
+func TestLokiOutput_json(t *testing.T) {
+	server, requests := testHTTPServer(t)
+	defer server.Close()
+	output := testLokiOutput(t, LokiConfig{
+		HTTPConfig:   HTTPConfig{URL: server.URL, BatchInterval: time.Hour},
+		Labels:       []string{"service", "service.version"},
+		StaticLabels: map[string]string{"job": "order"},
+		TenantID:     "tenant",
+		Formatter:    NewStringFormatter("{MESSAGE}", "", false),
+	})
+	defer output.(interface{ Close() error }).Close()
+	commonFields := []*CommonField{NewCommonField("service", "order"), NewCommonField("service.version", "1.0"), NewCommonField("host", "a1")}
+	base := time.Unix(1605884645, 100)
+	contentOutput := output.(ContentOutput)
+	contentOutput.WriteContent(commonFields, testLokiContent(InfoLog, base.Add(time.Second), "info 2"))
+	contentOutput.WriteContent(commonFields, testLokiContent(ErrorLog, base, "error 1"))
+	contentOutput.WriteContent(commonFields, testLokiContent(InfoLog, base, "info 1"))
+	assert.Nil(t, output.Flush())
+
+	rows := requests()
+	if !assert.Equal(t, 1, len(rows)) {
+		return
+	}
+	assert.Equal(t, "application/json", rows[0].Header.Get("Content-Type"))
+	assert.Equal(t, "tenant", rows[0].Header.Get("X-Scope-OrgID"))
+	var request map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(rows[0].Body), &request))
+	assert.Equal(t, map[string]interface{}{"streams": []interface{}{
+		map[string]interface{}{
+			"stream": map[string]interface{}{"job": "order", "level": "info", "service": "order", "service_version": "1.0"},
+			// sorted by time
+			"values": []interface{}{
+				[]interface{}{"1605884645000000100", "info 1"},
+				[]interface{}{"1605884646000000100", "info 2"},
+			},
+		},
+		map[string]interface{}{
+			"stream": map[string]interface{}{"job": "order", "level": "error", "service": "order", "service_version": "1.0"},
+			"values": []interface{}{[]interface{}{"1605884645000000100", "error 1"}},
+		},
+	}}, request)
+
+	// older entry than last pushed entry of its stream is pushed with last pushed time
+	contentOutput.WriteContent(commonFields, testLokiContent(InfoLog, base.Add(-time.Second), "info 0"))
+	assert.Nil(t, output.Flush())
+	rows = requests()
+	if assert.Equal(t, 2, len(rows)) {
+		assert.Contains(t, rows[1].Body, `"values":[["1605884646000000100","info 0"]]`)
+	}
+}
+
+func TestLokiOutput_protobuf(t *testing.T) {
+	server, requests := testHTTPServer(t)
+	defer server.Close()
+	output := testLokiOutput(t, LokiConfig{
+		HTTPConfig: HTTPConfig{URL: server.URL, BatchInterval: time.Hour, Gzip: true},
+		Labels:     []string{"service"},
+		LevelLabel: "severity",
+		Protobuf:   true,
+		Formatter:  NewStringFormatter("{MESSAGE}", "", false),
+	})
+	defer output.(interface{ Close() error }).Close()
+	commonFields := []*CommonField{NewCommonField("service", "order")}
+	output.(ContentOutput).WriteContent(commonFields, testLokiContent(WarningLog, time.Unix(1605884645, 7), `warning "quoted"`))
+	assert.Nil(t, output.Flush())
+
+	rows := requests()
+	if !assert.Equal(t, 1, len(rows)) {
+		return
+	}
+	assert.Equal(t, "application/x-protobuf", rows[0].Header.Get("Content-Type"))
+	assert.Equal(t, "snappy", rows[0].Header.Get("Content-Encoding"))
+	body, err := testSnappyDecode([]byte(rows[0].Body))
+	assert.Nil(t, err)
+	request, err := testDecodeProto(body)
+	assert.Nil(t, err)
+	streams := testProtoMessages(t, request, 1)
+	if !assert.Equal(t, 1, len(streams)) {
+		return
+	}
+	assert.Equal(t, []byte(`{service="order", severity="warning"}`), testProtoValue(streams[0], 1))
+	entries := testProtoMessages(t, streams[0], 2)
+	if assert.Equal(t, 1, len(entries)) {
+		assert.Equal(t, []byte(`warning "quoted"`), testProtoValue(entries[0], 2))
+		timestamp := testProtoMessages(t, entries[0], 1)
+		assert.Equal(t, []testProtoField{{1, uint64(1605884645)}, {2, uint64(7)}}, timestamp[0])
+	}
+}
+
+func TestLokiOutput_write(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	timeNow = func() time.Time {
+		return time.Unix(1605884645, 0)
+	}
+	server, requests := testHTTPServer(t, http.StatusServiceUnavailable)
+	defer server.Close()
+	output := testLokiOutput(t, LokiConfig{HTTPConfig: HTTPConfig{URL: server.URL, MinBackoff: time.Millisecond, BatchInterval: time.Hour}})
+	defer output.(interface{ Close() error }).Close()
+	output.Write([]byte("raw row\n"))
+	assert.Nil(t, output.Flush())
+	rows := requests()
+	if assert.Equal(t, 2, len(rows)) {
+		assert.Equal(t, rows[0].Body, rows[1].Body)
+		assert.Equal(t, `{"streams":[{"stream":{"level":"info"},"values":[["1605884645000000000","raw row"]]}]}`, rows[1].Body)
+	}
+}
+
+func TestLokiLabels(t *testing.T) {
+	testCases := []struct {
+		Input    map[string]string
+		Expected string
+	}{
+		{Input: map[string]string{}, Expected: "{}"},
+		{Input: map[string]string{"level": "info", "app": "order"}, Expected: `{app="order", level="info"}`},
+		{Input: map[string]string{"path": `c:\logs "a"` + "\n"}, Expected: `{path="c:\\logs \"a\"\n"}`},
+		{Input: map[string]string{"path": "日志\t"}, Expected: "{path=\"日志\t\"}"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, lokiLabels(testCase.Input))
+	}
+	assert.Equal(t, "service_name", lokiLabelName("service.name"))
+	assert.Equal(t, "_1a_b", lokiLabelName("1a-b"))
+}
@@ -6,30 +6,33 @@ import (
 )
 
 // NewCommonField create common field, often used for identify which machine generate this log row in cluster.
-func NewCommonField(key string, value string) *commonField {
-	return &commonField{
+func NewCommonField(key string, value string) *CommonField {
+	return &CommonField{
 		Key:   key,
 		Value: value,
 	}
 }
 
-type commonField struct {
+// CommonField is a key value pair logged with every row of a log instance.
+type CommonField struct {
 	Key   string `json:"ContextKey"`
 	Value string `json:"value"`
 }
 
-func (field commonField) MarshalJSON() ([]byte, error) {
+func (field CommonField) MarshalJSON() ([]byte, error) {
 	m := map[string]string{field.Key: field.Value}
 	return json.Marshal(m)
 }
 
 // MessageHeader collect log message infos.
 type MessageHeader struct {
-	Level   Severity  `json:"level"`
-	TraceID string    `json:"trace_id"`
-	Time    time.Time `json:"time"`
-	Line    int       `json:"line"`
-	File    string    `json:"file"`
+	Level      Severity  `json:"level"`
+	TraceID    string    `json:"trace_id"`
+	SpanID     string    `json:"span_id,omitempty"`
+	TraceFlags byte      `json:"trace_flags,omitempty"`
+	Time       time.Time `json:"time"`
+	Line       int       `json:"line"`
+	File       string    `json:"file"`
 }
 
 // Content full log infos.
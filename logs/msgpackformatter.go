@@ -0,0 +1,319 @@
+package logs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// NewMsgPackFormatter create a MsgPackFormatter
+func NewMsgPackFormatter() *MsgPackFormatter {
+	return &MsgPackFormatter{}
+}
+
+// MsgPackFormatter format log to a MessagePack row, prefixed with 4 bytes big endian length.
+// Rows can be read back with NewMsgPackDecoder.
+type MsgPackFormatter struct {
+}
+
+// Format format log to a length prefixed MessagePack row
+func (f MsgPackFormatter) Format(commonFields []*CommonField, content *Content) []byte {
+	e := &msgpackEncoder{buf: make([]byte, 0, 256)}
+	encodeRecord(e, commonFields, content)
+	return frame(e.bytes())
+}
+
+// msgpackTimestampExt MessagePack timestamp extension type
+const msgpackTimestampExt = -1
+
+type msgpackEncoder struct {
+	buf []byte
+}
+
+func (e *msgpackEncoder) bytes() []byte {
+	return e.buf
+}
+
+func (e *msgpackEncoder) mapHeader(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xde, byte(n>>8), byte(n))
+	default:
+		e.buf = append(e.buf, 0xdf)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) arrayHeader(n int) {
+	switch {
+	case n < 16:
+		e.buf = append(e.buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xdc, byte(n>>8), byte(n))
+	default:
+		e.buf = append(e.buf, 0xdd)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) str(s string) {
+	n := len(s)
+	switch {
+	case n < 32:
+		e.buf = append(e.buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xda, byte(n>>8), byte(n))
+	default:
+		e.buf = append(e.buf, 0xdb)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+	e.buf = append(e.buf, s...)
+}
+
+func (e *msgpackEncoder) binHeader(n int) {
+	switch {
+	case n <= math.MaxUint8:
+		e.buf = append(e.buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		e.buf = append(e.buf, 0xc5, byte(n>>8), byte(n))
+	default:
+		e.buf = append(e.buf, 0xc6)
+		e.buf = appendUint32(e.buf, uint32(n))
+	}
+}
+
+func (e *msgpackEncoder) int(i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		e.buf = append(e.buf, byte(i))
+	case i < 0 && i >= -32:
+		e.buf = append(e.buf, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		e.buf = append(e.buf, 0xd1, byte(i>>8), byte(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		e.buf = append(e.buf, 0xd2)
+		e.buf = appendUint32(e.buf, uint32(i))
+	default:
+		e.buf = append(e.buf, 0xd3)
+		e.buf = appendUint64(e.buf, uint64(i))
+	}
+}
+
+// time encode t with timestamp 96 format: ext8 | 12 | -1 | nanoseconds uint32 | seconds int64
+func (e *msgpackEncoder) time(t time.Time) {
+	e.buf = append(e.buf, 0xc7, 12, 0xff) // 0xff is msgpackTimestampExt(-1)
+	e.buf = appendUint32(e.buf, uint32(t.Nanosecond()))
+	e.buf = appendUint64(e.buf, uint64(t.Unix()))
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	return append(b, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32), byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// unmarshalMsgPack decode a MessagePack value.
+// Maps are decoded to map[string]interface{}, arrays to []interface{}, integers to int64(or uint64 when overflow int64),
+// timestamps to time.Time.
+func unmarshalMsgPack(b []byte) (interface{}, error) {
+	d := &msgpackDecoder{buf: b}
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(d.buf) {
+		return nil, fmt.Errorf("msgpack: %d trailing bytes", len(d.buf)-d.off)
+	}
+	return v, nil
+}
+
+type msgpackDecoder struct {
+	buf []byte
+	off int
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.buf)-d.off < n {
+		return nil, fmt.Errorf("msgpack: unexpected end of data at offset %d", d.off)
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (d *msgpackDecoder) value() (interface{}, error) {
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	c := head[0]
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return d.mapValue(int(c & 0x0f))
+	case c&0xf0 == 0x90:
+		return d.arrayValue(int(c & 0x0f))
+	case c&0xe0 == 0xa0:
+		return d.strValue(int(c & 0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (c - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), b...), nil
+	case 0xc7, 0xc8, 0xc9:
+		n, err := d.uint(1 << (c - 0xc7))
+		if err != nil {
+			return nil, err
+		}
+		return d.extValue(int(n))
+	case 0xca:
+		v, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.uint(8)
+		return math.Float64frombits(v), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		v, err := d.uint(1 << (c - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		if v > math.MaxInt64 {
+			return v, nil
+		}
+		return int64(v), nil
+	case 0xd0:
+		v, err := d.uint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := d.uint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := d.uint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := d.uint(8)
+		return int64(v), err
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.extValue(1 << (c - 0xd4))
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.strValue(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.arrayValue(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(int(n))
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type 0x%x at offset %d", c, d.off-1)
+}
+
+func (d *msgpackDecoder) strValue(n int) (interface{}, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *msgpackDecoder) arrayValue(n int) (interface{}, error) {
+	if n > len(d.buf)-d.off {
+		return nil, fmt.Errorf("msgpack: array length %d exceeds data", n)
+	}
+	items := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+func (d *msgpackDecoder) mapValue(n int) (interface{}, error) {
+	if n > len(d.buf)-d.off {
+		return nil, fmt.Errorf("msgpack: map length %d exceeds data", n)
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		k, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprintf("%v", k)] = v
+	}
+	return m, nil
+}
+
+func (d *msgpackDecoder) extValue(n int) (interface{}, error) {
+	typ, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	if int8(typ[0]) != msgpackTimestampExt {
+		return append([]byte(nil), data...), nil
+	}
+	switch n {
+	case 4:
+		return time.Unix(int64(binary.BigEndian.Uint32(data)), 0).UTC(), nil
+	case 8:
+		v := binary.BigEndian.Uint64(data)
+		return time.Unix(int64(v&0x3ffffffff), int64(v>>34)).UTC(), nil
+	case 12:
+		return time.Unix(int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data))).UTC(), nil
+	}
+	return nil, fmt.Errorf("msgpack: invalid timestamp length %d", n)
+}
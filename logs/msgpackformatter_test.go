@@ -0,0 +1,76 @@
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMsgPackFormatter(t *testing.T) {
+	assert.NotNil(t, NewMsgPackFormatter())
+}
+
+func TestMsgPackEncoder(t *testing.T) {
+	testCases := []struct {
+		Encode   func(e *msgpackEncoder)
+		Expected []byte
+	}{
+		{Encode: func(e *msgpackEncoder) { e.int(1) }, Expected: []byte{0x01}},
+		{Encode: func(e *msgpackEncoder) { e.int(-1) }, Expected: []byte{0xff}},
+		{Encode: func(e *msgpackEncoder) { e.int(300) }, Expected: []byte{0xd1, 0x01, 0x2c}},
+		{Encode: func(e *msgpackEncoder) { e.int(-70000) }, Expected: []byte{0xd2, 0xff, 0xfe, 0xee, 0x90}},
+		{Encode: func(e *msgpackEncoder) { e.str("a") }, Expected: []byte{0xa1, 'a'}},
+		{Encode: func(e *msgpackEncoder) { e.mapHeader(1) }, Expected: []byte{0x81}},
+		{Encode: func(e *msgpackEncoder) { e.arrayHeader(16) }, Expected: []byte{0xdc, 0x00, 0x10}},
+		{Encode: func(e *msgpackEncoder) { e.binHeader(2) }, Expected: []byte{0xc4, 0x02}},
+		{Encode: func(e *msgpackEncoder) { e.binHeader(256) }, Expected: []byte{0xc5, 0x01, 0x00}},
+		{
+			Encode:   func(e *msgpackEncoder) { e.eventTime(time.Unix(1, 2)) },
+			Expected: []byte{0xd7, 0x00, 0, 0, 0, 1, 0, 0, 0, 2},
+		},
+		{
+			Encode:   func(e *msgpackEncoder) { e.time(time.Unix(1, 2)) },
+			Expected: []byte{0xc7, 12, 0xff, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 1},
+		},
+	}
+	for _, testCase := range testCases {
+		e := &msgpackEncoder{}
+		testCase.Encode(e)
+		assert.Equal(t, testCase.Expected, e.bytes())
+	}
+}
+
+func TestUnmarshalMsgPack(t *testing.T) {
+	testCases := []struct {
+		Input    []byte
+		Expected interface{}
+		Error    bool
+	}{
+		{Input: []byte{0xc0}, Expected: nil},
+		{Input: []byte{0xc3}, Expected: true},
+		{Input: []byte{0xcc, 0xff}, Expected: int64(255)},
+		{Input: []byte{0xd0, 0x80}, Expected: int64(-128)},
+		{Input: []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, Expected: 1.5},
+		{Input: []byte{0xc4, 0x02, 0x01, 0x02}, Expected: []byte{0x01, 0x02}},
+		{Input: []byte{0x92, 0x01, 0xa1, 'a'}, Expected: []interface{}{int64(1), "a"}},
+		{Input: []byte{0x81, 0x01, 0x02}, Expected: map[string]interface{}{"1": int64(2)}},
+		{Input: []byte{0xd6, 0xff, 0, 0, 0, 1}, Expected: time.Unix(1, 0).UTC()},
+		{Input: []byte{0x92, 0x01}, Error: true},
+		{Input: []byte{0x01, 0x01}, Error: true},
+		{Input: []byte{0xc1}, Error: true},
+	}
+	for _, testCase := range testCases {
+		v, err := unmarshalMsgPack(testCase.Input)
+		if testCase.Error {
+			assert.NotNil(t, err, testCase.Input)
+		} else {
+			assert.Nil(t, err, testCase.Input)
+			assert.Equal(t, testCase.Expected, v)
+		}
+	}
+}
+
+func BenchmarkMsgPackFormatter_Format(b *testing.B) {
+	benchmarkFormatter(b, NewMsgPackFormatter())
+}
@@ -0,0 +1,366 @@
+package logs
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// NetworkFraming how rows are delimited on a network connection
+type NetworkFraming int
+
+const (
+	// NewlineFraming rows end with "\n", it is appended when formatted row does not end with it
+	NewlineFraming NetworkFraming = iota
+	// LengthPrefixFraming rows are prefixed with their length in 4 bytes big endian, trailing "\n" is removed
+	LengthPrefixFraming
+	// NullFraming rows end with a null byte instead of trailing "\n", such as GELF over TCP
+	NullFraming
+)
+
+const (
+	// DefaultNetworkBufferSize default max bytes of rows buffered while disconnected, 1MB
+	DefaultNetworkBufferSize = 1 << 20
+	// defaultNetworkFlushSize rows are sent once buffered bytes reach it, like bufio.Writer
+	defaultNetworkFlushSize = 4096
+)
+
+// NetworkConfig config of NewNetworkOutput
+type NetworkConfig struct {
+	// Network one of tcp, tcp4, tcp6, udp, udp4, udp6, unix, unixgram
+	Network string
+	// Address such as 127.0.0.1:5170 or /var/run/collector.sock
+	Address string
+	// Framing how rows are delimited, default is NewlineFraming. Datagram networks send a row per datagram.
+	Framing NetworkFraming
+	// TLS connect with TLS when it is not nil, only for stream networks
+	TLS *tls.Config
+	// DialTimeout default is 5s
+	DialTimeout time.Duration
+	// WriteTimeout default is 5s
+	WriteTimeout time.Duration
+	// MinBackoff first reconnect delay, doubled for every failure up to MaxBackoff, default is 100ms
+	MinBackoff time.Duration
+	// MaxBackoff default is 30s
+	MaxBackoff time.Duration
+	// BufferSize max bytes of rows buffered while disconnected, rows over it are dropped, default is DefaultNetworkBufferSize
+	BufferSize int
+}
+
+var randInt63n = rand.Int63n
+
+// NewNetworkOutput create a output writes rows to a network connection.
+// Connection is dialed in background once rows reach 4KB, and redialed with exponential backoff and jitter after it fails,
+// so writes never wait for dialing. Flush, HealthCheck and Close dial synchronously when it is not connected.
+// Rows are buffered up to BufferSize while disconnected, a row is never split across connections.
+// Flush sends buffered rows and returns connection errors and count of dropped rows.
+func NewNetworkOutput(levels []Severity, config NetworkConfig) (Output, error) {
+	switch config.Network {
+	case "tcp", "tcp4", "tcp6", "unix":
+	case "udp", "udp4", "udp6", "unixgram":
+		if config.TLS != nil {
+			return nil, fmt.Errorf("log network output TLS is not supported for %s", config.Network)
+		}
+	default:
+		return nil, fmt.Errorf("unknown log network output network %q, should be one of tcp, udp, unix", config.Network)
+	}
+	if config.Address == "" {
+		return nil, fmt.Errorf("log network output address is empty")
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = 5 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 5 * time.Second
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = 30 * time.Second
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultNetworkBufferSize
+	}
+	return &networkOutput{Levels: levels, config: config}, nil
+}
+
+type networkOutput struct {
+	Levels []Severity
+	config NetworkConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+	// rows framed rows not sent yet, size is their total bytes
+	rows [][]byte
+	size int
+	// backoff delay of next reconnect, nextDial is when next dial is allowed
+	backoff  time.Duration
+	nextDial time.Time
+	// dialing is closed when background dial finishes, nil when it is not dialing
+	dialing chan struct{}
+	lastErr error
+	dropped int
+	closed  bool
+}
+
+func (o *networkOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+// Write buffer a formatted row, buffered rows are sent once they reach 4KB.
+func (o *networkOutput) Write(p []byte) (int, error) {
+	if err := o.add(o.frame(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// add buffer framed rows of a message, they are dropped together when buffer is full.
+func (o *networkOutput) add(rows ...[]byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return fmt.Errorf("log network output %s://%s is closed", o.config.Network, o.config.Address)
+	}
+	size := 0
+	for _, row := range rows {
+		size += len(row)
+	}
+	if o.size+size > o.config.BufferSize {
+		o.dropped++
+		return nil
+	}
+	o.rows = append(o.rows, rows...)
+	o.size += size
+	if o.size >= defaultNetworkFlushSize {
+		if o.conn == nil {
+			o.dialBackground()
+			return nil
+		}
+		o.send()
+	}
+	return nil
+}
+
+func (o *networkOutput) frame(p []byte) []byte {
+	switch o.config.Framing {
+	case LengthPrefixFraming:
+		p = bytes.TrimSuffix(p, []byte("\n"))
+		row := make([]byte, 4+len(p))
+		binary.BigEndian.PutUint32(row, uint32(len(p)))
+		copy(row[4:], p)
+		return row
+	case NullFraming:
+		p = bytes.TrimSuffix(p, []byte("\n"))
+		row := make([]byte, len(p), len(p)+1)
+		copy(row, p)
+		return append(row, 0)
+	}
+	row := make([]byte, len(p), len(p)+1)
+	copy(row, p)
+	if !bytes.HasSuffix(row, []byte("\n")) {
+		row = append(row, '\n')
+	}
+	return row
+}
+
+// Flush send buffered rows, error is returned when they can not be sent or rows were dropped since last Flush.
+func (o *networkOutput) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.waitDial()
+	err := o.send()
+	if o.dropped > 0 {
+		dropped := o.dropped
+		o.dropped = 0
+		if err == nil {
+			return fmt.Errorf("log network output %s://%s buffer is full, %d rows dropped", o.config.Network, o.config.Address, dropped)
+		}
+		return fmt.Errorf("%s, %d rows dropped", err, dropped)
+	}
+	return err
+}
+
+// HealthCheck dial when it is not connected and backoff allows, error is returned when it is not connected.
+func (o *networkOutput) HealthCheck() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.waitDial()
+	if o.conn != nil {
+		return nil
+	}
+	return o.dial()
+}
+
+// send write buffered rows, connection is dialed synchronously when backoff allows. It must be called with mu held.
+func (o *networkOutput) send() error {
+	if len(o.rows) == 0 {
+		return nil
+	}
+	if o.conn == nil {
+		if err := o.dial(); err != nil {
+			return err
+		}
+	}
+	o.conn.SetWriteDeadline(timeNow().Add(o.config.WriteTimeout))
+	sent, err := o.write()
+	for _, row := range o.rows[:sent] {
+		o.size -= len(row)
+	}
+	o.rows = o.rows[sent:]
+	if err != nil {
+		o.conn.Close()
+		o.conn = nil
+		o.fail(err)
+		return o.lastErr
+	}
+	return nil
+}
+
+// write write rows to conn and return count of rows sent, a partly written row is counted as sent and dropped,
+// since the rest of it can not be resent on a new connection.
+func (o *networkOutput) write() (int, error) {
+	switch o.config.Network {
+	case "udp", "udp4", "udp6", "unixgram":
+		for i, row := range o.rows {
+			if _, err := o.conn.Write(row); err != nil {
+				return i, err
+			}
+		}
+		return len(o.rows), nil
+	}
+	buffers := net.Buffers(append([][]byte(nil), o.rows...))
+	n, err := buffers.WriteTo(o.conn)
+	if err == nil {
+		return len(o.rows), nil
+	}
+	sent := 0
+	for _, row := range o.rows {
+		if n <= 0 {
+			break
+		}
+		if n < int64(len(row)) {
+			o.dropped++
+		}
+		n -= int64(len(row))
+		sent++
+	}
+	return sent, err
+}
+
+// dial connect when backoff allows. It must be called with mu held.
+func (o *networkOutput) dial() error {
+	if timeNow().Before(o.nextDial) {
+		return o.lastErr
+	}
+	return o.connected(o.connect())
+}
+
+// dialBackground connect in a new goroutine when backoff allows and it is not dialing,
+// buffered rows are sent once connected. It must be called with mu held.
+func (o *networkOutput) dialBackground() {
+	if o.dialing != nil || timeNow().Before(o.nextDial) {
+		return
+	}
+	done := make(chan struct{})
+	o.dialing = done
+	go func() {
+		conn, err := o.connect()
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.dialing = nil
+		close(done)
+		if o.connected(conn, err) == nil && o.size >= defaultNetworkFlushSize {
+			o.send()
+		}
+	}()
+}
+
+// waitDial wait for background dial to finish, mu is released while waiting. It must be called with mu held.
+func (o *networkOutput) waitDial() {
+	for o.dialing != nil {
+		done := o.dialing
+		o.mu.Unlock()
+		<-done
+		o.mu.Lock()
+	}
+}
+
+func (o *networkOutput) connect() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: o.config.DialTimeout}
+	if o.config.TLS != nil {
+		return tls.DialWithDialer(dialer, o.config.Network, o.config.Address, o.config.TLS)
+	}
+	return dialer.Dial(o.config.Network, o.config.Address)
+}
+
+// connected record result of connect. It must be called with mu held.
+func (o *networkOutput) connected(conn net.Conn, err error) error {
+	if err != nil {
+		o.fail(fmt.Errorf("dial: %s", err))
+		return o.lastErr
+	}
+	o.conn = conn
+	o.backoff = 0
+	o.nextDial = time.Time{}
+	o.lastErr = nil
+	return nil
+}
+
+// fail record error and delay next dial with exponential backoff and jitter.
+func (o *networkOutput) fail(err error) {
+	o.lastErr = fmt.Errorf("log network output %s://%s error: %s", o.config.Network, o.config.Address, err)
+	o.backoff = nextBackoff(o.backoff, o.config.MinBackoff, o.config.MaxBackoff)
+	o.nextDial = timeNow().Add(jitter(o.backoff))
+}
+
+// nextBackoff double backoff and keep it in [min, max]
+func nextBackoff(backoff time.Duration, min time.Duration, max time.Duration) time.Duration {
+	if backoff < min {
+		backoff = min
+	} else {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return backoff
+}
+
+// jitter return a random duration in [d/2, d], so clients reconnect at different times.
+func jitter(d time.Duration) time.Duration {
+	half := int64(d / 2)
+	if half <= 0 {
+		return d
+	}
+	return d - time.Duration(half) + time.Duration(randInt63n(half+1))
+}
+
+// Close send buffered rows and close connection, later writes return error.
+func (o *networkOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	o.waitDial()
+	o.nextDial = time.Time{}
+	err := o.send()
+	if o.conn != nil {
+		if closeErr := o.conn.Close(); err == nil {
+			err = closeErr
+		}
+		o.conn = nil
+	}
+	return err
+}
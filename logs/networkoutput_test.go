@@ -0,0 +1,321 @@
+package logs
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	mrand "math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLineServer accept connections and send received lines to returned channel
+func testLineServer(t *testing.T, listener net.Listener, conns chan<- net.Conn) <-chan string {
+	lines := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			if conns != nil {
+				conns <- conn
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					lines <- line
+				}
+			}()
+		}
+	}()
+	return lines
+}
+
+func testReceive(t *testing.T, lines <-chan string) string {
+	select {
+	case line := <-lines:
+		return line
+	case <-time.After(5 * time.Second):
+		t.Fatal("no row received")
+	}
+	return ""
+}
+
+func TestNewNetworkOutput_error(t *testing.T) {
+	testCases := []struct {
+		Input    NetworkConfig
+		Expected string
+	}{
+		{Input: NetworkConfig{Network: "http", Address: "127.0.0.1:80"}, Expected: `unknown log network output network "http", should be one of tcp, udp, unix`},
+		{Input: NetworkConfig{Network: "tcp"}, Expected: "log network output address is empty"},
+		{Input: NetworkConfig{Network: "udp", Address: "127.0.0.1:514", TLS: &tls.Config{}}, Expected: "log network output TLS is not supported for udp"},
+	}
+	for _, testCase := range testCases {
+		_, err := NewNetworkOutput(AllSeverities, testCase.Input)
+		if assert.NotNil(t, err) {
+			assert.Equal(t, testCase.Expected, err.Error())
+		}
+	}
+}
+
+func TestNetworkOutput_reconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	conns := make(chan net.Conn, 2)
+	lines := testLineServer(t, listener, conns)
+
+	output, err := NewNetworkOutput(AllSeverities, NetworkConfig{Network: "tcp", Address: listener.Addr().String(), MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	assert.Nil(t, err)
+	defer output.(io.Closer).Close()
+
+	output.Write([]byte("first row"))
+	assert.Nil(t, output.Flush())
+	assert.Equal(t, "first row\n", testReceive(t, lines))
+
+	// server drops connection, rows are sent on a new connection once the broken one is detected
+	(<-conns).Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(conns) == 0 && time.Now().Before(deadline) {
+		output.Write([]byte("row after disconnect\n"))
+		output.Flush()
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 1, len(conns))
+	assert.Equal(t, "row after disconnect\n", testReceive(t, lines))
+}
+
+func TestNetworkOutput_buffer(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	cur := time.Now()
+	timeNow = func() time.Time {
+		return cur
+	}
+	output, err := NewNetworkOutput(AllSeverities, NetworkConfig{Network: "tcp", Address: address, BufferSize: 20, MinBackoff: time.Second})
+	assert.Nil(t, err)
+	defer output.(io.Closer).Close()
+
+	output.Write([]byte("row 1\n"))
+	output.Write([]byte("row 2\n"))
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "log network output tcp://"+address+" error: dial: "), err.Error())
+	}
+	// over buffer size
+	output.Write([]byte("row 3\n"))
+	output.Write([]byte("row 4\n"))
+	// next dial waits for backoff, last error is returned with dropped rows
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.HasSuffix(err.Error(), ", 1 rows dropped"), err.Error())
+	}
+
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Skipf("listen %s again error %s", address, err)
+	}
+	defer listener.Close()
+	lines := testLineServer(t, listener, nil)
+	cur = cur.Add(2 * time.Second)
+	assert.Nil(t, output.Flush())
+	assert.Equal(t, "row 1\n", testReceive(t, lines))
+	assert.Equal(t, "row 2\n", testReceive(t, lines))
+	assert.Equal(t, "row 3\n", testReceive(t, lines))
+}
+
+func TestNetworkOutput_dialBackground(t *testing.T) {
+	// server accepts connections but never completes TLS handshake
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	testLineServer(t, listener, nil)
+	output, err := NewNetworkOutput(AllSeverities, NetworkConfig{Network: "tcp", Address: listener.Addr().String(), TLS: &tls.Config{}, DialTimeout: 200 * time.Millisecond})
+	assert.Nil(t, err)
+	defer output.(io.Closer).Close()
+
+	start := time.Now()
+	_, err = output.Write([]byte(strings.Repeat("a", defaultNetworkFlushSize) + "\n"))
+	assert.Nil(t, err)
+	assert.True(t, time.Since(start) < 100*time.Millisecond, time.Since(start))
+	// Flush waits for background dial
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "error: dial: ")
+	}
+	assert.True(t, time.Since(start) >= 200*time.Millisecond, time.Since(start))
+}
+
+func TestNetworkOutput_lengthPrefix(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	o := &networkOutput{config: NetworkConfig{Network: "tcp", Address: "pipe", Framing: LengthPrefixFraming, BufferSize: DefaultNetworkBufferSize, WriteTimeout: time.Second}, conn: client}
+
+	received := make(chan []byte, 1)
+	go func() {
+		b := make([]byte, 4+len("row"))
+		io.ReadFull(server, b)
+		received <- b
+	}()
+	o.Write([]byte("row\n"))
+	assert.Nil(t, o.Flush())
+	b := <-received
+	assert.Equal(t, uint32(3), binary.BigEndian.Uint32(b))
+	assert.Equal(t, "row", string(b[4:]))
+	assert.Nil(t, o.Close())
+	_, err := o.Write([]byte("closed"))
+	assert.NotNil(t, err)
+}
+
+func TestNetworkOutput_udp(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	output, err := NewNetworkOutput(AllSeverities, NetworkConfig{Network: "udp", Address: conn.LocalAddr().String()})
+	assert.Nil(t, err)
+	output.Write([]byte("datagram 1\n"))
+	output.Write([]byte("datagram 2"))
+	assert.Nil(t, output.Flush())
+
+	b := make([]byte, 100)
+	for _, expected := range []string{"datagram 1\n", "datagram 2\n"} {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(b)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, string(b[:n]))
+	}
+}
+
+// testWriteCertificate write a self signed certificate of 127.0.0.1 to dir, return cert and key file name
+func testWriteCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "logs test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	assert.Nil(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.Nil(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestNetworkOutput_tls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "network")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := testWriteCertificate(t, dir)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.Nil(t, err)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	assert.Nil(t, err)
+	defer listener.Close()
+	lines := testLineServer(t, listener, nil)
+
+	l, err := NewLoggingFromConfig(&Config{
+		Formatter: &FormatterConfig{Type: "string", Template: "{MESSAGE}"},
+		Outputs:   []OutputConfig{{Type: "network", Network: "tcp", Address: listener.Addr().String(), TLS: &TLSConfig{CAFile: certFile}}},
+	})
+	assert.Nil(t, err)
+	l.Info(context.Background(), "tls row")
+	assert.Nil(t, l.Sync())
+	assert.Equal(t, "tls row\n", testReceive(t, lines))
+
+	// server certificate is not trusted without CA
+	untrusted, err := NewNetworkOutput(AllSeverities, NetworkConfig{Network: "tcp", Address: listener.Addr().String(), TLS: &tls.Config{}})
+	assert.Nil(t, err)
+	untrusted.Write([]byte("untrusted row"))
+	assert.NotNil(t, untrusted.Flush())
+}
+
+func TestNextBackoff(t *testing.T) {
+	testCases := []struct {
+		Input    time.Duration
+		Expected time.Duration
+	}{
+		{Input: 0, Expected: 100 * time.Millisecond},
+		{Input: 100 * time.Millisecond, Expected: 200 * time.Millisecond},
+		{Input: 800 * time.Millisecond, Expected: time.Second},
+		{Input: time.Second, Expected: time.Second},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, nextBackoff(testCase.Input, 100*time.Millisecond, time.Second))
+	}
+}
+
+func TestJitter(t *testing.T) {
+	defer func() {
+		randInt63n = mrand.Int63n
+	}()
+	randInt63n = func(n int64) int64 {
+		return n - 1
+	}
+	assert.Equal(t, time.Second, jitter(time.Second))
+	randInt63n = func(n int64) int64 {
+		return 0
+	}
+	assert.Equal(t, 500*time.Millisecond, jitter(time.Second))
+	assert.Equal(t, time.Duration(1), jitter(1))
+}
+
+func TestNetworkOutput_healthCheck(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+	output, err := NewNetworkOutput(AllSeverities, NetworkConfig{Network: "tcp", Address: address, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	assert.NotNil(t, output.(HealthChecker).HealthCheck())
+
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Skip("address is reused: ", err)
+	}
+	defer listener.Close()
+	testLineServer(t, listener, nil)
+	time.Sleep(2 * time.Millisecond)
+	assert.Nil(t, output.(HealthChecker).HealthCheck())
+}
@@ -22,7 +22,7 @@ func WithAddDirHeader(addDirHeader bool) Option {
 // Often used for identify which machine generate that log row.
 func WithCommonField(key string, value string) Option {
 	return func(o *options) {
-		o.commonFields = append(o.commonFields, &commonField{Key: key, Value: value})
+		o.commonFields = append(o.commonFields, &CommonField{Key: key, Value: value})
 	}
 }
 
@@ -49,3 +49,28 @@ func WithMaxLogChanNum(maxLogChanNum int) Option {
 		o.maxLogChanNum = maxLogChanNum
 	}
 }
+
+// WithContextExtractor add a context extractor.
+// Can called multi times, extractors run in order, later non-empty trace infos override earlier ones.
+// Such as WithContextExtractor(TraceParentExtractor) or an OpenTelemetry span context extractor.
+func WithContextExtractor(extractor ContextExtractor) Option {
+	return func(o *options) {
+		o.contextExtractors = append(o.contextExtractors, extractor)
+	}
+}
+
+// WithMinLevel set min log level, rows below it are dropped before caller info is collected.
+// Such as WithMinLevel(InfoLog) drops debug rows for all outputs.
+func WithMinLevel(minLevel Severity) Option {
+	return func(o *options) {
+		o.minLevel = minLevel
+	}
+}
+
+// WithVModule set per caller file min levels, they override min level for matched files.
+// Such as WithVModule(vModule) with vModule parsed by ParseVModule("storage/*=debug,http=warning").
+func WithVModule(vModule *VModule) Option {
+	return func(o *options) {
+		o.vModule = vModule
+	}
+}
@@ -67,3 +67,18 @@ func TestWithMaxLogChanNum(t *testing.T) {
 
 	assert.Equal(t, 1, o.maxLogChanNum)
 }
+
+func TestWithContextExtractor(t *testing.T) {
+	option := WithContextExtractor(TraceParentExtractor)
+	o := options{}
+	option(&o)
+	assert.Equal(t, 1, len(o.contextExtractors))
+}
+
+func TestWithMinLevel(t *testing.T) {
+	option := WithMinLevel(WarningLog)
+	o := options{}
+	option(&o)
+
+	assert.Equal(t, WarningLog, o.minLevel)
+}
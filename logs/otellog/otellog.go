@@ -0,0 +1,27 @@
+// Package otellog correlates logs rows with OpenTelemetry traces.
+//
+//	l := logs.NewLogging(logs.WithContextExtractor(otellog.Extractor))
+//	ctx, span := tracer.Start(ctx, "operation")
+//	l.Info(ctx, "handled") // row carries span trace id, span id and trace flags
+package otellog
+
+import (
+	"context"
+
+	"github.com/feehi.io/gopkg/logs"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Extractor extract trace id, span id and trace flags from OpenTelemetry span context stored in ctx.
+// It is a logs.ContextExtractor.
+func Extractor(ctx context.Context) (logs.TraceInfo, []logs.Field) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return logs.TraceInfo{}, nil
+	}
+	return logs.TraceInfo{
+		TraceID:    spanContext.TraceID().String(),
+		SpanID:     spanContext.SpanID().String(),
+		TraceFlags: byte(spanContext.TraceFlags()),
+	}, nil
+}
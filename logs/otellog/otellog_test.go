@@ -0,0 +1,65 @@
+package otellog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/feehi.io/gopkg/logs"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtractor(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+	testCases := []struct {
+		Input    context.Context
+		Expected logs.TraceInfo
+	}{
+		{Input: context.Background(), Expected: logs.TraceInfo{}},
+		{
+			Input: trace.ContextWithSpanContext(context.Background(), spanContext),
+			Expected: logs.TraceInfo{
+				TraceID:    "4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanID:     "00f067aa0ba902b7",
+				TraceFlags: 1,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		info, fields := Extractor(testCase.Input)
+		assert.Equal(t, testCase.Expected, info)
+		assert.Nil(t, fields)
+	}
+}
+
+func TestExtractor_logging(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	buf := bytes.Buffer{}
+	l := logs.NewLogging(
+		logs.WithFormatter(logs.NewJSONFormatter()),
+		logs.WithOutput(logs.NewOutPut(logs.AllSeverities, &buf)),
+		logs.WithContextExtractor(Extractor),
+	)
+	l.Info(ctx, "test otel")
+	l.Sync()
+
+	content := logs.Content{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &content))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", content.Headers.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", content.Headers.SpanID)
+	assert.Equal(t, byte(1), content.Headers.TraceFlags)
+}
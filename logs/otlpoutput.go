@@ -0,0 +1,324 @@
+package logs
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// otlpScopeName instrumentation scope name of exported log records
+const otlpScopeName = "github.com/feehi.io/gopkg/logs"
+
+// otlpSeverityNumber OTLP SeverityNumber of Severity
+var otlpSeverityNumber = []int{
+	DebugLog:   5,
+	InfoLog:    9,
+	WarningLog: 13,
+	ErrorLog:   17,
+	FatalLog:   21,
+}
+
+// OTLPConfig config of NewOTLPOutput
+type OTLPConfig struct {
+	// HTTPConfig URL is collector address such as http://localhost:4318, /v1/logs is appended when it is not the logs API.
+	HTTPConfig
+	// JSON whether export OTLP/JSON instead of protobuf
+	JSON bool
+	// ResourceAttributes added to resource attributes of every record, such as service.name
+	ResourceAttributes map[string]string
+}
+
+// NewOTLPOutput create a output exports rows as OTLP log records with OTLP/HTTP in batches.
+// Severity is mapped to severity number and text, message to body, fields to attributes and common fields to
+// resource attributes. Trace id, span id and trace flags come from MessageHeader, which are extracted from
+// context by context extractors such as otellog.Extractor.
+func NewOTLPOutput(levels []Severity, config OTLPConfig) (Output, error) {
+	if config.URL != "" && !strings.HasSuffix(config.URL, "/v1/logs") {
+		config.URL = strings.TrimSuffix(config.URL, "/") + "/v1/logs"
+	}
+	client, err := newHTTPClient(config.HTTPConfig)
+	if err != nil {
+		return nil, err
+	}
+	o := &otlpOutput{
+		Levels: levels,
+		config: config,
+		client: client,
+	}
+	for key, value := range config.ResourceAttributes {
+		o.resource = append(o.resource, otlpAttribute{key: key, value: value})
+	}
+	sort.Slice(o.resource, func(i, j int) bool {
+		return o.resource[i].key < o.resource[j].key
+	})
+	o.batcher = client.newBatcher(o.send)
+	return o, nil
+}
+
+type otlpOutput struct {
+	Levels  []Severity
+	config  OTLPConfig
+	client  *httpClient
+	batcher *batcher
+	// resource sorted ResourceAttributes
+	resource []otlpAttribute
+}
+
+type otlpAttribute struct {
+	key   string
+	value string
+	// intValue used instead of value when isInt is true
+	intValue int64
+	isInt    bool
+}
+
+type otlpRecord struct {
+	resource   []otlpAttribute
+	timestamp  int64
+	observed   int64
+	severity   Severity
+	body       string
+	attributes []otlpAttribute
+	traceID    []byte
+	spanID     []byte
+	flags      byte
+}
+
+func (o *otlpOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+// Write export a formatted row as body of a record with current time
+func (o *otlpOutput) Write(p []byte) (int, error) {
+	if err := o.WriteContent(nil, &Content{Headers: MessageHeader{Level: InfoLog, Time: timeNow()}, Message: strings.TrimSuffix(string(p), "\n")}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (o *otlpOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	headers := content.Headers
+	record := &otlpRecord{
+		timestamp: headers.Time.UnixNano(),
+		observed:  timeNow().UnixNano(),
+		severity:  headers.Level,
+		body:      content.Message,
+		flags:     headers.TraceFlags,
+	}
+	for _, field := range commonFields {
+		record.resource = append(record.resource, otlpAttribute{key: field.Key, value: field.Value})
+	}
+	record.resource = append(record.resource, o.resource...)
+	for _, field := range content.Fields {
+		record.attributes = append(record.attributes, otlpAttribute{key: field.Key(), value: field.Value()})
+	}
+	if headers.File != "" {
+		record.attributes = append(record.attributes,
+			otlpAttribute{key: "code.filepath", value: headers.File},
+			otlpAttribute{key: "code.lineno", intValue: int64(headers.Line), isInt: true})
+	}
+	// ids not in W3C format, such as ids set by TraceIDIdentifier, are kept as attributes
+	if traceID, err := hex.DecodeString(headers.TraceID); err == nil && len(traceID) == 16 {
+		record.traceID = traceID
+	} else if headers.TraceID != "" {
+		record.attributes = append(record.attributes, otlpAttribute{key: "trace_id", value: headers.TraceID})
+	}
+	if spanID, err := hex.DecodeString(headers.SpanID); err == nil && len(spanID) == 8 {
+		record.spanID = spanID
+	} else if headers.SpanID != "" {
+		record.attributes = append(record.attributes, otlpAttribute{key: "span_id", value: headers.SpanID})
+	}
+	return o.batcher.add(record, len(record.body)+64*len(record.attributes)+64)
+}
+
+func (o *otlpOutput) Flush() error {
+	return o.batcher.flush()
+}
+
+// Close export current batch and stop interval exporting
+func (o *otlpOutput) Close() error {
+	return o.batcher.close()
+}
+
+// otlpResourceLogs records of a resource in a batch
+type otlpResourceLogs struct {
+	resource []otlpAttribute
+	records  []*otlpRecord
+}
+
+func (o *otlpOutput) send(items []interface{}) error {
+	var resources []*otlpResourceLogs
+	index := map[string]*otlpResourceLogs{}
+	for _, item := range items {
+		record := item.(*otlpRecord)
+		key := otlpResourceKey(record.resource)
+		resource, ok := index[key]
+		if !ok {
+			resource = &otlpResourceLogs{resource: record.resource}
+			index[key] = resource
+			resources = append(resources, resource)
+		}
+		resource.records = append(resource.records, record)
+	}
+
+	var body []byte
+	contentType := "application/x-protobuf"
+	if o.config.JSON {
+		var err error
+		if body, err = encodeOTLPJSON(resources); err != nil {
+			return fmt.Errorf("encode OTLP logs request error: %s", err)
+		}
+		contentType = "application/json"
+	} else {
+		body = encodeOTLPProtobuf(resources)
+	}
+	_, err := o.client.post(body, contentType, nil)
+	return err
+}
+
+func otlpResourceKey(resource []otlpAttribute) string {
+	var b strings.Builder
+	for _, attribute := range resource {
+		b.WriteString(strconv.Quote(attribute.key))
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(attribute.value))
+		b.WriteByte(',')
+	}
+	return b.String()
+}
+
+// encodeOTLPProtobuf encode ExportLogsServiceRequest of opentelemetry/proto/collector/logs/v1:
+//
+//	message ExportLogsServiceRequest { repeated ResourceLogs resource_logs = 1; }
+//	message ResourceLogs { Resource resource = 1; repeated ScopeLogs scope_logs = 2; }
+//	message Resource { repeated KeyValue attributes = 1; }
+//	message ScopeLogs { InstrumentationScope scope = 1; repeated LogRecord log_records = 2; }
+//	message InstrumentationScope { string name = 1; }
+//	message LogRecord { fixed64 time_unix_nano = 1; SeverityNumber severity_number = 2; string severity_text = 3;
+//	  AnyValue body = 5; repeated KeyValue attributes = 6; fixed32 flags = 8; bytes trace_id = 9; bytes span_id = 10;
+//	  fixed64 observed_time_unix_nano = 11; }
+//	message KeyValue { string key = 1; AnyValue value = 2; }
+//	message AnyValue { oneof value { string string_value = 1; int64 int_value = 3; } }
+func encodeOTLPProtobuf(resources []*otlpResourceLogs) []byte {
+	attributes := func(e *protoEncoder, field int, attributes []otlpAttribute) {
+		for _, attribute := range attributes {
+			e.message(field, func(e *protoEncoder) {
+				e.string(1, attribute.key)
+				e.message(2, func(e *protoEncoder) {
+					if attribute.isInt {
+						e.int(3, attribute.intValue)
+					} else {
+						e.string(1, attribute.value)
+					}
+				})
+			})
+		}
+	}
+	e := &protoEncoder{}
+	for _, resource := range resources {
+		e.message(1, func(e *protoEncoder) {
+			e.message(1, func(e *protoEncoder) {
+				attributes(e, 1, resource.resource)
+			})
+			e.message(2, func(e *protoEncoder) {
+				e.message(1, func(e *protoEncoder) {
+					e.string(1, otlpScopeName)
+				})
+				for _, record := range resource.records {
+					e.message(2, func(e *protoEncoder) {
+						e.fixed64(1, uint64(record.timestamp))
+						e.uvarint(2, uint64(otlpSeverityNumber[record.severity]))
+						e.string(3, severityName[record.severity])
+						e.message(5, func(e *protoEncoder) {
+							e.string(1, record.body)
+						})
+						attributes(e, 6, record.attributes)
+						e.fixed32(8, uint32(record.flags))
+						e.bytes(9, record.traceID)
+						e.bytes(10, record.spanID)
+						e.fixed64(11, uint64(record.observed))
+					})
+				}
+			})
+		})
+	}
+	return e.buf
+}
+
+// encodeOTLPJSON encode ExportLogsServiceRequest in OTLP/JSON, ids are hex and 64 bits integers are strings.
+func encodeOTLPJSON(resources []*otlpResourceLogs) ([]byte, error) {
+	type anyValue struct {
+		StringValue *string `json:"stringValue,omitempty"`
+		IntValue    string  `json:"intValue,omitempty"`
+	}
+	type keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	type logRecord struct {
+		TimeUnixNano         string     `json:"timeUnixNano"`
+		ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+		SeverityNumber       int        `json:"severityNumber"`
+		SeverityText         string     `json:"severityText"`
+		Body                 anyValue   `json:"body"`
+		Attributes           []keyValue `json:"attributes,omitempty"`
+		Flags                byte       `json:"flags,omitempty"`
+		TraceID              string     `json:"traceId,omitempty"`
+		SpanID               string     `json:"spanId,omitempty"`
+	}
+	type scopeLogs struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		LogRecords []logRecord `json:"logRecords"`
+	}
+	type resourceLogs struct {
+		Resource struct {
+			Attributes []keyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []scopeLogs `json:"scopeLogs"`
+	}
+	keyValues := func(attributes []otlpAttribute) []keyValue {
+		keyValues := make([]keyValue, 0, len(attributes))
+		for i := range attributes {
+			attribute := attributes[i]
+			kv := keyValue{Key: attribute.key}
+			if attribute.isInt {
+				kv.Value.IntValue = strconv.FormatInt(attribute.intValue, 10)
+			} else {
+				kv.Value.StringValue = &attribute.value
+			}
+			keyValues = append(keyValues, kv)
+		}
+		return keyValues
+	}
+
+	request := struct {
+		ResourceLogs []resourceLogs `json:"resourceLogs"`
+	}{ResourceLogs: make([]resourceLogs, 0, len(resources))}
+	for _, resource := range resources {
+		scope := scopeLogs{LogRecords: make([]logRecord, 0, len(resource.records))}
+		scope.Scope.Name = otlpScopeName
+		for _, record := range resource.records {
+			body := record.body
+			scope.LogRecords = append(scope.LogRecords, logRecord{
+				TimeUnixNano:         strconv.FormatInt(record.timestamp, 10),
+				ObservedTimeUnixNano: strconv.FormatInt(record.observed, 10),
+				SeverityNumber:       otlpSeverityNumber[record.severity],
+				SeverityText:         severityName[record.severity],
+				Body:                 anyValue{StringValue: &body},
+				Attributes:           keyValues(record.attributes),
+				Flags:                record.flags,
+				TraceID:              hex.EncodeToString(record.traceID),
+				SpanID:               hex.EncodeToString(record.spanID),
+			})
+		}
+		logs := resourceLogs{ScopeLogs: []scopeLogs{scope}}
+		logs.Resource.Attributes = keyValues(resource.resource)
+		request.ResourceLogs = append(request.ResourceLogs, logs)
+	}
+	return json.Marshal(request)
+}
@@ -0,0 +1,178 @@
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testOTLPContents() ([]*CommonField, []*Content) {
+	base := time.Unix(1605884645, 100)
+	return []*CommonField{NewCommonField("service.name", "order")}, []*Content{
+		{
+			Headers: MessageHeader{
+				Level:      ErrorLog,
+				Time:       base,
+				TraceID:    "0af7651916cd43dd8448eb211c80319c",
+				SpanID:     "b7ad6b7169203331",
+				TraceFlags: 1,
+				File:       "a.go",
+				Line:       12,
+			},
+			Message: "error 1",
+			Fields:  []Field{String("order_id", "10")},
+		},
+		{Headers: MessageHeader{Level: WarningLog, Time: base.Add(time.Second), TraceID: "custom"}, Message: "warning 1"},
+	}
+}
+
+func TestOTLPOutput_protobuf(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	timeNow = func() time.Time {
+		return time.Unix(1605884646, 0)
+	}
+	server, requests := testHTTPServer(t)
+	defer server.Close()
+	output, err := NewOTLPOutput(AllSeverities, OTLPConfig{
+		HTTPConfig:         HTTPConfig{URL: server.URL, BatchInterval: time.Hour},
+		ResourceAttributes: map[string]string{"service.version": "1.0", "host.name": "a1"},
+	})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	commonFields, contents := testOTLPContents()
+	for _, content := range contents {
+		output.(ContentOutput).WriteContent(commonFields, content)
+	}
+	output.(ContentOutput).WriteContent(nil, &Content{Headers: MessageHeader{Level: InfoLog, Time: time.Unix(1, 0)}, Message: "no resource"})
+	assert.Nil(t, output.Flush())
+
+	rows := requests()
+	if !assert.Equal(t, 1, len(rows)) {
+		return
+	}
+	assert.Equal(t, "application/x-protobuf", rows[0].Header.Get("Content-Type"))
+	request, err := testDecodeProto([]byte(rows[0].Body))
+	assert.Nil(t, err)
+	resourceLogs := testProtoMessages(t, request, 1)
+	// records are grouped by common fields
+	if !assert.Equal(t, 2, len(resourceLogs)) {
+		return
+	}
+	keyValue := func(fields []testProtoField) (string, interface{}) {
+		value := testProtoMessages(t, fields, 2)[0]
+		return string(testProtoValue(fields, 1).([]byte)), value[0].Value
+	}
+	resource := testProtoMessages(t, resourceLogs[0], 1)[0]
+	attributes := map[string]interface{}{}
+	for _, attribute := range testProtoMessages(t, resource, 1) {
+		key, value := keyValue(attribute)
+		attributes[key] = string(value.([]byte))
+	}
+	assert.Equal(t, map[string]interface{}{"service.name": "order", "service.version": "1.0", "host.name": "a1"}, attributes)
+
+	scopeLogs := testProtoMessages(t, resourceLogs[0], 2)[0]
+	scope := testProtoMessages(t, scopeLogs, 1)[0]
+	assert.Equal(t, []byte(otlpScopeName), testProtoValue(scope, 1))
+	records := testProtoMessages(t, scopeLogs, 2)
+	if !assert.Equal(t, 2, len(records)) {
+		return
+	}
+	record := records[0]
+	assert.Equal(t, uint64(1605884645000000100), testProtoValue(record, 1))
+	assert.Equal(t, uint64(17), testProtoValue(record, 2))
+	assert.Equal(t, []byte("ERROR"), testProtoValue(record, 3))
+	assert.Equal(t, []testProtoField{{1, []byte("error 1")}}, testProtoMessages(t, record, 5)[0])
+	attributes = map[string]interface{}{}
+	for _, attribute := range testProtoMessages(t, record, 6) {
+		key, value := keyValue(attribute)
+		attributes[key] = value
+	}
+	assert.Equal(t, map[string]interface{}{"order_id": []byte("10"), "code.filepath": []byte("a.go"), "code.lineno": uint64(12)}, attributes)
+	assert.Equal(t, uint64(1), testProtoValue(record, 8))
+	assert.Equal(t, []byte{0x0a, 0xf7, 0x65, 0x19, 0x16, 0xcd, 0x43, 0xdd, 0x84, 0x48, 0xeb, 0x21, 0x1c, 0x80, 0x31, 0x9c}, testProtoValue(record, 9))
+	assert.Equal(t, []byte{0xb7, 0xad, 0x6b, 0x71, 0x69, 0x20, 0x33, 0x31}, testProtoValue(record, 10))
+	assert.Equal(t, uint64(1605884646000000000), testProtoValue(record, 11))
+
+	// trace id not in W3C format is kept as attribute
+	record = records[1]
+	assert.Equal(t, uint64(13), testProtoValue(record, 2))
+	assert.Nil(t, testProtoValue(record, 9))
+	key, value := keyValue(testProtoMessages(t, record, 6)[0])
+	assert.Equal(t, "trace_id", key)
+	assert.Equal(t, []byte("custom"), value)
+
+	resource = testProtoMessages(t, resourceLogs[1], 1)[0]
+	assert.Equal(t, 2, len(testProtoMessages(t, resource, 1)))
+}
+
+func TestOTLPOutput_json(t *testing.T) {
+	server, requests := testHTTPServer(t, http.StatusServiceUnavailable)
+	defer server.Close()
+	output, err := NewOTLPOutput(AllSeverities, OTLPConfig{
+		HTTPConfig: HTTPConfig{URL: server.URL + "/v1/logs", MinBackoff: time.Millisecond, BatchInterval: time.Hour},
+		JSON:       true,
+	})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	commonFields, contents := testOTLPContents()
+	output.(ContentOutput).WriteContent(commonFields, contents[0])
+	assert.Nil(t, output.Flush())
+
+	// exported again after 503
+	rows := requests()
+	if !assert.Equal(t, 2, len(rows)) {
+		return
+	}
+	assert.Equal(t, rows[0].Body, rows[1].Body)
+	assert.Equal(t, "application/json", rows[1].Header.Get("Content-Type"))
+	var request map[string]interface{}
+	assert.Nil(t, json.Unmarshal([]byte(rows[1].Body), &request))
+	resourceLogs := request["resourceLogs"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"attributes": []interface{}{
+		map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "order"}},
+	}}, resourceLogs["resource"])
+	scopeLogs := resourceLogs["scopeLogs"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"timeUnixNano":         "1605884645000000100",
+		"observedTimeUnixNano": scopeLogs["logRecords"].([]interface{})[0].(map[string]interface{})["observedTimeUnixNano"],
+		"severityNumber":       float64(17),
+		"severityText":         "ERROR",
+		"body":                 map[string]interface{}{"stringValue": "error 1"},
+		"attributes": []interface{}{
+			map[string]interface{}{"key": "order_id", "value": map[string]interface{}{"stringValue": "10"}},
+			map[string]interface{}{"key": "code.filepath", "value": map[string]interface{}{"stringValue": "a.go"}},
+			map[string]interface{}{"key": "code.lineno", "value": map[string]interface{}{"intValue": "12"}},
+		},
+		"flags":   float64(1),
+		"traceId": "0af7651916cd43dd8448eb211c80319c",
+		"spanId":  "b7ad6b7169203331",
+	}}, scopeLogs["logRecords"])
+}
+
+func TestOTLPOutput_logging(t *testing.T) {
+	server, requests := testHTTPServer(t)
+	defer server.Close()
+	output, err := NewOTLPOutput(AllSeverities, OTLPConfig{HTTPConfig: HTTPConfig{URL: server.URL + "/", BatchInterval: time.Hour}, JSON: true})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	l := NewLogging(WithOutput(output))
+	l.Warning(context.Background(), "from logger")
+	assert.Nil(t, l.Sync())
+
+	rows := requests()
+	if assert.Equal(t, 1, len(rows)) {
+		assert.Equal(t, "/v1/logs", rows[0].Path)
+		assert.Contains(t, rows[0].Body, `"body":{"stringValue":"from logger"}`)
+		assert.Contains(t, rows[0].Body, `"severityText":"WARNING"`)
+		assert.Contains(t, rows[0].Body, `{"key":"code.filepath","value":{"stringValue":"otlpoutput_test.go"}}`)
+	}
+
+	_, err = NewOTLPOutput(AllSeverities, OTLPConfig{})
+	assert.NotNil(t, err)
+}
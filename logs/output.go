@@ -14,9 +14,18 @@ type Output interface {
 	IsLevelNeedRecord(s Severity) bool
 }
 
+// ContentOutput is an Output receives structured log content rather than formatted bytes.
+// When an output implements it, WriteContent is called instead of Write, and formatter is not used.
+type ContentOutput interface {
+	Output
+	WriteContent(commonFields []*CommonField, content *Content) error
+}
+
 type output struct {
 	Levels []Severity
 	Buffer *bufio.Writer
+	// file opened by NewFileOutput, closed with the output
+	file *os.File
 }
 
 func (o output) Write(p []byte) (n int, err error) {
@@ -27,6 +36,18 @@ func (o output) Flush() error {
 	return o.Buffer.Flush()
 }
 
+// Close flush buffered rows and close the file opened by NewFileOutput, writer passed to NewOutPut is not closed.
+func (o *output) Close() error {
+	err := o.Flush()
+	if o.file == nil {
+		return err
+	}
+	if closeErr := o.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
 func (o output) IsLevelNeedRecord(s Severity) bool {
 	for _, l := range o.Levels {
 		if l == s {
@@ -50,10 +71,39 @@ func NewFileOutput(levels []Severity, filename string) (Output, error) {
 	if err != nil {
 		return output{}, fmt.Errorf("open log file error: %s", err)
 	}
-	return NewOutPut(levels, fl), nil
+	return &output{Levels: levels, Buffer: bufio.NewWriter(fl), file: fl}, nil
 }
 
 // NewStdOutOutput create a STD log output
 func NewStdOutOutput(levels []Severity) Output {
 	return NewOutPut(levels, os.Stdout)
 }
+
+// NewFormattedOutput create a output formats rows with formatter rather than the log instance formatter.
+// Used when outputs of one log instance need different formats, such as console for stdout and JSON for file.
+func NewFormattedOutput(output Output, formatter Formatter) Output {
+	return &formattedOutput{Output: output, formatter: formatter}
+}
+
+type formattedOutput struct {
+	Output
+	formatter Formatter
+}
+
+func (o *formattedOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	_, err := o.Output.Write(o.formatter.Format(commonFields, content))
+	return err
+}
+
+// HealthCheck check wrapped output if it is a HealthChecker
+func (o *formattedOutput) HealthCheck() error {
+	return healthCheck(o.Output)
+}
+
+// Close close wrapped output if it is an io.Closer
+func (o *formattedOutput) Close() error {
+	if closer, ok := o.Output.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -2,8 +2,10 @@ package logs
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"testing"
 
@@ -141,3 +143,17 @@ func TestOutput_isLevelNeedRecord(t *testing.T) {
 		assert.Equal(t, testCase.Expected, result, fmt.Sprintf("isLevelNeedRecord input %s expected %t but got %t", severityName[testCase.Input], testCase.Expected, result))
 	}
 }
+
+func TestNewFormattedOutput(t *testing.T) {
+	buf := bytes.Buffer{}
+	output := NewFormattedOutput(NewOutPut([]Severity{InfoLog}, &buf), NewStringFormatter("{LEVEL} {MESSAGE}", "", false))
+	l := NewLogging(WithOutput(output), WithFormatter(NewJSONFormatter()))
+	l.Info(context.Background(), "formatted by output")
+	l.Debug(context.Background(), "not recorded")
+	l.Sync()
+	assert.Equal(t, "INFO formatted by output\n", buf.String())
+
+	closer, ok := output.(io.Closer)
+	assert.True(t, ok)
+	assert.Nil(t, closer.Close())
+}
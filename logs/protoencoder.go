@@ -0,0 +1,111 @@
+package logs
+
+import "encoding/binary"
+
+// protobuf wire types
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// protoEncoder append protobuf wire format, only field types used by push APIs are supported.
+// Zero values are skipped like proto3 does.
+type protoEncoder struct {
+	buf []byte
+}
+
+func (e *protoEncoder) tag(field int, wireType int) {
+	e.buf = appendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+func (e *protoEncoder) uvarint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, protoVarint)
+	e.buf = appendUvarint(e.buf, v)
+}
+
+// int write v even it is 0, for oneof fields whose presence matters.
+func (e *protoEncoder) int(field int, v int64) {
+	e.tag(field, protoVarint)
+	e.buf = appendUvarint(e.buf, uint64(v))
+}
+
+func (e *protoEncoder) fixed64(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, protoFixed64)
+	e.buf = append(e.buf, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint64(e.buf[len(e.buf)-8:], v)
+}
+
+func (e *protoEncoder) fixed32(field int, v uint32) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, protoFixed32)
+	e.buf = append(e.buf, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(e.buf[len(e.buf)-4:], v)
+}
+
+func (e *protoEncoder) bytes(field int, b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	e.tag(field, protoBytes)
+	e.buf = appendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *protoEncoder) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(field, protoBytes)
+	e.buf = appendUvarint(e.buf, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// message encode a embedded message with f, it is written even it is empty.
+func (e *protoEncoder) message(field int, f func(e *protoEncoder)) {
+	sub := &protoEncoder{}
+	f(sub)
+	e.tag(field, protoBytes)
+	e.buf = appendUvarint(e.buf, uint64(len(sub.buf)))
+	e.buf = append(e.buf, sub.buf...)
+}
+
+func appendUvarint(b []byte, v uint64) []byte {
+	for v >= 0x80 {
+		b = append(b, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(b, byte(v))
+}
+
+// snappyEncode encode src to snappy block format with literals only.
+// It is valid snappy any decoder accepts, compression ratio is traded for a small encoder.
+func snappyEncode(src []byte) []byte {
+	dst := appendUvarint(make([]byte, 0, len(src)+len(src)/65536*3+10), uint64(len(src)))
+	for len(src) > 0 {
+		n := len(src)
+		if n > 65536 {
+			n = 65536
+		}
+		switch {
+		case n <= 60:
+			dst = append(dst, byte(n-1)<<2)
+		case n <= 256:
+			dst = append(dst, 60<<2, byte(n-1))
+		default:
+			dst = append(dst, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
@@ -0,0 +1,153 @@
+package logs
+
+import (
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testProtoField a decoded protobuf field, Value is uint64 for varint and fixed, []byte for bytes
+type testProtoField struct {
+	Number int
+	Value  interface{}
+}
+
+// testDecodeProto decode fields of a protobuf message without schema
+func testDecodeProto(b []byte) ([]testProtoField, error) {
+	var fields []testProtoField
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid tag")
+		}
+		b = b[n:]
+		field := testProtoField{Number: int(key >> 3)}
+		switch key & 7 {
+		case protoVarint:
+			v, n := binary.Uvarint(b)
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid varint")
+			}
+			field.Value, b = v, b[n:]
+		case protoFixed64:
+			if len(b) < 8 {
+				return nil, fmt.Errorf("invalid fixed64")
+			}
+			field.Value, b = binary.LittleEndian.Uint64(b), b[8:]
+		case protoFixed32:
+			if len(b) < 4 {
+				return nil, fmt.Errorf("invalid fixed32")
+			}
+			field.Value, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case protoBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < l {
+				return nil, fmt.Errorf("invalid length")
+			}
+			field.Value, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d", key&7)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// testProtoMessages return embedded messages of field number
+func testProtoMessages(t *testing.T, fields []testProtoField, number int) [][]testProtoField {
+	var messages [][]testProtoField
+	for _, field := range fields {
+		if field.Number == number {
+			message, err := testDecodeProto(field.Value.([]byte))
+			assert.Nil(t, err)
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// testProtoValue return last value of field number, nil when it is not set
+func testProtoValue(fields []testProtoField, number int) interface{} {
+	var value interface{}
+	for _, field := range fields {
+		if field.Number == number {
+			value = field.Value
+		}
+	}
+	return value
+}
+
+// testSnappyDecode decode snappy block with literals only, which snappyEncode produces
+func testSnappyDecode(b []byte) ([]byte, error) {
+	length, n := binary.Uvarint(b)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid length")
+	}
+	b = b[n:]
+	var dst []byte
+	for len(b) > 0 {
+		if b[0]&3 != 0 {
+			return nil, fmt.Errorf("not a literal")
+		}
+		l := int(b[0] >> 2)
+		b = b[1:]
+		switch l {
+		case 60:
+			l, b = int(b[0]), b[1:]
+		case 61:
+			l, b = int(b[0])|int(b[1])<<8, b[2:]
+		}
+		l++
+		dst, b = append(dst, b[:l]...), b[l:]
+	}
+	if uint64(len(dst)) != length {
+		return nil, fmt.Errorf("length %d, expected %d", len(dst), length)
+	}
+	return dst, nil
+}
+
+func TestProtoEncoder(t *testing.T) {
+	e := &protoEncoder{}
+	e.uvarint(1, 300)
+	e.uvarint(2, 0)
+	e.string(3, "row")
+	e.string(4, "")
+	e.message(5, func(e *protoEncoder) {
+		e.uvarint(1, 1)
+	})
+	e.message(6, func(e *protoEncoder) {})
+	assert.Equal(t, []byte{0x08, 0xac, 0x02, 0x1a, 0x03, 'r', 'o', 'w', 0x2a, 0x02, 0x08, 0x01, 0x32, 0x00}, e.buf)
+
+	fields, err := testDecodeProto(e.buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []testProtoField{{1, uint64(300)}, {3, []byte("row")}, {5, []byte{0x08, 0x01}}, {6, []byte{}}}, fields)
+
+	e = &protoEncoder{}
+	e.int(1, 0)
+	e.int(2, -1)
+	e.fixed64(3, 1)
+	e.fixed64(4, 0)
+	e.fixed32(5, 2)
+	e.bytes(6, []byte{0xff})
+	e.bytes(7, nil)
+	fields, err = testDecodeProto(e.buf)
+	assert.Nil(t, err)
+	assert.Equal(t, []testProtoField{{1, uint64(0)}, {2, uint64(1<<64 - 1)}, {3, uint64(1)}, {5, uint64(2)}, {6, []byte{0xff}}}, fields)
+}
+
+func TestSnappyEncode(t *testing.T) {
+	for _, n := range []int{0, 1, 60, 61, 256, 257, 65536, 65537, 200000} {
+		src := make([]byte, n)
+		for i := range src {
+			src[i] = byte(i)
+		}
+		encoded := snappyEncode(src)
+		decoded, err := testSnappyDecode(encoded)
+		assert.Nil(t, err, n)
+		assert.Equal(t, len(src), len(decoded), n)
+		assert.Equal(t, src, append([]byte{}, decoded...), n)
+	}
+	assert.Equal(t, []byte{0x03, 0x08, 'a', 'b', 'c'}, snappyEncode([]byte("abc")))
+}
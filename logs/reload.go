@@ -0,0 +1,194 @@
+package logs
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+)
+
+// swapContent marks the position of a swap in contentChan,
+// rows before it are written with old options and rows after it with new options.
+var swapContent = &Content{}
+
+type swap struct {
+	apply    func(prev *options) *options
+	closeOld bool
+}
+
+// swap replace options in write goroutine once rows already in contentChan are written with old options,
+// so no row is lost or written twice. Old outputs are flushed, and closed when closeOld is true and new options
+// do not use them. It must not be called by an output, which runs in write goroutine.
+func (l *logging) swap(apply func(prev *options) *options, closeOld bool) []error {
+	l.swapMu.Lock()
+	defer l.swapMu.Unlock()
+	l.pendingSwap = &swap{apply: apply, closeOld: closeOld}
+	l.contentChan <- swapContent
+	return <-l.swapFinishChan
+}
+
+func (l *logging) applySwap(s *swap) {
+	prev := l.opts()
+	next := s.apply(prev)
+	errs := flushOutputs(prev.outputs)
+	l.snapshot.Store(next)
+	if s.closeOld {
+		errs = append(errs, closeOutputs(unusedOutputs(prev.outputs, next.outputs))...)
+	}
+	l.swapFinishChan <- errs
+}
+
+// unusedOutputs return outputs in prev but not in next. Outputs are matched by pointer identity,
+// outputs are not pointers can not be matched and are never returned.
+func unusedOutputs(prev []Output, next []Output) []Output {
+	var unused []Output
+	for _, output := range prev {
+		if reflect.ValueOf(output).Kind() != reflect.Ptr {
+			continue
+		}
+		used := false
+		for _, n := range next {
+			if output == n {
+				used = true
+				break
+			}
+		}
+		if !used {
+			unused = append(unused, output)
+		}
+	}
+	return unused
+}
+
+// update replace options with a modified copy, rows already in contentChan are written with current options
+// and current outputs are flushed first. The current snapshot is never modified.
+func (l *logging) update(f func(o *options)) {
+	errs := l.swap(func(prev *options) *options {
+		next := *prev
+		f(&next)
+		return &next
+	}, false)
+	for _, err := range errs {
+		fmt.Printf("flush replaced log output error %s \n", err)
+	}
+}
+
+// Reload replace outputs, formatter, common fields, min level, vmodule and dir header with config.
+// Rows logged before Reload are written to old outputs, then old outputs are flushed and closed(when they implement io.Closer).
+// ChannelSize of config is ignored since channel can not be resized.
+// Invalid config returns error and current config is kept.
+func (l *logging) Reload(config *Config) error {
+	opts, err := config.options()
+	if err != nil {
+		return err
+	}
+	reloaded := newOptions(opts...)
+	errs := l.swap(func(prev *options) *options {
+		next := *prev
+		next.outputs = reloaded.outputs
+		next.formatter = reloaded.formatter
+		next.commonFields = reloaded.commonFields
+		next.minLevel = reloaded.minLevel
+		next.vModule = reloaded.vModule
+		next.addDirHeader = reloaded.addDirHeader
+		return &next
+	}, true)
+	for _, err := range errs {
+		fmt.Printf("flush or close replaced log output error %s \n", err)
+	}
+	return nil
+}
+
+// WatchConfigFile reload config from filename when its modification time or size changes, checked every interval.
+// Load or reload errors are printed and current config is kept. Call returned stop to stop watching.
+func (l *logging) WatchConfigFile(filename string, interval time.Duration) (stop func()) {
+	return watchConfigFile(filename, interval, l.Reload)
+}
+
+// ReloadOnSignal reload config from filename when one of signals is received, default is SIGHUP.
+// Load or reload errors are printed and current config is kept. Call returned stop to stop listening.
+func (l *logging) ReloadOnSignal(filename string, signals ...os.Signal) (stop func()) {
+	return reloadOnSignal(filename, signals, l.Reload)
+}
+
+// NewLoggingFromConfigFile create log instance from config file, see LoadConfigFile.
+func NewLoggingFromConfigFile(filename string) (*logging, error) {
+	config, err := LoadConfigFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return NewLoggingFromConfig(config)
+}
+
+func reloadConfigFile(filename string, reload func(config *Config) error) {
+	config, err := LoadConfigFile(filename)
+	if err == nil {
+		err = reload(config)
+	}
+	if err != nil {
+		fmt.Printf("reload log config error %s \n", err)
+	}
+}
+
+func watchConfigFile(filename string, interval time.Duration, reload func(config *Config) error) func() {
+	stat := func() (time.Time, int64) {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return info.ModTime(), info.Size()
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	modTime, size := stat()
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				m, s := stat()
+				if s < 0 || (m.Equal(modTime) && s == size) {
+					continue
+				}
+				modTime, size = m, s
+				reloadConfigFile(filename, reload)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+func reloadOnSignal(filename string, signals []os.Signal, reload func(config *Config) error) func() {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, signals...)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-done:
+				return
+			case <-c:
+				reloadConfigFile(filename, reload)
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(c)
+		close(done)
+		<-stopped
+	}
+}
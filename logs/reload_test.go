@@ -0,0 +1,179 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testReadRows(t *testing.T, filenames ...string) []string {
+	var rows []string
+	for _, filename := range filenames {
+		b, err := ioutil.ReadFile(filename)
+		assert.Nil(t, err)
+		for _, row := range strings.Split(string(b), "\n") {
+			if row != "" {
+				rows = append(rows, row)
+			}
+		}
+	}
+	sort.Strings(rows)
+	return rows
+}
+
+func testRotatingConfig(path string) *Config {
+	return &Config{
+		CommonFields: map[string]string{"config": filepath.Base(path)},
+		Formatter:    &FormatterConfig{Type: "string", Template: "{MESSAGE}"},
+		Outputs:      []OutputConfig{{Type: "rotating", Path: path}},
+	}
+}
+
+func TestLoggingT_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	first, second := filepath.Join(dir, "first.log"), filepath.Join(dir, "second.log")
+
+	l, err := NewLoggingFromConfig(testRotatingConfig(first))
+	assert.Nil(t, err)
+	oldOutput := l.opts().outputs[0]
+
+	var expected []string
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				l.Info(context.Background(), fmt.Sprintf("row %d-%03d", i, j))
+			}
+		}(i)
+		for j := 0; j < 200; j++ {
+			expected = append(expected, fmt.Sprintf("row %d-%03d", i, j))
+		}
+	}
+	assert.Nil(t, l.Reload(testRotatingConfig(second)))
+	wg.Wait()
+	assert.Nil(t, l.Sync())
+
+	// every row is written exactly once, to the old or the new output
+	sort.Strings(expected)
+	assert.Equal(t, expected, testReadRows(t, first, second))
+	assert.Equal(t, []*CommonField{NewCommonField("config", "second.log")}, l.opts().commonFields)
+	_, err = oldOutput.Write([]byte("closed\n"))
+	assert.NotNil(t, err)
+
+	l.Info(context.Background(), "after reload")
+	l.Sync()
+	rows := testReadRows(t, second)
+	assert.Contains(t, rows, "after reload")
+}
+
+func TestLoggingT_Reload_keep(t *testing.T) {
+	output := NewOutPut(AllSeverities, &bytes.Buffer{})
+	l := NewLogging(WithOutput(output), WithContextExtractor(TraceParentExtractor), WithMinLevel(InfoLog))
+
+	err := l.Reload(&Config{MinLevel: "verbose"})
+	assert.NotNil(t, err)
+	assert.Equal(t, []Output{output}, l.opts().outputs)
+	assert.Equal(t, InfoLog, l.opts().minLevel)
+
+	assert.Nil(t, l.Reload(&Config{MinLevel: "error", VModule: "http=debug", DirHeader: true}))
+	assert.Equal(t, ErrorLog, l.opts().minLevel)
+	assert.Equal(t, "http=debug", l.opts().vModule.String())
+	assert.True(t, l.opts().addDirHeader)
+	assert.Equal(t, 2, len(l.opts().contextExtractors))
+	assert.Equal(t, defaultFormatter(), l.opts().formatter)
+}
+
+func TestUnusedOutputs(t *testing.T) {
+	a, b, c := NewOutPut(AllSeverities, &bytes.Buffer{}), NewOutPut(AllSeverities, &bytes.Buffer{}), NewOutPut(AllSeverities, &bytes.Buffer{})
+	assert.Equal(t, []Output{a, c}, unusedOutputs([]Output{a, b, c}, []Output{b}))
+	assert.Nil(t, unusedOutputs([]Output{a}, []Output{a, b}))
+	// outputs are not pointers can not be matched, they are never treated as unused
+	assert.Nil(t, unusedOutputs([]Output{output{}}, []Output{output{}}))
+	assert.Nil(t, unusedOutputs([]Output{output{}}, nil))
+}
+
+func TestLoggingT_Reload_closeFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	config := func(path string) *Config {
+		return &Config{Outputs: []OutputConfig{{Type: "file", Path: path}}}
+	}
+
+	l, err := NewLoggingFromConfig(config(filepath.Join(dir, "first.log")))
+	assert.Nil(t, err)
+	oldOutput, ok := l.opts().outputs[0].(*output)
+	if !assert.True(t, ok) {
+		return
+	}
+	l.Info(context.Background(), "before reload")
+	assert.Nil(t, l.Reload(config(filepath.Join(dir, "second.log"))))
+
+	// old file is flushed and closed
+	assert.Contains(t, testReadRows(t, filepath.Join(dir, "first.log"))[0], "before reload")
+	assert.True(t, errors.Is(oldOutput.file.Close(), os.ErrClosed))
+
+	l.Info(context.Background(), "after reload")
+	assert.Nil(t, l.Sync())
+	assert.Contains(t, testReadRows(t, filepath.Join(dir, "second.log"))[0], "after reload")
+	newOutput := l.opts().outputs[0].(*output)
+	assert.Nil(t, newOutput.Close())
+}
+
+func TestLoggingT_WatchConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "logs.yaml")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("min_level: info\noutputs: [{type: stderr}]\n"), 0644))
+
+	l, err := NewLoggingFromConfigFile(filename)
+	assert.Nil(t, err)
+	assert.Equal(t, InfoLog, l.opts().minLevel)
+
+	// watcher is stopped before stdout is restored, so its error is always printed to captured stdout
+	stdOutput := testCaptureSTDOutput(func() {
+		stop := l.WatchConfigFile(filename, 5*time.Millisecond)
+		defer stop()
+		assert.Nil(t, ioutil.WriteFile(filename, []byte("min_level: verbose\noutputs: [{type: stderr}]\n"), 0644))
+		time.Sleep(50 * time.Millisecond)
+	})
+	assert.Contains(t, stdOutput, `reload log config error `+filename+`: invalid log config: min_level: unknown log level "verbose"`)
+	assert.Equal(t, InfoLog, l.opts().minLevel)
+
+	stop := l.WatchConfigFile(filename, 5*time.Millisecond)
+	defer stop()
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("min_level: error\noutputs: [{type: stderr}]\n"), 0644))
+	for i := 0; i < 200 && l.opts().minLevel != ErrorLog; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, ErrorLog, l.opts().minLevel)
+}
+
+func TestNewLoggingFromConfigFile(t *testing.T) {
+	_, err := NewLoggingFromConfigFile(filepath.Join("not", "exists.yaml"))
+	assert.NotNil(t, err)
+}
+
+func TestReload(t *testing.T) {
+	defer testInitLogging()
+	testInitLogging()
+	assert.Nil(t, Reload(&Config{MinLevel: "warning", Outputs: []OutputConfig{{Type: "stderr"}}}))
+	assert.Equal(t, WarningLog, log().opts().minLevel)
+	assert.NotNil(t, Reload(&Config{Outputs: []OutputConfig{{Type: "unknown"}}}))
+}
@@ -0,0 +1,35 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package logs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggingT_ReloadOnSignal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reload")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "logs.json")
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(`{"min_level": "info"}`), 0644))
+
+	l, err := NewLoggingFromConfigFile(filename)
+	assert.Nil(t, err)
+	stop := l.ReloadOnSignal(filename, syscall.SIGUSR1)
+	defer stop()
+
+	assert.Nil(t, ioutil.WriteFile(filename, []byte(`{"min_level": "fatal"}`), 0644))
+	assert.Nil(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	for i := 0; i < 200 && l.opts().minLevel != FatalLog; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, FatalLog, l.opts().minLevel)
+}
@@ -0,0 +1,255 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRingCapacity default count of rows kept by ring output
+const DefaultRingCapacity = 1000
+
+// RingRow a row kept by RingOutput
+type RingRow struct {
+	*Content
+	CommonFields []*CommonField `json:"common_fields"`
+}
+
+// RingQuery conditions of rows returned by RingOutput.Rows, zero values match all rows.
+type RingQuery struct {
+	// Levels rows logged with one of them
+	Levels []Severity
+	// TraceID rows of the trace
+	TraceID string
+	// Since rows logged at or after it
+	Since time.Time
+	// Until rows logged before it
+	Until time.Time
+	// Message rows whose message contains it
+	Message string
+	// Fields rows have all the fields, a field with empty value matches any value of the key
+	Fields map[string]string
+	// Limit return at most Limit newest rows
+	Limit int
+}
+
+func (q RingQuery) match(row RingRow) bool {
+	headers := row.Headers
+	if len(q.Levels) > 0 && !(output{Levels: q.Levels}).IsLevelNeedRecord(headers.Level) {
+		return false
+	}
+	if q.TraceID != "" && headers.TraceID != q.TraceID {
+		return false
+	}
+	if !q.Since.IsZero() && headers.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !headers.Time.Before(q.Until) {
+		return false
+	}
+	if q.Message != "" && !strings.Contains(row.Message, q.Message) {
+		return false
+	}
+	for key, value := range q.Fields {
+		if !row.hasField(key, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r RingRow) hasField(key string, value string) bool {
+	for _, field := range r.Fields {
+		if field.Key() == key && (value == "" || field.Value() == value) {
+			return true
+		}
+	}
+	for _, field := range r.CommonFields {
+		if field.Key == key && (value == "" || field.Value == value) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRingOutput create a output keeps the latest capacity rows in memory, older rows are overwritten.
+// It is a flight recorder, add it with all levels to inspect recent rows, including DEBUG rows not
+// written to other outputs, with Rows or the http.Handler returned by Handler.
+func NewRingOutput(levels []Severity, capacity int) *RingOutput {
+	if capacity <= 0 {
+		capacity = DefaultRingCapacity
+	}
+	return &RingOutput{Levels: levels, rows: make([]RingRow, 0, capacity)}
+}
+
+// RingOutput output keeps the latest rows in memory, see NewRingOutput
+type RingOutput struct {
+	Levels []Severity
+
+	mu   sync.RWMutex
+	rows []RingRow
+	// next index row is written to once rows are full
+	next int
+}
+
+func (o *RingOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+// Write keep a formatted row as message with info level and current time
+func (o *RingOutput) Write(p []byte) (int, error) {
+	o.add(RingRow{Content: &Content{Headers: MessageHeader{Level: InfoLog, Time: timeNow()}, Message: strings.TrimSuffix(string(p), "\n")}})
+	return len(p), nil
+}
+
+func (o *RingOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	row := *content
+	row.Fields = append([]Field(nil), content.Fields...)
+	o.add(RingRow{Content: &row, CommonFields: commonFields})
+	return nil
+}
+
+func (o *RingOutput) add(row RingRow) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if len(o.rows) < cap(o.rows) {
+		o.rows = append(o.rows, row)
+		return
+	}
+	o.rows[o.next] = row
+	o.next = (o.next + 1) % len(o.rows)
+}
+
+func (o *RingOutput) Flush() error {
+	return nil
+}
+
+// Rows return rows match query, oldest first
+func (o *RingOutput) Rows(query RingQuery) []RingRow {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	var rows []RingRow
+	for i := len(o.rows) - 1; i >= 0; i-- {
+		if query.Limit > 0 && len(rows) >= query.Limit {
+			break
+		}
+		row := o.rows[(o.next+i)%len(o.rows)]
+		if query.match(row) {
+			rows = append(rows, row)
+		}
+	}
+	for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+		rows[i], rows[j] = rows[j], rows[i]
+	}
+	return rows
+}
+
+// Handler create a http.Handler responds rows match query parameters, oldest first:
+//
+//	level     level names, comma separated or repeated, such as level=warning,error
+//	trace_id  trace id
+//	since     RFC3339 time, or duration before now such as 5m
+//	until     RFC3339 time
+//	message   substring of message
+//	field     key:value or key, repeated, fields and common fields are matched
+//	limit     max count of newest rows
+//	format    json(default) or text, text rows are formatted by formatter, default formatter is used when it is nil
+func (o *RingOutput) Handler(formatter Formatter) http.Handler {
+	if formatter == nil {
+		formatter = defaultFormatter()
+	}
+	return &ringHandler{ring: o, formatter: formatter}
+}
+
+type ringHandler struct {
+	ring      *RingOutput
+	formatter Formatter
+}
+
+func (h *ringHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	values := r.URL.Query()
+	query, err := parseRingQuery(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows := h.ring.Rows(query)
+	switch values.Get("format") {
+	case "", "json":
+		if rows == nil {
+			rows = []RingRow{}
+		}
+		body, err := json.Marshal(rows)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("marshal log rows error: %s", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(append(body, '\n'))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		for _, row := range rows {
+			w.Write(h.formatter.Format(row.CommonFields, row.Content))
+		}
+	default:
+		http.Error(w, fmt.Sprintf("unknown format %q, should be one of json, text", values.Get("format")), http.StatusBadRequest)
+	}
+}
+
+func parseRingQuery(values map[string][]string) (RingQuery, error) {
+	get := func(key string) string {
+		if len(values[key]) == 0 {
+			return ""
+		}
+		return values[key][0]
+	}
+	query := RingQuery{TraceID: get("trace_id"), Message: get("message")}
+	for _, value := range values["level"] {
+		for _, name := range strings.Split(value, ",") {
+			level, err := ParseSeverity(name)
+			if err != nil {
+				return query, err
+			}
+			query.Levels = append(query.Levels, level)
+		}
+	}
+	if since := get("since"); since != "" {
+		if d, err := time.ParseDuration(since); err == nil {
+			query.Since = timeNow().Add(-d)
+		} else if query.Since, err = time.Parse(time.RFC3339Nano, since); err != nil {
+			return query, fmt.Errorf("invalid since %q, should be RFC3339 time or duration", since)
+		}
+	}
+	if until := get("until"); until != "" {
+		var err error
+		if query.Until, err = time.Parse(time.RFC3339Nano, until); err != nil {
+			return query, fmt.Errorf("invalid until %q, should be RFC3339 time", until)
+		}
+	}
+	for _, field := range values["field"] {
+		if query.Fields == nil {
+			query.Fields = map[string]string{}
+		}
+		kv := strings.SplitN(field, ":", 2)
+		query.Fields[kv[0]] = ""
+		if len(kv) == 2 {
+			query.Fields[kv[0]] = kv[1]
+		}
+	}
+	if limit := get("limit"); limit != "" {
+		var err error
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 0 {
+			return query, fmt.Errorf("invalid limit %q", limit)
+		}
+	}
+	return query, nil
+}
@@ -0,0 +1,156 @@
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRingOutput() *RingOutput {
+	ring := NewRingOutput([]Severity{DebugLog, InfoLog, WarningLog, ErrorLog}, 4)
+	base := time.Unix(1605884645, 0).UTC()
+	rows := []*Content{
+		{Headers: MessageHeader{Level: DebugLog, Time: base, TraceID: "t1"}, Message: "dropped"},
+		{Headers: MessageHeader{Level: DebugLog, Time: base.Add(time.Second), TraceID: "t1"}, Message: "query order"},
+		{Headers: MessageHeader{Level: InfoLog, Time: base.Add(2 * time.Second), TraceID: "t2"}, Message: "login", Fields: []Field{String("user_id", "1")}},
+		{Headers: MessageHeader{Level: ErrorLog, Time: base.Add(3 * time.Second), TraceID: "t1"}, Message: "create order failed", Fields: []Field{String("user_id", "2")}},
+		{Headers: MessageHeader{Level: WarningLog, Time: base.Add(4 * time.Second), TraceID: "t2"}, Message: "slow query"},
+	}
+	for _, row := range rows {
+		ring.WriteContent([]*CommonField{NewCommonField("service", "order")}, row)
+	}
+	return ring
+}
+
+func ringMessages(rows []RingRow) []string {
+	messages := []string{}
+	for _, row := range rows {
+		messages = append(messages, row.Message)
+	}
+	return messages
+}
+
+func TestRingOutput_Rows(t *testing.T) {
+	ring := testRingOutput()
+	base := time.Unix(1605884645, 0)
+	tests := []struct {
+		Input    RingQuery
+		Expected []string
+	}{
+		{Input: RingQuery{}, Expected: []string{"query order", "login", "create order failed", "slow query"}},
+		{Input: RingQuery{Levels: []Severity{ErrorLog, WarningLog}}, Expected: []string{"create order failed", "slow query"}},
+		{Input: RingQuery{TraceID: "t1"}, Expected: []string{"query order", "create order failed"}},
+		{Input: RingQuery{Since: base.Add(2 * time.Second), Until: base.Add(4 * time.Second)}, Expected: []string{"login", "create order failed"}},
+		{Input: RingQuery{Message: "order"}, Expected: []string{"query order", "create order failed"}},
+		{Input: RingQuery{Fields: map[string]string{"user_id": ""}}, Expected: []string{"login", "create order failed"}},
+		{Input: RingQuery{Fields: map[string]string{"user_id": "2", "service": "order"}}, Expected: []string{"create order failed"}},
+		{Input: RingQuery{Fields: map[string]string{"service": "user"}}, Expected: []string{}},
+		{Input: RingQuery{Limit: 2}, Expected: []string{"create order failed", "slow query"}},
+		{Input: RingQuery{TraceID: "t2", Limit: 1}, Expected: []string{"slow query"}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.Expected, ringMessages(ring.Rows(test.Input)), "%+v", test.Input)
+	}
+}
+
+func TestRingOutput_logging(t *testing.T) {
+	ring := NewRingOutput([]Severity{DebugLog, InfoLog}, 0)
+	l := NewLogging(WithOutput(ring), WithMinLevel(DebugLog), WithCommonField("service", "order"))
+	l.Debug(context.Background(), "debug", String("k", "v"))
+	l.Error(context.Background(), "not recorded")
+	assert.Nil(t, l.Sync())
+	ring.Write([]byte("raw\n"))
+	rows := ring.Rows(RingQuery{})
+	if assert.Equal(t, 2, len(rows)) {
+		assert.Equal(t, "debug", rows[0].Message)
+		assert.Equal(t, DebugLog, rows[0].Headers.Level)
+		assert.True(t, rows[0].hasField("service", "order"))
+		assert.True(t, rows[0].hasField("k", "v"))
+		assert.Equal(t, "raw", rows[1].Message)
+		assert.Equal(t, InfoLog, rows[1].Headers.Level)
+	}
+}
+
+func TestRingOutput_Handler(t *testing.T) {
+	handler := testRingOutput().Handler(NewStringFormatter("{LEVEL} {TRACE_ID} {MESSAGE} {FIELDS}", "", false))
+	tests := []struct {
+		Input    string
+		Expected string
+	}{
+		{Input: "/?format=text&level=warn,error", Expected: "ERROR t1 create order failed {user_id:2}\nWARNING t2 slow query\n"},
+		{Input: "/?format=text&level=debug&level=info", Expected: "DEBUG t1 query order\nINFO t2 login {user_id:1}\n"},
+		{Input: "/?format=text&trace_id=t2&message=query", Expected: "WARNING t2 slow query\n"},
+		{Input: "/?format=text&since=2020-11-20T15:04:07Z&until=2020-11-20T15:04:09Z", Expected: "INFO t2 login {user_id:1}\nERROR t1 create order failed {user_id:2}\n"},
+		{Input: "/?format=text&field=user_id:1", Expected: "INFO t2 login {user_id:1}\n"},
+		{Input: "/?format=text&field=user_id&field=service:order&limit=1", Expected: "ERROR t1 create order failed {user_id:2}\n"},
+		{Input: "/?format=text&field=service:user", Expected: ""},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.Input, nil))
+		assert.Equal(t, http.StatusOK, w.Code, test.Input)
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Equal(t, test.Expected, w.Body.String(), test.Input)
+	}
+}
+
+func TestRingOutput_Handler_json(t *testing.T) {
+	handler := testRingOutput().Handler(nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?trace_id=t1&level=error", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	var rows []map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rows))
+	if assert.Equal(t, 1, len(rows)) {
+		assert.Equal(t, "create order failed", rows[0]["message"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"user_id": "2"}}, rows[0]["fields"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"service": "order"}}, rows[0]["common_fields"])
+		assert.Equal(t, "t1", rows[0]["headers"].(map[string]interface{})["trace_id"])
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?trace_id=t3", nil))
+	assert.Equal(t, "[]\n", w.Body.String())
+}
+
+func TestRingOutput_Handler_error(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	timeNow = func() time.Time {
+		return time.Unix(1605884645, 0).Add(5 * time.Second)
+	}
+	handler := testRingOutput().Handler(nil)
+	tests := []struct {
+		Input    string
+		Expected int
+	}{
+		{Input: "/?since=2s", Expected: http.StatusOK},
+		{Input: "/?level=trace", Expected: http.StatusBadRequest},
+		{Input: "/?since=yesterday", Expected: http.StatusBadRequest},
+		{Input: "/?until=5m", Expected: http.StatusBadRequest},
+		{Input: "/?limit=-1", Expected: http.StatusBadRequest},
+		{Input: "/?format=xml", Expected: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, test.Input, nil))
+		assert.Equal(t, test.Expected, w.Code, test.Input)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?since=2s", nil))
+	var rows []map[string]interface{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &rows))
+	assert.Equal(t, 2, len(rows))
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
@@ -0,0 +1,222 @@
+package logs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRotatingMaxSize default max size of a rotating log file, 100MB
+const DefaultRotatingMaxSize = 100 << 20
+
+// rotatingTimeFormat UTC time suffix of rotated files, such as app.log.20201120-150405.000
+const rotatingTimeFormat = "20060102-150405.000"
+
+// RotatingFileConfig config of NewRotatingFileOutput
+type RotatingFileConfig struct {
+	// Filename file rows are written to, rotated files are named Filename.<time>
+	Filename string
+	// MaxSize max bytes of a file before it is rotated, default is DefaultRotatingMaxSize
+	MaxSize int64
+	// MaxBackups max rotated files kept, 0 is unlimited
+	MaxBackups int
+	// MaxAge max age of rotated files kept, 0 is unlimited
+	MaxAge time.Duration
+}
+
+// NewRotatingFileOutput create a file log output rotates file when its size exceeds MaxSize.
+// A row is never split across files. Old rotated files are removed by MaxBackups and MaxAge.
+func NewRotatingFileOutput(levels []Severity, config RotatingFileConfig) (Output, error) {
+	if config.Filename == "" {
+		return nil, fmt.Errorf("rotating log filename is empty")
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultRotatingMaxSize
+	}
+	o := &rotatingOutput{
+		Levels: levels,
+		config: config,
+	}
+	if err := o.open(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+type rotatingOutput struct {
+	Levels []Severity
+	config RotatingFileConfig
+	mu     sync.Mutex
+	file   *os.File
+	buf    *bufio.Writer
+	size   int64
+}
+
+func (o *rotatingOutput) open() error {
+	fl, err := os.OpenFile(o.config.Filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open log file error: %s", err)
+	}
+	info, err := fl.Stat()
+	if err != nil {
+		fl.Close()
+		return fmt.Errorf("stat log file error: %s", err)
+	}
+	o.file = fl
+	o.buf = bufio.NewWriter(fl)
+	o.size = info.Size()
+	return nil
+}
+
+func (o *rotatingOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+func (o *rotatingOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return 0, fmt.Errorf("rotating log file %s is closed", o.config.Filename)
+	}
+	if o.size > 0 && o.size+int64(len(p)) > o.config.MaxSize {
+		if err := o.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := o.buf.Write(p)
+	o.size += int64(n)
+	return n, err
+}
+
+func (o *rotatingOutput) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	return o.buf.Flush()
+}
+
+// Close flush and close current file
+func (o *rotatingOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.file == nil {
+		return nil
+	}
+	err := o.buf.Flush()
+	if closeErr := o.file.Close(); err == nil {
+		err = closeErr
+	}
+	o.file = nil
+	return err
+}
+
+// osRename rename file, mocked in tests
+var osRename = os.Rename
+
+// rotate rename current file with time suffix then open a new one.
+// When it fails the original file is reopened, so later writes can go on and rotate again.
+func (o *rotatingOutput) rotate() error {
+	if err := o.buf.Flush(); err != nil {
+		return err
+	}
+	if err := o.file.Close(); err != nil {
+		return o.reopen(o.config.Filename, fmt.Errorf("close log file error: %s", err))
+	}
+	o.file = nil
+	rotated := o.backupName()
+	if err := osRename(o.config.Filename, rotated); err != nil {
+		return o.reopen(o.config.Filename, fmt.Errorf("rotate log file error: %s", err))
+	}
+	if err := o.open(); err != nil {
+		return o.reopen(rotated, err)
+	}
+	o.removeBackups()
+	return nil
+}
+
+// reopen open filename in append mode after rotate failed with err, err is returned.
+func (o *rotatingOutput) reopen(filename string, err error) error {
+	fl, openErr := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if openErr != nil {
+		o.file = nil
+		return fmt.Errorf("%s, reopen log file error: %s", err, openErr)
+	}
+	o.file = fl
+	o.buf = bufio.NewWriter(fl)
+	return err
+}
+
+// backupName name of rotated file, a sequence is appended when files are rotated in the same millisecond,
+// such as app.log.20201120-150405.000.1
+func (o *rotatingOutput) backupName() string {
+	name := o.config.Filename + "." + timeNow().UTC().Format(rotatingTimeFormat)
+	backup := name
+	for seq := 1; ; seq++ {
+		if _, err := os.Lstat(backup); os.IsNotExist(err) {
+			return backup
+		}
+		backup = fmt.Sprintf("%s.%d", name, seq)
+	}
+}
+
+// parseBackup parse time and sequence of rotated file suffix, ok is false when it is not a rotated file
+func parseBackup(suffix string) (t time.Time, seq int, ok bool) {
+	if len(suffix) > len(rotatingTimeFormat) {
+		if suffix[len(rotatingTimeFormat)] != '.' {
+			return t, 0, false
+		}
+		var err error
+		if seq, err = strconv.Atoi(suffix[len(rotatingTimeFormat)+1:]); err != nil || seq <= 0 {
+			return t, 0, false
+		}
+		suffix = suffix[:len(rotatingTimeFormat)]
+	}
+	t, err := time.Parse(rotatingTimeFormat, suffix)
+	return t, seq, err == nil
+}
+
+// removeBackups remove rotated files over MaxBackups or older than MaxAge
+func (o *rotatingOutput) removeBackups() {
+	if o.config.MaxBackups <= 0 && o.config.MaxAge <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(o.config.Filename + ".*")
+	if err != nil {
+		return
+	}
+	prefix := o.config.Filename + "."
+	type backup struct {
+		name string
+		time time.Time
+		seq  int
+	}
+	backups := make([]backup, 0, len(matches))
+	for _, match := range matches {
+		if t, seq, ok := parseBackup(strings.TrimPrefix(match, prefix)); ok {
+			backups = append(backups, backup{name: match, time: t, seq: seq})
+		}
+	}
+	// newest first
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].time.Equal(backups[j].time) {
+			return backups[i].seq > backups[j].seq
+		}
+		return backups[i].time.After(backups[j].time)
+	})
+	for i, backup := range backups {
+		expired := o.config.MaxAge > 0 && timeNow().Sub(backup.time) > o.config.MaxAge
+		if expired || (o.config.MaxBackups > 0 && i >= o.config.MaxBackups) {
+			if err := os.Remove(backup.name); err != nil {
+				fmt.Printf("remove rotated log file error %s \n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package logs
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRotatedFiles(t *testing.T, filename string) []string {
+	matches, err := filepath.Glob(filename + ".*")
+	assert.Nil(t, err)
+	sort.Strings(matches)
+	return matches
+}
+
+func TestNewRotatingFileOutput(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	dir, err := ioutil.TempDir("", "rotating")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.log")
+
+	now := time.Date(2020, 11, 20, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	output, err := NewRotatingFileOutput(AllSeverities, RotatingFileConfig{Filename: filename, MaxSize: 10, MaxBackups: 2})
+	assert.Nil(t, err)
+	for _, row := range []string{"row1 12345\n", "row2\n", "row3\n", "row4 12345\n", "row5\n"} {
+		_, err = output.Write([]byte(row))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, output.Flush())
+
+	current, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, "row5\n", string(current))
+	rotated := testRotatedFiles(t, filename)
+	if assert.Equal(t, 2, len(rotated)) {
+		// rows never split across files, row1 is removed by MaxBackups
+		content, _ := ioutil.ReadFile(rotated[0])
+		assert.Equal(t, "row2\nrow3\n", string(content))
+		content, _ = ioutil.ReadFile(rotated[1])
+		assert.Equal(t, "row4 12345\n", string(content))
+		assert.Equal(t, filename+".20201120-000002.000", rotated[0])
+	}
+
+	assert.Nil(t, output.(io.Closer).Close())
+	_, err = output.Write([]byte("closed\n"))
+	assert.NotNil(t, err)
+	assert.Nil(t, output.(io.Closer).Close())
+}
+
+func TestNewRotatingFileOutput_maxAge(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	dir, err := ioutil.TempDir("", "rotating")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.log")
+	assert.Nil(t, ioutil.WriteFile(filename+".20201101-000000.000", []byte("old\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filename+".not-a-backup", []byte("keep\n"), 0644))
+	// existing size is counted
+	assert.Nil(t, ioutil.WriteFile(filename, []byte("existing\n"), 0644))
+
+	timeNow = func() time.Time {
+		return time.Date(2020, 11, 20, 0, 0, 0, 0, time.UTC)
+	}
+	output, err := NewRotatingFileOutput(AllSeverities, RotatingFileConfig{Filename: filename, MaxSize: 10, MaxAge: 24 * time.Hour})
+	assert.Nil(t, err)
+	_, err = output.Write([]byte("new\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, output.Flush())
+
+	assert.Equal(t, []string{filename + ".20201120-000000.000", filename + ".not-a-backup"}, testRotatedFiles(t, filename))
+}
+
+func TestNewRotatingFileOutput_sameTime(t *testing.T) {
+	defer func() {
+		timeNow = time.Now
+	}()
+	dir, err := ioutil.TempDir("", "rotating")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.log")
+
+	timeNow = func() time.Time {
+		return time.Date(2020, 11, 20, 0, 0, 0, 0, time.UTC)
+	}
+	output, err := NewRotatingFileOutput(AllSeverities, RotatingFileConfig{Filename: filename, MaxSize: 5, MaxBackups: 2})
+	assert.Nil(t, err)
+	for _, row := range []string{"row1\n", "row2\n", "row3\n", "row4\n"} {
+		_, err = output.Write([]byte(row))
+		assert.Nil(t, err)
+	}
+	assert.Nil(t, output.Flush())
+
+	// backups rotated in the same millisecond are not overwritten, the oldest is removed by MaxBackups
+	name := filename + ".20201120-000000.000"
+	assert.Equal(t, []string{name + ".1", name + ".2"}, testRotatedFiles(t, filename))
+	content, _ := ioutil.ReadFile(name + ".1")
+	assert.Equal(t, "row2\n", string(content))
+	content, _ = ioutil.ReadFile(name + ".2")
+	assert.Equal(t, "row3\n", string(content))
+}
+
+func TestNewRotatingFileOutput_rotateError(t *testing.T) {
+	defer func() {
+		osRename = os.Rename
+	}()
+	dir, err := ioutil.TempDir("", "rotating")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "app.log")
+
+	osRename = func(string, string) error {
+		return errors.New("rename failed")
+	}
+	output, err := NewRotatingFileOutput(AllSeverities, RotatingFileConfig{Filename: filename, MaxSize: 5})
+	assert.Nil(t, err)
+	_, err = output.Write([]byte("row1\n"))
+	assert.Nil(t, err)
+	_, err = output.Write([]byte("row2\n"))
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "rotate log file error: rename failed", err.Error())
+	}
+
+	// original file is reopened and rotated once rename works again
+	osRename = os.Rename
+	_, err = output.Write([]byte("row3\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, output.Flush())
+	current, _ := ioutil.ReadFile(filename)
+	assert.Equal(t, "row3\n", string(current))
+	rotated := testRotatedFiles(t, filename)
+	if assert.Equal(t, 1, len(rotated)) {
+		content, _ := ioutil.ReadFile(rotated[0])
+		assert.Equal(t, "row1\n", string(content))
+	}
+	assert.Nil(t, output.(io.Closer).Close())
+}
+
+func TestParseBackup(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected struct {
+			Seq int
+			OK  bool
+		}
+	}{
+		{Input: "20201120-000000.000", Expected: struct {
+			Seq int
+			OK  bool
+		}{Seq: 0, OK: true}},
+		{Input: "20201120-000000.000.12", Expected: struct {
+			Seq int
+			OK  bool
+		}{Seq: 12, OK: true}},
+		{Input: "20201120-000000.000.x", Expected: struct {
+			Seq int
+			OK  bool
+		}{}},
+		{Input: "20201120-000000.0001", Expected: struct {
+			Seq int
+			OK  bool
+		}{}},
+		{Input: "not-a-backup", Expected: struct {
+			Seq int
+			OK  bool
+		}{}},
+	}
+	for _, testCase := range testCases {
+		_, seq, ok := parseBackup(testCase.Input)
+		assert.Equal(t, testCase.Expected.Seq, seq, testCase.Input)
+		assert.Equal(t, testCase.Expected.OK, ok, testCase.Input)
+	}
+}
+
+func TestNewRotatingFileOutput_error(t *testing.T) {
+	_, err := NewRotatingFileOutput(AllSeverities, RotatingFileConfig{})
+	assert.NotNil(t, err)
+	_, err = NewRotatingFileOutput(AllSeverities, RotatingFileConfig{Filename: filepath.Join("not", "exists", "dir", "app.log")})
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,223 @@
+//go:build go1.21
+// +build go1.21
+
+package logs
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// SlogLevelFatal slog level mapped to FatalLog, slog has no fatal level itself.
+const SlogLevelFatal = slog.LevelError + 4
+
+// SeverityFromSlogLevel map slog level to Severity.
+func SeverityFromSlogLevel(level slog.Level) Severity {
+	switch {
+	case level < slog.LevelInfo:
+		return DebugLog
+	case level < slog.LevelWarn:
+		return InfoLog
+	case level < slog.LevelError:
+		return WarningLog
+	case level < SlogLevelFatal:
+		return ErrorLog
+	}
+	return FatalLog
+}
+
+// SlogLevelFromSeverity map Severity to slog level.
+func SlogLevelFromSeverity(s Severity) slog.Level {
+	switch s {
+	case DebugLog:
+		return slog.LevelDebug
+	case InfoLog:
+		return slog.LevelInfo
+	case WarningLog:
+		return slog.LevelWarn
+	case ErrorLog:
+		return slog.LevelError
+	}
+	return SlogLevelFatal
+}
+
+// NewSlogHandler create a slog.Handler forwards records to logger.
+// Attributes and groups are logged as fields, group names are joined to field key with dot(such as request.id).
+// When logger is NewLogging returned instance or Default(), record PC is used as log file and line.
+func NewSlogHandler(logger Logger) slog.Handler {
+	return &slogHandler{logger: logger}
+}
+
+type slogHandler struct {
+	logger Logger
+	fields []Field
+	prefix string
+}
+
+// target return the logging instance records can be written to with PC as caller.
+func (h *slogHandler) target() *logging {
+	switch l := h.logger.(type) {
+	case *logging:
+		return l
+	case globalLogger:
+		return log()
+	}
+	return nil
+}
+
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	l := h.target()
+	if l == nil {
+		return true
+	}
+	s := SeverityFromSlogLevel(level)
+	options := l.opts()
+	if s < options.lowestLevel() {
+		return false
+	}
+	for _, output := range options.outputs {
+		if output.IsLevelNeedRecord(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *slogHandler) Handle(ctx context.Context, record slog.Record) error {
+	fields := make([]Field, 0, len(h.fields)+record.NumAttrs())
+	fields = append(fields, h.fields...)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.prefix, attr)
+		return true
+	})
+	s := SeverityFromSlogLevel(record.Level)
+
+	l := h.target()
+	if l == nil {
+		h.logger.LogDepth(ctx, s, 0, record.Message, fields...)
+		return nil
+	}
+	var file string
+	var line int
+	if record.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		file, line = frame.File, frame.Line
+	}
+	if !l.opts().levelEnabled(s, record.PC, file) {
+		return nil
+	}
+	headers := l.callerHeader(ctx, s, file, line, file != "")
+	if !record.Time.IsZero() {
+		headers.Time = record.Time
+	}
+	l.send(ctx, &Content{
+		Headers: headers,
+		Message: record.Message,
+		Fields:  fields,
+	})
+	return nil
+}
+
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make([]Field, 0, len(h.fields)+len(attrs))
+	fields = append(fields, h.fields...)
+	for _, attr := range attrs {
+		fields = appendSlogAttr(fields, h.prefix, attr)
+	}
+	return &slogHandler{logger: h.logger, fields: fields, prefix: h.prefix}
+}
+
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &slogHandler{logger: h.logger, fields: h.fields, prefix: h.prefix + name + "."}
+}
+
+// appendSlogAttr convert attr to fields follow slog.Handler rules:
+// empty attrs are ignored, groups are flattened with dot joined keys, groups with empty key are inlined.
+func appendSlogAttr(fields []Field, prefix string, attr slog.Attr) []Field {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return fields
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			fields = appendSlogAttr(fields, groupPrefix, groupAttr)
+		}
+		return fields
+	}
+	if attr.Value.Kind() == slog.KindTime {
+		return append(fields, String(prefix+attr.Key, attr.Value.Time().Format(time.RFC3339Nano)))
+	}
+	return append(fields, String(prefix+attr.Key, attr.Value.String()))
+}
+
+// SlogAttrs convert fields to slog attributes
+func SlogAttrs(fields ...Field) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, field := range fields {
+		attrs = append(attrs, slog.String(field.Key(), field.Value()))
+	}
+	return attrs
+}
+
+// NewSlogOutput create a output writes log rows through an existing slog.Handler.
+// Fields and common fields are converted to attributes, trace id, span id and caller are added as
+// trace_id, span_id and caller attributes.
+func NewSlogOutput(levels []Severity, handler slog.Handler) Output {
+	return &slogOutput{
+		Levels:  levels,
+		handler: handler,
+	}
+}
+
+type slogOutput struct {
+	Levels  []Severity
+	handler slog.Handler
+}
+
+func (o *slogOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+func (o *slogOutput) Flush() error {
+	return nil
+}
+
+// Write write formatted bytes as an info record message.
+func (o *slogOutput) Write(p []byte) (int, error) {
+	record := slog.NewRecord(timeNow(), slog.LevelInfo, string(p), 0)
+	return len(p), o.handler.Handle(context.Background(), record)
+}
+
+func (o *slogOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	level := SlogLevelFromSeverity(content.Headers.Level)
+	ctx := context.Background()
+	if !o.handler.Enabled(ctx, level) {
+		return nil
+	}
+	record := slog.NewRecord(content.Headers.Time, level, content.Message, 0)
+	record.AddAttrs(SlogAttrs(content.Fields...)...)
+	for _, commonField := range commonFields {
+		record.AddAttrs(slog.String(commonField.Key, commonField.Value))
+	}
+	if content.Headers.TraceID != "" {
+		record.AddAttrs(slog.String("trace_id", content.Headers.TraceID))
+	}
+	if content.Headers.SpanID != "" {
+		record.AddAttrs(slog.String("span_id", content.Headers.SpanID))
+	}
+	record.AddAttrs(slog.String("caller", content.Headers.File+":"+strconv.Itoa(content.Headers.Line)))
+	return o.handler.Handle(ctx, record)
+}
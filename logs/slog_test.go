@@ -0,0 +1,113 @@
+//go:build go1.21
+// +build go1.21
+
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverityFromSlogLevel(t *testing.T) {
+	testCases := []struct {
+		Input    slog.Level
+		Expected Severity
+	}{
+		{Input: slog.LevelDebug - 4, Expected: DebugLog},
+		{Input: slog.LevelDebug, Expected: DebugLog},
+		{Input: slog.LevelInfo, Expected: InfoLog},
+		{Input: slog.LevelInfo + 2, Expected: InfoLog},
+		{Input: slog.LevelWarn, Expected: WarningLog},
+		{Input: slog.LevelError, Expected: ErrorLog},
+		{Input: SlogLevelFatal, Expected: FatalLog},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, SeverityFromSlogLevel(testCase.Input))
+	}
+	for _, s := range AllSeverities {
+		assert.Equal(t, s, SeverityFromSlogLevel(SlogLevelFromSeverity(s)))
+	}
+}
+
+func TestSlogHandler(t *testing.T) {
+	outputCollects := bytes.Buffer{}
+	l := NewLogging(
+		WithFormatter(NewStringFormatter("{LEVEL} {TRACE_ID} {FILE} {MESSAGE} {FIELDS}", defaultTimeHeaderFormat(), false)),
+		WithOutput(NewOutPut([]Severity{InfoLog, WarningLog, ErrorLog}, &outputCollects)),
+	)
+	logger := slog.New(NewSlogHandler(l))
+	ctx := context.WithValue(context.Background(), TraceIDIdentifier, "slog_trace_id")
+
+	assert.False(t, logger.Enabled(ctx, slog.LevelDebug))
+	assert.True(t, logger.Enabled(ctx, slog.LevelWarn))
+
+	logger.DebugContext(ctx, "not recorded")
+	logger.InfoContext(ctx, "slog info", "user_id", 1, slog.Group("request", "method", "GET", slog.Group("", "inline", true)), slog.Group("empty"))
+	logger.With("app", "feehi").WithGroup("db").With("table", "user").ErrorContext(ctx, "slog error", "err", errors.New("not found"))
+	logger.WithGroup("").Log(ctx, slog.LevelWarn, "slog warn", slog.Any("", nil))
+	l.Sync()
+
+	assert.Equal(t, "INFO slog_trace_id slog_test.go slog info {user_id:1,request.method:GET,request.inline:true}\n"+
+		"ERROR slog_trace_id slog_test.go slog error {app:feehi,db.table:user,db.err:not found}\n"+
+		"WARNING slog_trace_id slog_test.go slog warn\n", outputCollects.String())
+}
+
+func TestSlogHandler_time(t *testing.T) {
+	outputCollects := bytes.Buffer{}
+	l := NewLogging(WithFormatter(NewJSONFormatter()), WithOutput(NewOutPut(AllSeverities, &outputCollects)))
+	handler := NewSlogHandler(l)
+	recordTime := time.Date(2020, 11, 20, 0, 0, 0, 0, time.UTC)
+	record := slog.NewRecord(recordTime, slog.LevelInfo, "record without pc", 0)
+	record.AddAttrs(slog.Time("at", recordTime))
+	assert.Nil(t, handler.Handle(context.Background(), record))
+	l.Sync()
+
+	content := struct {
+		Content
+		Fields []map[string]string `json:"fields"`
+	}{}
+	assert.Nil(t, json.Unmarshal(outputCollects.Bytes(), &content))
+	assert.Equal(t, recordTime, content.Headers.Time.UTC())
+	assert.Equal(t, "???", content.Headers.File)
+	assert.Equal(t, []map[string]string{{"at": "2020-11-20T00:00:00Z"}}, content.Fields)
+}
+
+func TestSlogHandler_default(t *testing.T) {
+	testInitLogging()
+	slog.New(NewSlogHandler(Default())).Info("slog default", "k", "v")
+	Sync()
+	assert.Contains(t, outputCollects.String(), "slog_test.go")
+	assert.Contains(t, outputCollects.String(), "slog default {k:v}")
+}
+
+func TestSlogOutput(t *testing.T) {
+	buf := bytes.Buffer{}
+	handler := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	l := NewLogging(WithOutput(NewSlogOutput(AllSeverities, handler)), WithCommonField("instance", "testMachine"))
+	ctx := context.WithValue(context.Background(), TraceIDIdentifier, "slog_trace_id")
+	l.Debug(ctx, "filtered by handler level")
+	l.Warning(ctx, "through slog", String("category", "Go"))
+	l.Sync()
+
+	record := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "through slog", record["msg"])
+	assert.Equal(t, "Go", record["category"])
+	assert.Equal(t, "testMachine", record["instance"])
+	assert.Equal(t, "slog_trace_id", record["trace_id"])
+	assert.Contains(t, record["caller"], "slog_test.go:")
+
+	buf.Reset()
+	n, err := NewSlogOutput(AllSeverities, handler).Write([]byte("raw row"))
+	assert.Nil(t, err)
+	assert.Equal(t, 7, n)
+	assert.Contains(t, buf.String(), "raw row")
+}
@@ -0,0 +1,517 @@
+package logs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSpoolSegmentSize default max bytes of a spool segment, 8MB
+	DefaultSpoolSegmentSize = 8 << 20
+	// DefaultSpoolMaxSize default max bytes of all spool segments, 256MB
+	DefaultSpoolMaxSize = 256 << 20
+	// spoolSegmentExt file extension of spool segments
+	spoolSegmentExt = ".spool"
+	// spoolCursorFile file keeps segment id, offset and records count forwarded
+	spoolCursorFile = "cursor"
+	// spoolRecordHeaderSize length, checksum and kind of a record
+	spoolRecordHeaderSize = 9
+)
+
+const (
+	// spoolRawRecord payload is a formatted row
+	spoolRawRecord byte = iota
+	// spoolContentRecord payload is a MessagePack record of encodeRecord
+	spoolContentRecord
+)
+
+var errSpoolCorrupt = errors.New("corrupt spool record")
+
+// SpoolConfig config of NewSpoolOutput
+type SpoolConfig struct {
+	// Dir directory of spool segments, created when it does not exist
+	Dir string
+	// SegmentSize max bytes of a segment, default is DefaultSpoolSegmentSize, at most half of MaxSize
+	SegmentSize int64
+	// MaxSize max bytes of all segments, oldest segments are evicted when it is exceeded, default is DefaultSpoolMaxSize
+	MaxSize int64
+	// BatchCount max rows forwarded before wrapped output is flushed, default is 100
+	BatchCount int
+	// MinBackoff first delay before forwarding again after wrapped output fails, doubled for every failure up to
+	// MaxBackoff, default is 100ms
+	MinBackoff time.Duration
+	// MaxBackoff default is 30s
+	MaxBackoff time.Duration
+}
+
+// NewSpoolOutput create a output writes rows to append-only segment files in Dir, and forwards them to output
+// in order in background. Rows are flushed to output in batches, a batch is forwarded again when output fails,
+// so rows are delivered at least once. Rows not forwarded are kept on disk and forwarded again after restart,
+// records broken by a crash are truncated. Flush syncs segment to disk, and returns count of evicted rows and
+// forwarding errors since last Flush.
+// Rows are replayed to output as content when it is a ContentOutput, otherwise as formatted rows.
+func NewSpoolOutput(output Output, config SpoolConfig) (Output, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("log spool dir is empty")
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = DefaultSpoolMaxSize
+	}
+	if config.SegmentSize <= 0 {
+		config.SegmentSize = DefaultSpoolSegmentSize
+	}
+	if config.SegmentSize > config.MaxSize/2 {
+		config.SegmentSize = config.MaxSize / 2
+	}
+	if config.BatchCount <= 0 {
+		config.BatchCount = 100
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = 100 * time.Millisecond
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = 30 * time.Second
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+	o := &spoolOutput{
+		output:  output,
+		config:  config,
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if err := o.recover(); err != nil {
+		return nil, err
+	}
+	go o.forward()
+	if _, ok := output.(ContentOutput); ok {
+		return &spoolContentOutput{o}, nil
+	}
+	return o, nil
+}
+
+type spoolOutput struct {
+	output Output
+	config SpoolConfig
+
+	mu sync.Mutex
+	// segments oldest first, rows are written to the last one and forwarded from the first one
+	segments []*spoolSegment
+	file     *os.File
+	size     int64
+	// readOffset and readRecords forwarded bytes and records of the first segment
+	readOffset  int64
+	readRecords int
+	evicted     int
+	errs        []string
+	closed      bool
+
+	notify  chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+type spoolSegment struct {
+	id      int64
+	size    int64
+	records int
+}
+
+// spoolContentOutput spool output of a ContentOutput, rows are kept as content
+type spoolContentOutput struct {
+	*spoolOutput
+}
+
+func (o *spoolContentOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	e := &msgpackEncoder{buf: make([]byte, 0, 256)}
+	encodeRecord(e, commonFields, content)
+	return o.append(spoolContentRecord, e.bytes())
+}
+
+func (o *spoolOutput) IsLevelNeedRecord(s Severity) bool {
+	return o.output.IsLevelNeedRecord(s)
+}
+
+// Write append a formatted row to spool
+func (o *spoolOutput) Write(p []byte) (int, error) {
+	if err := o.append(spoolRawRecord, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (o *spoolOutput) append(kind byte, payload []byte) error {
+	record := make([]byte, spoolRecordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(record, uint32(len(payload)+1))
+	record[8] = kind
+	copy(record[9:], payload)
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(record[8:]))
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return fmt.Errorf("log spool %s is closed", o.config.Dir)
+	}
+	last := o.segments[len(o.segments)-1]
+	if last.size > 0 && last.size+int64(len(record)) > o.config.SegmentSize {
+		if err := o.rotate(); err != nil {
+			return err
+		}
+		last = o.segments[len(o.segments)-1]
+	}
+	n, err := o.file.Write(record)
+	if err != nil {
+		// a partly written record is truncated by recovery, and following records are not readable before it
+		o.file.Truncate(last.size)
+		return fmt.Errorf("write log spool error: %s", err)
+	}
+	last.size += int64(n)
+	last.records++
+	o.size += int64(n)
+	o.evict()
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// rotate start a new segment, it must be called with mu held.
+func (o *spoolOutput) rotate() error {
+	segment := &spoolSegment{id: o.segments[len(o.segments)-1].id + 1}
+	file, err := os.OpenFile(o.segmentPath(segment.id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("create log spool segment error: %s", err)
+	}
+	o.file.Close()
+	o.file = file
+	o.segments = append(o.segments, segment)
+	return nil
+}
+
+// evict remove oldest segments until size is under MaxSize, it must be called with mu held.
+func (o *spoolOutput) evict() {
+	for o.size > o.config.MaxSize && len(o.segments) > 1 {
+		o.evicted += o.segments[0].records - o.readRecords
+		o.removeFirst()
+	}
+}
+
+// removeFirst remove first segment and forward from start of next one, it must be called with mu held.
+func (o *spoolOutput) removeFirst() {
+	segment := o.segments[0]
+	os.Remove(o.segmentPath(segment.id))
+	o.size -= segment.size
+	o.segments = o.segments[1:]
+	o.readOffset = 0
+	o.readRecords = 0
+	o.saveCursor()
+}
+
+func (o *spoolOutput) segmentPath(id int64) string {
+	return filepath.Join(o.config.Dir, fmt.Sprintf("%020d%s", id, spoolSegmentExt))
+}
+
+// saveCursor save forwarding position, it must be called with mu held.
+func (o *spoolOutput) saveCursor() {
+	if len(o.segments) == 0 {
+		return
+	}
+	cursor := fmt.Sprintf("%d %d %d\n", o.segments[0].id, o.readOffset, o.readRecords)
+	path := filepath.Join(o.config.Dir, spoolCursorFile)
+	if err := ioutil.WriteFile(path+".tmp", []byte(cursor), 0644); err != nil {
+		o.errs = append(o.errs, fmt.Sprintf("save log spool cursor error: %s", err))
+		return
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		o.errs = append(o.errs, fmt.Sprintf("save log spool cursor error: %s", err))
+	}
+}
+
+// recover load segments and cursor of Dir, segments forwarded are removed and broken records are truncated.
+func (o *spoolOutput) recover() error {
+	if err := os.MkdirAll(o.config.Dir, 0755); err != nil {
+		return fmt.Errorf("create log spool dir error: %s", err)
+	}
+	names, err := filepath.Glob(filepath.Join(o.config.Dir, "*"+spoolSegmentExt))
+	if err != nil {
+		return fmt.Errorf("list log spool segments error: %s", err)
+	}
+	var ids []int64
+	for _, name := range names {
+		id, err := strconv.ParseInt(strings.TrimSuffix(filepath.Base(name), spoolSegmentExt), 10, 64)
+		if err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var cursorID, cursorOffset int64
+	if b, err := ioutil.ReadFile(filepath.Join(o.config.Dir, spoolCursorFile)); err == nil {
+		fmt.Sscanf(string(b), "%d %d", &cursorID, &cursorOffset)
+	}
+	for _, id := range ids {
+		if id < cursorID {
+			os.Remove(o.segmentPath(id))
+			continue
+		}
+		segment := &spoolSegment{id: id}
+		var offsets []int64
+		if err := o.scan(segment, &offsets); err != nil {
+			return err
+		}
+		if len(o.segments) == 0 && id == cursorID {
+			// forward from the last record boundary not after cursor
+			for _, offset := range offsets {
+				if offset > cursorOffset {
+					break
+				}
+				o.readOffset = offset
+				o.readRecords++
+			}
+		}
+		o.segments = append(o.segments, segment)
+		o.size += segment.size
+	}
+
+	if len(o.segments) == 0 {
+		next := int64(1)
+		if len(ids) > 0 {
+			next = ids[len(ids)-1] + 1
+		}
+		o.segments = append(o.segments, &spoolSegment{id: next})
+	}
+	last := o.segments[len(o.segments)-1]
+	if o.file, err = os.OpenFile(o.segmentPath(last.id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err != nil {
+		return fmt.Errorf("open log spool segment error: %s", err)
+	}
+	o.saveCursor()
+	o.evict()
+	return nil
+}
+
+// scan count valid records of segment and append end offset of each record to offsets,
+// segment is truncated after the last valid record.
+func (o *spoolOutput) scan(segment *spoolSegment, offsets *[]int64) error {
+	path := o.segmentPath(segment.id)
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open log spool segment error: %s", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("open log spool segment error: %s", err)
+	}
+	reader := bufio.NewReader(file)
+	for {
+		_, _, n, err := readSpoolRecord(reader)
+		if err != nil {
+			break
+		}
+		segment.size += n
+		segment.records++
+		*offsets = append(*offsets, segment.size)
+	}
+	file.Close()
+	if segment.size < info.Size() {
+		if err := os.Truncate(path, segment.size); err != nil {
+			return fmt.Errorf("truncate log spool segment error: %s", err)
+		}
+	}
+	return nil
+}
+
+// readSpoolRecord read a record of length, checksum, kind and payload, n is bytes of the record.
+// It returns io.EOF at end of segment, and errSpoolCorrupt for partly written or broken records.
+func readSpoolRecord(reader *bufio.Reader) (kind byte, payload []byte, n int64, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(reader, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = errSpoolCorrupt
+		}
+		return 0, nil, 0, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length == 0 || length > maxFrameSize {
+		return 0, nil, 0, errSpoolCorrupt
+	}
+	body := make([]byte, length)
+	if _, err = io.ReadFull(reader, body); err != nil {
+		return 0, nil, 0, errSpoolCorrupt
+	}
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(header[4:]) {
+		return 0, nil, 0, errSpoolCorrupt
+	}
+	return body[0], body[1:], int64(8 + length), nil
+}
+
+// forward send spooled rows to output in order until spool is closed
+func (o *spoolOutput) forward() {
+	defer close(o.stopped)
+	var backoff time.Duration
+	for {
+		forwarded, err := o.forwardBatch()
+		if err != nil {
+			o.mu.Lock()
+			o.errs = append(o.errs, fmt.Sprintf("forward spooled rows error: %s", err))
+			o.mu.Unlock()
+			backoff = nextBackoff(backoff, o.config.MinBackoff, o.config.MaxBackoff)
+			select {
+			case <-o.done:
+				return
+			case <-time.After(jitter(backoff)):
+			}
+			continue
+		}
+		backoff = 0
+		if forwarded {
+			select {
+			case <-o.done:
+				return
+			default:
+			}
+			continue
+		}
+		select {
+		case <-o.done:
+			return
+		case <-o.notify:
+		}
+	}
+}
+
+// forwardBatch forward a batch of rows of first segment and flush output, forwarding position is saved after
+// output is flushed. It returns false when there is no row to forward.
+func (o *spoolOutput) forwardBatch() (bool, error) {
+	o.mu.Lock()
+	for o.readOffset >= o.segments[0].size && len(o.segments) > 1 {
+		o.removeFirst()
+	}
+	segment := o.segments[0]
+	id, offset, size := segment.id, o.readOffset, segment.size
+	o.mu.Unlock()
+	if offset >= size {
+		return false, nil
+	}
+
+	file, err := os.Open(o.segmentPath(id))
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(io.NewSectionReader(file, offset, size-offset))
+	next := offset
+	records := 0
+	for records < o.config.BatchCount && next < size {
+		kind, payload, n, err := readSpoolRecord(reader)
+		if err != nil {
+			if records > 0 {
+				break
+			}
+			// rest of a broken segment can not be read, skip it rather than retrying it forever
+			o.mu.Lock()
+			if o.segments[0].id == id && o.readOffset == offset {
+				o.readOffset = size
+				o.saveCursor()
+			}
+			o.mu.Unlock()
+			return false, fmt.Errorf("read segment %d at %d error: %s, rest of it is skipped", id, next, err)
+		}
+		if err = o.forwardRecord(kind, payload); err != nil {
+			return false, err
+		}
+		next += n
+		records++
+	}
+	if err = o.output.Flush(); err != nil {
+		return false, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	// position is moved by eviction while forwarding
+	if o.segments[0].id == id && o.readOffset == offset {
+		o.readOffset = next
+		o.readRecords += records
+		o.saveCursor()
+	}
+	return true, nil
+}
+
+func (o *spoolOutput) forwardRecord(kind byte, payload []byte) error {
+	if kind == spoolContentRecord {
+		v, err := unmarshalMsgPack(payload)
+		if err != nil {
+			return err
+		}
+		commonFields, content, err := decodeRecord(v)
+		if err != nil {
+			return err
+		}
+		return o.output.(ContentOutput).WriteContent(commonFields, content)
+	}
+	_, err := o.output.Write(payload)
+	return err
+}
+
+// Flush sync segment to disk, and return count of evicted rows and forwarding errors since last Flush
+func (o *spoolOutput) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	errs := o.errs
+	o.errs = nil
+	if o.evicted > 0 {
+		errs = append(errs, fmt.Sprintf("log spool %s is full, %d rows evicted", o.config.Dir, o.evicted))
+		o.evicted = 0
+	}
+	if !o.closed {
+		if err := o.file.Sync(); err != nil {
+			errs = append(errs, fmt.Sprintf("sync log spool error: %s", err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Close stop forwarding and close segment and output, rows not forwarded are forwarded after restart.
+func (o *spoolOutput) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	o.mu.Unlock()
+	close(o.done)
+	<-o.stopped
+
+	o.mu.Lock()
+	err := o.file.Sync()
+	if closeErr := o.file.Close(); err == nil {
+		err = closeErr
+	}
+	o.mu.Unlock()
+	if closer, ok := o.output.(io.Closer); ok {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
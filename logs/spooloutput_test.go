@@ -0,0 +1,248 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSinkOutput output keeps rows written since last Flush, they are dropped with error when it is down.
+// It needs all levels when levels is nil.
+type testSinkOutput struct {
+	levels  []Severity
+	mu      sync.Mutex
+	down    bool
+	pending []string
+	rows    []string
+	flushes int
+}
+
+func (o *testSinkOutput) IsLevelNeedRecord(s Severity) bool {
+	return o.levels == nil || output{Levels: o.levels}.IsLevelNeedRecord(s)
+}
+
+func (o *testSinkOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.down {
+		return 0, errors.New("sink is down")
+	}
+	o.pending = append(o.pending, string(p))
+	return len(p), nil
+}
+
+func (o *testSinkOutput) HealthCheck() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.down {
+		return errors.New("sink is down")
+	}
+	return nil
+}
+
+func (o *testSinkOutput) Flush() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.flushes++
+	pending := o.pending
+	o.pending = nil
+	if o.down {
+		return errors.New("sink is down")
+	}
+	o.rows = append(o.rows, pending...)
+	return nil
+}
+
+func (o *testSinkOutput) setDown(down bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.down = down
+}
+
+func (o *testSinkOutput) received() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]string(nil), o.rows...)
+}
+
+// wait wait until n rows are received
+func (o *testSinkOutput) wait(n int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for len(o.received()) < n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return o.received()
+}
+
+// testContentSinkOutput testSinkOutput receives content, rows are common fields, message and fields
+type testContentSinkOutput struct {
+	testSinkOutput
+}
+
+func (o *testContentSinkOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	row := content.Message
+	for _, field := range commonFields {
+		row += " " + field.Key + "=" + field.Value
+	}
+	for _, field := range content.Fields {
+		row += " " + field.Key() + "=" + field.Value()
+	}
+	_, err := o.Write([]byte(fmt.Sprintf("%s %s", severityName[content.Headers.Level], row)))
+	return err
+}
+
+func testSpoolDir(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "spool")
+	assert.Nil(t, err)
+	return dir, func() {
+		os.RemoveAll(dir)
+	}
+}
+
+func testSpoolRows(n int) []string {
+	var rows []string
+	for i := 0; i < n; i++ {
+		rows = append(rows, fmt.Sprintf("row %02d\n", i))
+	}
+	return rows
+}
+
+func TestSpoolOutput(t *testing.T) {
+	dir, remove := testSpoolDir(t)
+	defer remove()
+	sink := &testSinkOutput{down: true}
+	output, err := NewSpoolOutput(sink, SpoolConfig{Dir: dir, BatchCount: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	_, ok := output.(ContentOutput)
+	assert.False(t, ok)
+
+	rows := testSpoolRows(10)
+	for _, row := range rows {
+		n, err := output.Write([]byte(row))
+		assert.Nil(t, err)
+		assert.Equal(t, len(row), n)
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 0, len(sink.received()))
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "forward spooled rows error: sink is down")
+	}
+
+	// rows are forwarded in order once sink recovers
+	sink.setDown(false)
+	assert.Equal(t, rows, sink.wait(len(rows)))
+	output.Write([]byte("row 10\n"))
+	assert.Equal(t, append(rows, "row 10\n"), sink.wait(len(rows)+1))
+}
+
+func TestSpoolOutput_content(t *testing.T) {
+	dir, remove := testSpoolDir(t)
+	defer remove()
+	sink := &testContentSinkOutput{}
+	output, err := NewSpoolOutput(sink, SpoolConfig{Dir: dir})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	l := NewLogging(WithOutput(output), WithCommonField("service", "order"))
+	l.Error(context.Background(), "from logger", String("order_id", "10"))
+	assert.Nil(t, l.Sync())
+	assert.Equal(t, []string{"ERROR from logger service=order order_id=10"}, sink.wait(1))
+}
+
+func TestSpoolOutput_recover(t *testing.T) {
+	dir, remove := testSpoolDir(t)
+	defer remove()
+	sink := &testSinkOutput{down: true}
+	output, err := NewSpoolOutput(sink, SpoolConfig{Dir: dir, MinBackoff: time.Hour})
+	assert.Nil(t, err)
+	rows := testSpoolRows(5)
+	for _, row := range rows {
+		output.Write([]byte(row))
+	}
+	assert.Nil(t, output.(interface{ Close() error }).Close())
+	_, err = output.Write([]byte("closed"))
+	assert.NotNil(t, err)
+
+	// a record partly written before crash
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.spool"))
+	if !assert.Equal(t, 1, len(segments)) {
+		return
+	}
+	info, _ := os.Stat(segments[0])
+	file, _ := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0644)
+	file.Write([]byte{0, 0, 0, 20, 1, 2})
+	file.Close()
+
+	sink = &testSinkOutput{}
+	output, err = NewSpoolOutput(sink, SpoolConfig{Dir: dir})
+	assert.Nil(t, err)
+	assert.Equal(t, rows, sink.wait(len(rows)))
+	output.Write([]byte("row 5\n"))
+	assert.Equal(t, append(rows, "row 5\n"), sink.wait(len(rows)+1))
+	assert.Nil(t, output.(interface{ Close() error }).Close())
+	info2, _ := os.Stat(segments[0])
+	assert.Equal(t, info.Size()+int64(spoolRecordHeaderSize+len("row 5\n")), info2.Size())
+
+	// forwarded rows are not forwarded again
+	sink = &testSinkOutput{}
+	output, err = NewSpoolOutput(sink, SpoolConfig{Dir: dir})
+	assert.Nil(t, err)
+	output.Write([]byte("row 6\n"))
+	assert.Equal(t, []string{"row 6\n"}, sink.wait(1))
+	assert.Nil(t, output.(interface{ Close() error }).Close())
+}
+
+func TestSpoolOutput_evict(t *testing.T) {
+	dir, remove := testSpoolDir(t)
+	defer remove()
+	sink := &testSinkOutput{down: true}
+	rows := testSpoolRows(100)
+	recordSize := int64(spoolRecordHeaderSize + len(rows[0]))
+	output, err := NewSpoolOutput(sink, SpoolConfig{Dir: dir, SegmentSize: 10 * recordSize, MaxSize: 30 * recordSize, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	assert.Nil(t, err)
+	defer output.(interface{ Close() error }).Close()
+	for _, row := range rows {
+		output.Write([]byte(row))
+	}
+	segments, _ := filepath.Glob(filepath.Join(dir, "*.spool"))
+	assert.Equal(t, 3, len(segments))
+	err = output.Flush()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), fmt.Sprintf("log spool %s is full, 70 rows evicted", dir))
+	}
+
+	// newest rows are kept
+	sink.setDown(false)
+	assert.Equal(t, rows[70:], sink.wait(30))
+	for len(segments) > 1 {
+		time.Sleep(time.Millisecond)
+		segments, _ = filepath.Glob(filepath.Join(dir, "*.spool"))
+	}
+	cursor, err := ioutil.ReadFile(filepath.Join(dir, spoolCursorFile))
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf("10 %d 10\n", 10*recordSize), string(cursor))
+}
+
+func TestNewSpoolOutput_error(t *testing.T) {
+	_, err := NewSpoolOutput(&testSinkOutput{}, SpoolConfig{})
+	assert.NotNil(t, err)
+
+	dir, remove := testSpoolDir(t)
+	defer remove()
+	file := filepath.Join(dir, "file")
+	assert.Nil(t, ioutil.WriteFile(file, nil, 0644))
+	_, err = NewSpoolOutput(&testSinkOutput{}, SpoolConfig{Dir: file})
+	if assert.NotNil(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "create log spool dir error"), err.Error())
+	}
+}
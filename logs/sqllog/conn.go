@@ -0,0 +1,240 @@
+package sqllog
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+)
+
+type conn struct {
+	conn    driver.Conn
+	options options
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := timeNow()
+	var s driver.Stmt
+	var err error
+	if preparer, ok := c.conn.(driver.ConnPrepareContext); ok {
+		s, err = preparer.PrepareContext(ctx, query)
+	} else {
+		s, err = c.conn.Prepare(query)
+	}
+	c.options.log(ctx, "sql prepare", query, nil, start, nil, err)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := &stmt{stmt: s, query: query, options: c.options}
+	// database/sql converts args with driver.ColumnConverter only when stmt implements it
+	if converter, ok := s.(driver.ColumnConverter); ok {
+		return &converterStmt{stmt: wrapped, converter: converter}, nil
+	}
+	return wrapped, nil
+}
+
+func (c *conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := timeNow()
+	var t driver.Tx
+	var err error
+	if beginner, ok := c.conn.(driver.ConnBeginTx); ok {
+		t, err = beginner.BeginTx(ctx, opts)
+	} else {
+		t, err = c.conn.Begin()
+	}
+	c.options.log(ctx, "sql begin", "", nil, start, nil, err)
+	if err != nil {
+		return nil, err
+	}
+	return &tx{tx: t, ctx: ctx, options: c.options}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := timeNow()
+	var result driver.Result
+	var err error
+	switch execer := c.conn.(type) {
+	case driver.ExecerContext:
+		result, err = execer.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = execer.Exec(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.options.log(ctx, "sql exec", query, args, start, result, err)
+	return result, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := timeNow()
+	var rows driver.Rows
+	var err error
+	switch queryer := c.conn.(type) {
+	case driver.QueryerContext:
+		rows, err = queryer.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = queryer.Query(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.options.log(ctx, "sql query", query, args, start, nil, err)
+	return rows, err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if pinger, ok := c.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// IsValid report whether wrapped connection can be reused, connections do not implement driver.Validator are valid.
+func (c *conn) IsValid() bool {
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+type stmt struct {
+	stmt    driver.Stmt
+	query   string
+	options options
+}
+
+func (s *stmt) Close() error {
+	return s.stmt.Close()
+}
+
+func (s *stmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := timeNow()
+	var result driver.Result
+	var err error
+	if execer, ok := s.stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			result, err = s.stmt.Exec(values)
+		}
+	}
+	s.options.log(ctx, "sql stmt exec", s.query, args, start, result, err)
+	return result, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := timeNow()
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = s.stmt.Query(values)
+		}
+	}
+	s.options.log(ctx, "sql stmt query", s.query, args, start, nil, err)
+	return rows, err
+}
+
+func (s *stmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// converterStmt stmt whose wrapped stmt implements driver.ColumnConverter
+type converterStmt struct {
+	*stmt
+	converter driver.ColumnConverter
+}
+
+func (s *converterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return s.converter.ColumnConverter(idx)
+}
+
+type tx struct {
+	tx      driver.Tx
+	ctx     context.Context
+	options options
+}
+
+func (t *tx) Commit() error {
+	start := timeNow()
+	err := t.tx.Commit()
+	t.options.log(t.ctx, "sql commit", "", nil, start, nil, err)
+	return err
+}
+
+func (t *tx) Rollback() error {
+	start := timeNow()
+	err := t.tx.Rollback()
+	t.options.log(t.ctx, "sql rollback", "", nil, start, nil, err)
+	return err
+}
+
+var errNamedArgs = errors.New("sqllog: driver does not support named arguments")
+
+func namedValuesToValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, errNamedArgs
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
+
+func valuesToNamedValues(args []driver.Value) []driver.NamedValue {
+	namedValues := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		namedValues[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return namedValues
+}
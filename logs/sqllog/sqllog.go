@@ -0,0 +1,201 @@
+// Package sqllog wraps a database/sql driver, logs every Exec, Query, Prepare, Begin, Commit and Rollback.
+//
+//	sql.Register("mysql-logged", sqllog.Wrap(&mysql.MySQLDriver{}, sqllog.WithLogger(l), sqllog.WithSlowThreshold(time.Second)))
+//	db, err := sql.Open("mysql-logged", dsn)
+//	db.QueryContext(ctx, "SELECT ...") // row carries trace id of ctx
+package sqllog
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/feehi.io/gopkg/logs"
+)
+
+// DefaultMaxArgLength default max length of a logged string argument
+const DefaultMaxArgLength = 64
+
+// Option create wrapped driver can pass option values.
+type Option func(*options)
+
+type options struct {
+	logger        logs.Logger
+	level         logs.Severity
+	slowThreshold time.Duration
+	sanitizer     func(args []driver.NamedValue) []string
+}
+
+// WithLogger set logger rows write to. Default is logs.Default().
+func WithLogger(logger logs.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// WithLevel set severity of successful operations. Default is DebugLog.
+// Failed operations are logged with ErrorLog.
+func WithLevel(level logs.Severity) Option {
+	return func(o *options) {
+		o.level = level
+	}
+}
+
+// WithSlowThreshold operations take longer than threshold are logged with WarningLog. Default is 0(disabled).
+func WithSlowThreshold(threshold time.Duration) Option {
+	return func(o *options) {
+		o.slowThreshold = threshold
+	}
+}
+
+// WithArgSanitizer set how query arguments are logged. Default is SanitizeArgs.
+// Use a sanitizer returns nil to omit arguments.
+func WithArgSanitizer(sanitizer func(args []driver.NamedValue) []string) Option {
+	return func(o *options) {
+		o.sanitizer = sanitizer
+	}
+}
+
+// SanitizeArgs format arguments for logging,
+// strings longer than DefaultMaxArgLength are cut and binary values are replaced by their length.
+func SanitizeArgs(args []driver.NamedValue) []string {
+	values := make([]string, 0, len(args))
+	for _, arg := range args {
+		var value string
+		switch v := arg.Value.(type) {
+		case nil:
+			value = "NULL"
+		case []byte:
+			value = fmt.Sprintf("<%d bytes>", len(v))
+		case string:
+			if len(v) > DefaultMaxArgLength {
+				v = v[:DefaultMaxArgLength] + "..."
+			}
+			value = fmt.Sprintf("%q", v)
+		case time.Time:
+			value = v.Format(time.RFC3339Nano)
+		default:
+			value = fmt.Sprintf("%v", v)
+		}
+		if arg.Name != "" {
+			value = arg.Name + "=" + value
+		}
+		values = append(values, value)
+	}
+	return values
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		logger:    logs.Default(),
+		level:     logs.DebugLog,
+		sanitizer: SanitizeArgs,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+var timeNow = time.Now
+
+// log write one row for a finished operation, driver.ErrSkip is not logged since database/sql will retry another way.
+func (o options) log(ctx context.Context, message string, query string, args []driver.NamedValue, start time.Time, result driver.Result, err error) {
+	if err == driver.ErrSkip {
+		return
+	}
+	duration := timeNow().Sub(start)
+	level := o.level
+	if o.slowThreshold > 0 && duration >= o.slowThreshold {
+		level = logs.WarningLog
+	}
+	fields := make([]logs.Field, 0, 5)
+	if query != "" {
+		fields = append(fields, logs.String("query", query))
+	}
+	if len(args) > 0 && o.sanitizer != nil {
+		if values := o.sanitizer(args); values != nil {
+			fields = append(fields, logs.String("args", strings.Join(values, ", ")))
+		}
+	}
+	if result != nil {
+		if rowsAffected, e := result.RowsAffected(); e == nil {
+			fields = append(fields, logs.Any("rows_affected", rowsAffected))
+		}
+	}
+	fields = append(fields, logs.String("duration", duration.String()))
+	if err != nil {
+		level = logs.ErrorLog
+		fields = append(fields, logs.Err(err))
+	}
+	o.logger.LogDepth(ctx, level, 1, message, fields...)
+}
+
+// Wrap wrap d, connections opened by returned driver log every operation.
+func Wrap(d driver.Driver, opts ...Option) driver.Driver {
+	return &wrappedDriver{driver: d, options: newOptions(opts)}
+}
+
+// WrapConnector wrap c, used with sql.OpenDB.
+func WrapConnector(c driver.Connector, opts ...Option) driver.Connector {
+	o := newOptions(opts)
+	return &connector{connector: c, driver: &wrappedDriver{driver: c.Driver(), options: o}, options: o}
+}
+
+type wrappedDriver struct {
+	driver  driver.Driver
+	options options
+}
+
+func (d *wrappedDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{conn: c, options: d.options}, nil
+}
+
+func (d *wrappedDriver) OpenConnector(name string) (driver.Connector, error) {
+	if driverContext, ok := d.driver.(driver.DriverContext); ok {
+		c, err := driverContext.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &connector{connector: c, driver: d, options: d.options}, nil
+	}
+	return &dsnConnector{name: name, driver: d}, nil
+}
+
+type connector struct {
+	connector driver.Connector
+	driver    driver.Driver
+	options   options
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{conn: cn, options: c.options}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// dsnConnector connector for drivers do not implement driver.DriverContext
+type dsnConnector struct {
+	name   string
+	driver driver.Driver
+}
+
+func (c *dsnConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.name)
+}
+
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
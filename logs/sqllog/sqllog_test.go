@@ -0,0 +1,391 @@
+package sqllog
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/feehi.io/gopkg/logs"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeDriver in memory driver, dsn "legacy" opens a connection only implements driver.Conn,
+// dsn "converter" opens a legacy connection which is invalid and prepares statements convert args to string.
+// Statements starting with FAIL return errFake, statements starting with SLOW advance the mocked clock by one second,
+// Exec affects len(args) rows, Query returns one row per arg.
+type fakeDriver struct{}
+
+var errFake = errors.New("fake failure")
+
+var fakeClock = time.Date(2020, 11, 20, 0, 0, 0, 0, time.UTC)
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	switch name {
+	case "legacy":
+		return &fakeLegacyConn{}, nil
+	case "converter":
+		return &fakeConverterConn{}, nil
+	}
+	return &fakeConn{}, nil
+}
+
+func fakeRun(query string, args []driver.NamedValue) error {
+	if strings.HasPrefix(query, "SLOW") {
+		fakeClock = fakeClock.Add(time.Second)
+	}
+	if strings.HasPrefix(query, "FAIL") {
+		return errFake
+	}
+	return nil
+}
+
+type fakeLegacyConn struct{}
+
+func (c *fakeLegacyConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.HasPrefix(query, "FAIL PREPARE") {
+		return nil, errFake
+	}
+	return &fakeStmt{query: query}, nil
+}
+
+func (c *fakeLegacyConn) Close() error {
+	return nil
+}
+
+func (c *fakeLegacyConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+type fakeConn struct {
+	fakeLegacyConn
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := fakeRun(query, args); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := fakeRun(query, args); err != nil {
+		return nil, err
+	}
+	return &fakeRows{args: args}, nil
+}
+
+type fakeStmt struct {
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := fakeRun(s.query, nil); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := fakeRun(s.query, nil); err != nil {
+		return nil, err
+	}
+	rows := &fakeRows{}
+	for i, arg := range args {
+		rows.args = append(rows.args, driver.NamedValue{Ordinal: i + 1, Value: arg})
+	}
+	return rows, nil
+}
+
+type fakeConverterConn struct {
+	fakeLegacyConn
+}
+
+func (c *fakeConverterConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeConverterStmt{fakeStmt{query: query}}, nil
+}
+
+func (c *fakeConverterConn) IsValid() bool {
+	return false
+}
+
+type fakeConverterStmt struct {
+	fakeStmt
+}
+
+func (s *fakeConverterStmt) ColumnConverter(idx int) driver.ValueConverter {
+	return driver.String
+}
+
+type fakeRows struct {
+	args []driver.NamedValue
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.args) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.args[0].Value
+	r.args = r.args[1:]
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error {
+	return nil
+}
+
+func (fakeTx) Rollback() error {
+	return errFake
+}
+
+type testRow struct {
+	logs.Content
+	Fields []map[string]string `json:"fields"`
+}
+
+func (r testRow) field(key string) string {
+	for _, field := range r.Fields {
+		if v, ok := field[key]; ok {
+			return v
+		}
+	}
+	return ""
+}
+
+func testDB(t *testing.T, dsn string, opts ...Option) (*sql.DB, func() []testRow) {
+	buf := &bytes.Buffer{}
+	logger := logs.NewLogging(
+		logs.WithFormatter(logs.NewJSONFormatter()),
+		logs.WithOutput(logs.NewOutPut(logs.AllSeverities, buf)),
+	)
+	connector, err := Wrap(fakeDriver{}, append([]Option{WithLogger(logger)}, opts...)...).(driver.DriverContext).OpenConnector(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	db.SetMaxOpenConns(1)
+	return db, func() []testRow {
+		logger.Sync()
+		var rows []testRow
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			row := testRow{}
+			assert.Nil(t, json.Unmarshal([]byte(line), &row))
+			rows = append(rows, row)
+		}
+		buf.Reset()
+		return rows
+	}
+}
+
+func TestWrap_exec(t *testing.T) {
+	defer func(f func() time.Time) { timeNow = f }(timeNow)
+	timeNow = func() time.Time { return fakeClock }
+
+	db, rows := testDB(t, "", WithSlowThreshold(time.Second))
+	defer db.Close()
+	ctx := context.WithValue(context.Background(), logs.TraceIDIdentifier, "sql_trace_id")
+
+	result, err := db.ExecContext(ctx, "INSERT INTO t VALUES (?, ?, ?)", 1, "a", []byte("bin"))
+	assert.Nil(t, err)
+	affected, _ := result.RowsAffected()
+	assert.Equal(t, int64(3), affected)
+	_, err = db.ExecContext(ctx, "FAIL INSERT")
+	assert.Equal(t, errFake, err)
+	_, err = db.ExecContext(ctx, "SLOW UPDATE t")
+	assert.Nil(t, err)
+
+	logged := rows()
+	if assert.Equal(t, 3, len(logged)) {
+		assert.Equal(t, "sql exec", logged[0].Message)
+		assert.Equal(t, logs.DebugLog, logged[0].Headers.Level)
+		assert.Equal(t, "sql_trace_id", logged[0].Headers.TraceID)
+		assert.Equal(t, "INSERT INTO t VALUES (?, ?, ?)", logged[0].field("query"))
+		assert.Equal(t, `1, "a", <3 bytes>`, logged[0].field("args"))
+		assert.Equal(t, "3", logged[0].field("rows_affected"))
+		assert.Equal(t, "0s", logged[0].field("duration"))
+
+		assert.Equal(t, logs.ErrorLog, logged[1].Headers.Level)
+		assert.Equal(t, errFake.Error(), logged[1].field("error"))
+
+		assert.Equal(t, logs.WarningLog, logged[2].Headers.Level)
+		assert.Equal(t, "1s", logged[2].field("duration"))
+	}
+}
+
+func TestWrap_query(t *testing.T) {
+	db, rows := testDB(t, "")
+	defer db.Close()
+
+	result, err := db.QueryContext(context.Background(), "SELECT value FROM t WHERE id IN (?, ?)", 1, 2)
+	assert.Nil(t, err)
+	var values []int64
+	for result.Next() {
+		var v int64
+		assert.Nil(t, result.Scan(&v))
+		values = append(values, v)
+	}
+	assert.Nil(t, result.Close())
+	assert.Equal(t, []int64{1, 2}, values)
+
+	logged := rows()
+	if assert.Equal(t, 1, len(logged)) {
+		assert.Equal(t, "sql query", logged[0].Message)
+		assert.Equal(t, "1, 2", logged[0].field("args"))
+		assert.Equal(t, "", logged[0].field("rows_affected"))
+	}
+}
+
+func TestWrap_legacyConn(t *testing.T) {
+	db, rows := testDB(t, "legacy")
+	defer db.Close()
+
+	// legacy connection has no ExecerContext, database/sql falls back to prepare then exec
+	_, err := db.Exec("INSERT INTO t VALUES (?)", 1)
+	assert.Nil(t, err)
+	_, err = db.Exec("FAIL PREPARE")
+	assert.Equal(t, errFake, err)
+
+	logged := rows()
+	messages := make([]string, 0, len(logged))
+	for _, row := range logged {
+		messages = append(messages, row.Message)
+	}
+	assert.Equal(t, []string{"sql prepare", "sql stmt exec", "sql prepare"}, messages)
+	if assert.Equal(t, 3, len(logged)) {
+		assert.Equal(t, "1", logged[1].field("args"))
+		assert.Equal(t, "1", logged[1].field("rows_affected"))
+		assert.Equal(t, logs.ErrorLog, logged[2].Headers.Level)
+	}
+}
+
+func TestWrap_converterConn(t *testing.T) {
+	db, rows := testDB(t, "converter")
+	defer db.Close()
+
+	_, err := db.Exec("INSERT INTO t VALUES (?, ?)", 1, true)
+	assert.Nil(t, err)
+	logged := rows()
+	if assert.Equal(t, 2, len(logged)) {
+		assert.Equal(t, "sql stmt exec", logged[1].Message)
+		assert.Equal(t, `"1", "true"`, logged[1].field("args"))
+	}
+
+	assert.False(t, (&conn{conn: &fakeConverterConn{}}).IsValid())
+	assert.True(t, (&conn{conn: &fakeLegacyConn{}}).IsValid())
+}
+
+func TestWrap_tx(t *testing.T) {
+	db, rows := testDB(t, "")
+	defer db.Close()
+	ctx := context.WithValue(context.Background(), logs.TraceIDIdentifier, "tx_trace_id")
+
+	tx, err := db.BeginTx(ctx, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, tx.Commit())
+	tx, err = db.BeginTx(ctx, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, errFake, tx.Rollback())
+
+	logged := rows()
+	if assert.Equal(t, 4, len(logged)) {
+		assert.Equal(t, "sql begin", logged[0].Message)
+		assert.Equal(t, "sql commit", logged[1].Message)
+		assert.Equal(t, "tx_trace_id", logged[1].Headers.TraceID)
+		assert.Equal(t, "sql rollback", logged[3].Message)
+		assert.Equal(t, logs.ErrorLog, logged[3].Headers.Level)
+	}
+}
+
+func TestWithArgSanitizer(t *testing.T) {
+	db, rows := testDB(t, "", WithLevel(logs.InfoLog), WithArgSanitizer(func(args []driver.NamedValue) []string {
+		return nil
+	}))
+	defer db.Close()
+
+	_, err := db.Exec("UPDATE users SET password = ?", "secret")
+	assert.Nil(t, err)
+
+	logged := rows()
+	if assert.Equal(t, 1, len(logged)) {
+		assert.Equal(t, logs.InfoLog, logged[0].Headers.Level)
+		assert.Equal(t, "", logged[0].field("args"))
+		assert.NotContains(t, logged[0].field("query"), "secret")
+	}
+}
+
+func TestSanitizeArgs(t *testing.T) {
+	testCases := []struct {
+		Input    []driver.NamedValue
+		Expected []string
+	}{
+		{Input: nil, Expected: []string{}},
+		{
+			Input:    []driver.NamedValue{{Value: int64(1)}, {Value: nil}, {Value: true}, {Value: 1.5}},
+			Expected: []string{"1", "NULL", "true", "1.5"},
+		},
+		{
+			Input:    []driver.NamedValue{{Name: "id", Value: "a\"b"}, {Value: []byte{1, 2}}},
+			Expected: []string{`id="a\"b"`, "<2 bytes>"},
+		},
+		{
+			Input:    []driver.NamedValue{{Value: strings.Repeat("x", DefaultMaxArgLength+1)}},
+			Expected: []string{`"` + strings.Repeat("x", DefaultMaxArgLength) + `..."`},
+		},
+		{
+			Input:    []driver.NamedValue{{Value: time.Date(2020, 11, 20, 1, 2, 3, 0, time.UTC)}},
+			Expected: []string{"2020-11-20T01:02:03Z"},
+		},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, SanitizeArgs(testCase.Input))
+	}
+}
+
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(_ context.Context) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+func (fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+func TestWrapConnector(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := logs.NewLogging(logs.WithOutput(logs.NewOutPut(logs.AllSeverities, buf)))
+	db := sql.OpenDB(WrapConnector(fakeConnector{}, WithLogger(logger)))
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM t")
+	assert.Nil(t, err)
+	logger.Sync()
+	assert.Equal(t, 1, strings.Count(buf.String(), "sql exec"))
+}
@@ -0,0 +1,75 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	stdlog "log"
+	"sync"
+)
+
+// stdLoggerDepth frames between lineWriter.Write and the caller of standard library logger,
+// which are (*log.Logger).output and (*log.Logger).Printf(or log.Printf...).
+const stdLoggerDepth = 3
+
+// NewStdLogger create a standard library *log.Logger, every line it prints is written to logger with severity s.
+// Often used for third-party libraries only accept *log.Logger, such as http.Server.ErrorLog.
+func NewStdLogger(logger Logger, s Severity) *stdlog.Logger {
+	return stdlog.New(&lineWriter{logger: logger, severity: s, depth: stdLoggerDepth}, "", 0)
+}
+
+// NewWriter create an io.Writer, incoming bytes are split on newlines and every line is written to logger
+// with severity s. Incomplete trailing line is kept until the next newline arrives.
+func NewWriter(logger Logger, s Severity) io.Writer {
+	return &lineWriter{logger: logger, severity: s, depth: 1}
+}
+
+// Writer create an io.Writer writes lines to package level log instance with severity s, see NewWriter.
+func Writer(s Severity) io.Writer {
+	return NewWriter(Default(), s)
+}
+
+// RedirectStdLog redirect standard library global logger(log.Printf...) to package level log instance with InfoLog,
+// return a function restores the previous output, flags and prefix.
+func RedirectStdLog() func() {
+	flags := stdlog.Flags()
+	prefix := stdlog.Prefix()
+	writer := stdlog.Writer()
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(&lineWriter{logger: Default(), severity: InfoLog, depth: stdLoggerDepth})
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetOutput(writer)
+	}
+}
+
+type lineWriter struct {
+	logger   Logger
+	severity Severity
+	depth    int
+	mu       sync.Mutex
+	buf      []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := bytes.TrimRight(w.buf[:i], "\r")
+		if len(line) > 0 {
+			w.logger.LogDepth(context.Background(), w.severity, w.depth, string(line))
+		}
+		w.buf = w.buf[i+1:]
+	}
+	if len(w.buf) == 0 {
+		w.buf = nil
+	}
+	return len(p), nil
+}
@@ -0,0 +1,64 @@
+package logs
+
+import (
+	"bytes"
+	stdlog "log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStdLogger(t *testing.T) {
+	outputCollects := bytes.Buffer{}
+	l := NewLogging(
+		WithFormatter(NewStringFormatter("{LEVEL} {FILE} {MESSAGE}", defaultTimeHeaderFormat(), false)),
+		WithOutput(NewOutPut(AllSeverities, &outputCollects)),
+	)
+	logger := NewStdLogger(l, WarningLog)
+	logger.Printf("std printf %d", 1)
+	logger.Println("std println\nsecond line")
+	l.Sync()
+	assert.Equal(t, "WARNING stdlog_test.go std printf 1\nWARNING stdlog_test.go std println\nWARNING stdlog_test.go second line\n", outputCollects.String())
+}
+
+func TestNewWriter(t *testing.T) {
+	outputCollects := bytes.Buffer{}
+	l := NewLogging(
+		WithFormatter(NewStringFormatter("{LEVEL} {FILE} {MESSAGE}", defaultTimeHeaderFormat(), false)),
+		WithOutput(NewOutPut(AllSeverities, &outputCollects)),
+	)
+	w := NewWriter(l, ErrorLog)
+	n, err := w.Write([]byte("first"))
+	assert.Nil(t, err)
+	assert.Equal(t, 5, n)
+	w.Write([]byte(" line\r\n\nsecond line\nthird"))
+	l.Sync()
+	assert.Equal(t, "ERROR stdlog_test.go first line\nERROR stdlog_test.go second line\n", outputCollects.String())
+}
+
+func TestWriter(t *testing.T) {
+	testInitLogging()
+	Writer(DebugLog).Write([]byte("package writer\n"))
+	Sync()
+	assert.Contains(t, outputCollects.String(), "DEBUG")
+	assert.Contains(t, outputCollects.String(), "stdlog_test.go")
+	assert.Contains(t, outputCollects.String(), "package writer")
+}
+
+func TestRedirectStdLog(t *testing.T) {
+	testInitLogging()
+	stdlog.SetPrefix("prefix ")
+	undo := RedirectStdLog()
+	stdlog.Printf("redirected %s", "std log")
+	Sync()
+	assert.Contains(t, outputCollects.String(), "INFO")
+	assert.Contains(t, outputCollects.String(), "stdlog_test.go")
+	assert.Contains(t, outputCollects.String(), "] redirected std log")
+
+	undo()
+	defer stdlog.SetPrefix("")
+	assert.Equal(t, "prefix ", stdlog.Prefix())
+	assert.Equal(t, stdlog.LstdFlags, stdlog.Flags())
+	assert.Equal(t, os.Stderr, stdlog.Writer())
+}
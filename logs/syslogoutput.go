@@ -0,0 +1,66 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package logs
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+)
+
+// NewSyslogOutput create a output writes rows to syslog, severity is mapped to syslog priority.
+// network and raddr are passed to syslog.Dial, empty network connects to local syslog server.
+// Rows are formatted with formatter, nil formatter uses a formatter without time since syslog adds it.
+func NewSyslogOutput(levels []Severity, network string, raddr string, tag string, formatter Formatter) (Output, error) {
+	writer, err := syslog.Dial(network, raddr, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		return nil, fmt.Errorf("dial syslog error: %s", err)
+	}
+	if formatter == nil {
+		formatter = NewStringFormatter(DefaultSyslogFormatTemplate, "", false)
+	}
+	return &syslogOutput{
+		Levels:    levels,
+		writer:    writer,
+		formatter: formatter,
+	}, nil
+}
+
+type syslogOutput struct {
+	Levels    []Severity
+	writer    *syslog.Writer
+	formatter Formatter
+}
+
+func (o *syslogOutput) IsLevelNeedRecord(s Severity) bool {
+	return output{Levels: o.Levels}.IsLevelNeedRecord(s)
+}
+
+func (o *syslogOutput) Flush() error {
+	return nil
+}
+
+// Write write formatted bytes with info priority.
+func (o *syslogOutput) Write(p []byte) (int, error) {
+	return len(p), o.writer.Info(string(p))
+}
+
+func (o *syslogOutput) WriteContent(commonFields []*CommonField, content *Content) error {
+	m := strings.TrimSuffix(string(o.formatter.Format(commonFields, content)), "\n")
+	switch content.Headers.Level {
+	case DebugLog:
+		return o.writer.Debug(m)
+	case InfoLog:
+		return o.writer.Info(m)
+	case WarningLog:
+		return o.writer.Warning(m)
+	case ErrorLog:
+		return o.writer.Err(m)
+	}
+	return o.writer.Crit(m)
+}
+
+func (o *syslogOutput) Close() error {
+	return o.writer.Close()
+}
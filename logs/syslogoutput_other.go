@@ -0,0 +1,14 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package logs
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// NewSyslogOutput syslog is not supported on windows and plan9, always return error.
+func NewSyslogOutput(levels []Severity, network string, raddr string, tag string, formatter Formatter) (Output, error) {
+	return nil, fmt.Errorf("syslog output is not supported on %s", runtime.GOOS)
+}
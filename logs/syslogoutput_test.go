@@ -0,0 +1,55 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package logs
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSyslogOutput(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	output, err := NewSyslogOutput([]Severity{InfoLog, ErrorLog}, "udp", conn.LocalAddr().String(), "order", nil)
+	assert.Nil(t, err)
+	l := NewLogging(WithOutput(output))
+	l.Info(context.Background(), "syslog info")
+	l.Error(context.Background(), "syslog error", String("category", "test"))
+	l.Debug(context.Background(), "not recorded")
+	assert.Nil(t, l.Sync())
+
+	// priority is facility user(1) * 8 + syslog severity
+	expected := []struct {
+		Priority string
+		Message  string
+	}{
+		{Priority: "<14>", Message: "syslog info"},
+		{Priority: "<11>", Message: "syslog error"},
+	}
+	buf := make([]byte, 2048)
+	for _, e := range expected {
+		assert.Nil(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, _, err := conn.ReadFrom(buf)
+		assert.Nil(t, err)
+		packet := string(buf[:n])
+		assert.True(t, strings.HasPrefix(packet, e.Priority), packet)
+		assert.Contains(t, packet, "order[")
+		assert.Contains(t, packet, e.Message)
+		assert.NotContains(t, packet, "\n\n")
+	}
+	assert.Nil(t, output.(io.Closer).Close())
+}
+
+func TestNewSyslogOutput_error(t *testing.T) {
+	_, err := NewSyslogOutput(AllSeverities, "invalid", "127.0.0.1:514", "order", nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,176 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultTemplateFormatTemplate default TemplateFormatter template
+const DefaultTemplateFormatTemplate = `[{{.Level | pad 7}} {{.TraceID | default "-"}} {{.Time}} {{.File}}:{{.Line}}] {{.Message}}{{range .Fields}} {{.Key}}={{.Value}}{{end}}`
+
+var templateColors = map[string]string{
+	"reset":   colorReset,
+	"dim":     colorDim,
+	"red":     colorRed,
+	"green":   colorGreen,
+	"yellow":  colorYellow,
+	"blue":    colorBlue,
+	"magenta": colorMagenta,
+	"cyan":    colorCyan,
+}
+
+func init() {
+	for s, name := range severityName {
+		templateColors[name] = severityColor[s]
+	}
+}
+
+// templateFuncs helper functions can be used in TemplateFormatter template.
+var templateFuncs = template.FuncMap{
+	// pad pad s with spaces to width runes, negative width pad on the left
+	"pad": func(width int, s string) string {
+		if width < 0 {
+			n := utf8.RuneCountInString(s)
+			if n >= -width {
+				return s
+			}
+			return strings.Repeat(" ", -width-n) + s
+		}
+		return padRight(s, width)
+	},
+	// trunc cut s to at most n bytes, a multi-byte rune is not split
+	"trunc": func(n int, s string) string {
+		if n >= 0 && len(s) > n {
+			for n > 0 && !utf8.RuneStart(s[n]) {
+				n--
+			}
+			return s[:n]
+		}
+		return s
+	},
+	// json marshal v to JSON
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// color wrap s with ANSI color, name is a color name(red, green...) or a level name(DEBUG, INFO...)
+	"color": func(name string, s string) string {
+		color, ok := templateColors[name]
+		if !ok || color == "" {
+			return s
+		}
+		return color + s + colorReset
+	},
+	"upper": strings.ToUpper,
+	// default return def when s is empty
+	"default": func(def string, s string) string {
+		if s == "" {
+			return def
+		}
+		return s
+	},
+}
+
+// NewTemplateFormatter create a TemplateFormatter.
+// Template is parsed and verified here, so template errors are reported once rather than every log row.
+// Template is executed with TemplateData, helper functions are pad, trunc, json, color, upper and default.
+// Such as `{{.Level | color .Level}} {{.Field "user_id" | default "-"}} {{.Message | trunc 100}}`.
+func NewTemplateFormatter(text string, timeFormat string, toUTCTime bool) (*TemplateFormatter, error) {
+	tpl, err := template.New("logs").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse log template error: %s", err)
+	}
+	f := &TemplateFormatter{
+		TimeFormat: timeFormat,
+		ToUTCTime:  toUTCTime,
+		template:   tpl,
+	}
+	sample := &Content{
+		Headers: MessageHeader{Level: InfoLog, Time: time.Time{}},
+		Fields:  []Field{String("key", "value")},
+	}
+	if err := tpl.Execute(&bytes.Buffer{}, f.data([]*CommonField{NewCommonField("key", "value")}, sample)); err != nil {
+		return nil, fmt.Errorf("execute log template error: %s", err)
+	}
+	return f, nil
+}
+
+// TemplateFormatter format log to a row with text/template
+type TemplateFormatter struct {
+	TimeFormat string
+	ToUTCTime  bool
+	template   *template.Template
+}
+
+// TemplateData the data TemplateFormatter template executed with.
+type TemplateData struct {
+	Content      *Content
+	Level        string
+	TraceID      string
+	SpanID       string
+	Time         string
+	File         string
+	Line         int
+	Message      string
+	Fields       []Field
+	CommonFields []*CommonField
+}
+
+// Field get field value by key, return empty string when not exists
+func (d TemplateData) Field(key string) string {
+	for _, field := range d.Fields {
+		if field.Key() == key {
+			return field.Value()
+		}
+	}
+	return ""
+}
+
+// CommonField get common field value by key, return empty string when not exists
+func (d TemplateData) CommonField(key string) string {
+	for _, commonField := range d.CommonFields {
+		if commonField.Key == key {
+			return commonField.Value
+		}
+	}
+	return ""
+}
+
+func (f TemplateFormatter) data(commonFields []*CommonField, content *Content) TemplateData {
+	t := content.Headers.Time
+	if f.ToUTCTime {
+		t = t.UTC()
+	}
+	return TemplateData{
+		Content:      content,
+		Level:        severityName[content.Headers.Level],
+		TraceID:      content.Headers.TraceID,
+		SpanID:       content.Headers.SpanID,
+		Time:         t.Format(f.TimeFormat),
+		File:         content.Headers.File,
+		Line:         content.Headers.Line,
+		Message:      content.Message,
+		Fields:       content.Fields,
+		CommonFields: commonFields,
+	}
+}
+
+// Format format log with template
+func (f TemplateFormatter) Format(commonFields []*CommonField, content *Content) []byte {
+	buf := bytes.Buffer{}
+	err := f.template.Execute(&buf, f.data(commonFields, content))
+	if err != nil {
+		fmt.Println("format log with template err", err)
+		buf.Reset()
+		buf.WriteString(content.Message)
+	}
+	if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,107 @@
+package logs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTemplateFormatter(t *testing.T) {
+	testCases := []struct {
+		Input   string
+		Error   bool
+		Message string
+	}{
+		{Input: DefaultTemplateFormatTemplate, Error: false, Message: "default template"},
+		{Input: "{{.Message", Error: true, Message: "parse error"},
+		{Input: "{{unknown .Message}}", Error: true, Message: "undefined function"},
+		{Input: "{{.NotExists}}", Error: true, Message: "undefined field"},
+		{Input: "{{pad .Message 1}}", Error: true, Message: "wrong argument type"},
+	}
+	for _, testCase := range testCases {
+		formatter, err := NewTemplateFormatter(testCase.Input, defaultTimeHeaderFormat(), false)
+		if testCase.Error {
+			assert.NotNil(t, err, testCase.Message)
+			assert.Nil(t, formatter, testCase.Message)
+		} else {
+			assert.Nil(t, err, testCase.Message)
+			assert.Equal(t, defaultTimeHeaderFormat(), formatter.TimeFormat, testCase.Message)
+		}
+	}
+}
+
+func TestTemplateFormatter_Format(t *testing.T) {
+	testCases := []struct {
+		Input struct {
+			Template    string
+			CommonField []*CommonField
+			Content     *Content
+		}
+		Expected string
+	}{
+		{
+			Input: struct {
+				Template    string
+				CommonField []*CommonField
+				Content     *Content
+			}{Template: DefaultTemplateFormatTemplate, Content: func() *Content {
+				content := mockContent()
+				content.Fields = []Field{String("category", "Go"), String("account_id", "123")}
+				return content
+			}()},
+			Expected: "[DEBUG   test_trace_id 2020-11-20 00:00:00 test.go:101] test message category=Go account_id=123\n",
+		},
+		{
+			Input: struct {
+				Template    string
+				CommonField []*CommonField
+				Content     *Content
+			}{Template: `{{.CommonField "instance"}} {{.Level | pad -7}} {{.Field "user_id" | default "-"}} {{.Field "category" | upper}} {{.Message | trunc 4}}`, CommonField: []*CommonField{NewCommonField("instance", "testMachine")}, Content: func() *Content {
+				content := mockContent()
+				content.Fields = []Field{String("category", "Go")}
+				return content
+			}()},
+			Expected: "testMachine   DEBUG - GO test\n",
+		},
+		{
+			Input: struct {
+				Template    string
+				CommonField []*CommonField
+				Content     *Content
+			}{Template: `{{.Message | trunc 5}}|{{.Message | trunc 6}}|{{.Message | pad 7}}|{{.Message | pad -7}}`, Content: func() *Content {
+				content := mockContent()
+				content.Message = "日志abc"
+				return content
+			}()},
+			Expected: "日|日志|日志abc  |  日志abc\n",
+		},
+		{
+			Input: struct {
+				Template    string
+				CommonField []*CommonField
+				Content     *Content
+			}{Template: "{{.Level | color .Level}} {{color \"unknown\" .Message}} {{json .Content.Headers.Line}} {{json .Message}}\n", Content: mockContent()},
+			Expected: "\x1b[35mDEBUG\x1b[0m test message 101 \"test message\"\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		formatter, err := NewTemplateFormatter(testCase.Input.Template, defaultTimeHeaderFormat(), true)
+		assert.Nil(t, err)
+		message := formatter.Format(testCase.Input.CommonField, testCase.Input.Content)
+		assert.Equal(t, testCase.Expected, string(message))
+	}
+}
+
+func TestTemplateFormatter_Format_error(t *testing.T) {
+	formatter, err := NewTemplateFormatter(`{{json .Content.Headers.Time}}`, defaultTimeHeaderFormat(), false)
+	assert.Nil(t, err)
+	content := mockContent()
+	content.Headers.Time = content.Headers.Time.AddDate(20000, 0, 0)
+	var message []byte
+	stdOut := testCaptureSTDOutput(func() {
+		message = formatter.Format(nil, content)
+	})
+	assert.Contains(t, stdOut, "format log with template err")
+	assert.Equal(t, "test message\n", string(message))
+}
@@ -0,0 +1,138 @@
+package logs
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// VModule per caller file min levels, parsed by ParseVModule.
+// Its results are cached per caller PC, a new VModule is needed to change patterns.
+type VModule struct {
+	spec     string
+	filters  []vModuleFilter
+	minLevel Severity
+	// cache caller PC to vModuleResult
+	cache sync.Map
+}
+
+type vModuleFilter struct {
+	pattern  string
+	segments int
+	level    Severity
+}
+
+type vModuleResult struct {
+	level   Severity
+	matched bool
+}
+
+// ParseVModule parse comma separated pattern=level list, such as "storage/*=debug,http=warning".
+// Pattern without "/" matches caller file name without .go, such as http matches http.go.
+// Pattern with "/" matches the same number of trailing path elements, such as storage/* matches all files in storage dir.
+// Patterns are path.Match patterns, the first matched pattern decides the min level of a file instead of min level option.
+func ParseVModule(spec string) (*VModule, error) {
+	v := &VModule{spec: spec, minLevel: FatalLog}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid vmodule item %q, should be pattern=level", item)
+		}
+		pattern := strings.TrimSuffix(kv[0], ".go")
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid vmodule pattern %q: %s", kv[0], err)
+		}
+		level, err := ParseSeverity(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid vmodule item %q: %s", item, err)
+		}
+		v.filters = append(v.filters, vModuleFilter{pattern: pattern, segments: strings.Count(pattern, "/") + 1, level: level})
+		if level < v.minLevel {
+			v.minLevel = level
+		}
+	}
+	if len(v.filters) == 0 {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// String return the spec VModule parsed from
+func (v *VModule) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.spec
+}
+
+// level return min level of caller file and whether a pattern matched, results are cached by pc when it is not 0.
+func (v *VModule) level(pc uintptr, file string) (Severity, bool) {
+	if pc != 0 {
+		if result, ok := v.cache.Load(pc); ok {
+			return result.(vModuleResult).level, result.(vModuleResult).matched
+		}
+	}
+	result := v.match(file)
+	if pc != 0 {
+		v.cache.Store(pc, result)
+	}
+	return result.level, result.matched
+}
+
+func (v *VModule) match(file string) vModuleResult {
+	if file == "" {
+		return vModuleResult{}
+	}
+	file = strings.TrimSuffix(file, ".go")
+	for _, filter := range v.filters {
+		if matched, _ := path.Match(filter.pattern, trailingElements(file, filter.segments)); matched {
+			return vModuleResult{level: filter.level, matched: true}
+		}
+	}
+	return vModuleResult{}
+}
+
+// trailingElements return last n slash separated elements of file
+func trailingElements(file string, n int) string {
+	i := len(file)
+	for ; n > 0 && i >= 0; n-- {
+		i = strings.LastIndex(file[:i], "/")
+	}
+	return file[i+1:]
+}
+
+// lowestLevel return the lowest level rows may be recorded at, rows below it are dropped before caller info is collected.
+func (o *options) lowestLevel() Severity {
+	if o.vModule != nil && o.vModule.minLevel < o.minLevel {
+		return o.vModule.minLevel
+	}
+	return o.minLevel
+}
+
+// levelEnabled report whether rows of level s from caller file should be recorded,
+// min level of file matched by vmodule overrides min level option.
+func (o *options) levelEnabled(s Severity, pc uintptr, file string) bool {
+	if o.vModule != nil {
+		if level, matched := o.vModule.level(pc, file); matched {
+			return s >= level
+		}
+	}
+	return s >= o.minLevel
+}
+
+// SetVModule replace vmodule patterns, see ParseVModule. Empty spec removes them.
+func (l *logging) SetVModule(spec string) error {
+	vModule, err := ParseVModule(spec)
+	if err != nil {
+		return err
+	}
+	l.update(func(o *options) {
+		o.vModule = vModule
+	})
+	return nil
+}
@@ -0,0 +1,156 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVModule(t *testing.T) {
+	testCases := []struct {
+		Input    string
+		Expected []vModuleFilter
+		Error    string
+	}{
+		{Input: "", Expected: nil},
+		{Input: " , ", Expected: nil},
+		{
+			Input: "storage/*=debug, http.go=warn,github.com/feehi/*/db=error",
+			Expected: []vModuleFilter{
+				{pattern: "storage/*", segments: 2, level: DebugLog},
+				{pattern: "http", segments: 1, level: WarningLog},
+				{pattern: "github.com/feehi/*/db", segments: 4, level: ErrorLog},
+			},
+		},
+		{Input: "storage", Error: `invalid vmodule item "storage", should be pattern=level`},
+		{Input: "=debug", Error: `invalid vmodule item "=debug", should be pattern=level`},
+		{Input: "http=verbose", Error: `invalid vmodule item "http=verbose": unknown log level "verbose"`},
+		{Input: "[a=debug", Error: `invalid vmodule pattern "[a"`},
+	}
+	for _, testCase := range testCases {
+		v, err := ParseVModule(testCase.Input)
+		if testCase.Error != "" {
+			if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), testCase.Error)
+			}
+			continue
+		}
+		assert.Nil(t, err)
+		if testCase.Expected == nil {
+			assert.Nil(t, v)
+			continue
+		}
+		assert.Equal(t, testCase.Expected, v.filters)
+		assert.Equal(t, DebugLog, v.minLevel)
+		assert.Equal(t, testCase.Input, v.String())
+	}
+}
+
+func TestVModule_match(t *testing.T) {
+	v, err := ParseVModule("storage/*=debug,http=warning,*_test=info,cmd/*/main=error")
+	assert.Nil(t, err)
+	testCases := []struct {
+		Input    string
+		Expected vModuleResult
+	}{
+		{Input: "/src/app/storage/db.go", Expected: vModuleResult{level: DebugLog, matched: true}},
+		{Input: "storage/db.go", Expected: vModuleResult{level: DebugLog, matched: true}},
+		{Input: "/src/app/storage/sub/db.go", Expected: vModuleResult{}},
+		{Input: "/src/app/server/http.go", Expected: vModuleResult{level: WarningLog, matched: true}},
+		{Input: "/src/app/server/https.go", Expected: vModuleResult{}},
+		{Input: "/src/app/storage/db_test.go", Expected: vModuleResult{level: DebugLog, matched: true}},
+		{Input: "/src/app/server/http_test.go", Expected: vModuleResult{level: InfoLog, matched: true}},
+		{Input: "/src/app/cmd/order/main.go", Expected: vModuleResult{level: ErrorLog, matched: true}},
+		{Input: "main.go", Expected: vModuleResult{}},
+		{Input: "", Expected: vModuleResult{}},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.Expected, v.match(testCase.Input), testCase.Input)
+	}
+}
+
+func TestVModule_level_cache(t *testing.T) {
+	v, err := ParseVModule("http=warning")
+	assert.Nil(t, err)
+
+	level, matched := v.level(1, "/src/http.go")
+	assert.Equal(t, WarningLog, level)
+	assert.True(t, matched)
+	// cached by pc, file is not matched again
+	level, matched = v.level(1, "/src/db.go")
+	assert.Equal(t, WarningLog, level)
+	assert.True(t, matched)
+	// pc 0 is unknown caller, never cached
+	_, matched = v.level(0, "/src/http.go")
+	assert.True(t, matched)
+	_, matched = v.level(0, "/src/db.go")
+	assert.False(t, matched)
+}
+
+func TestLoggingT_vModule(t *testing.T) {
+	outputCollects := bytes.Buffer{}
+	vModule, err := ParseVModule("vmodule_test=debug")
+	assert.Nil(t, err)
+	l := NewLogging(WithOutput(NewOutPut(AllSeverities, &outputCollects)), WithMinLevel(ErrorLog), WithVModule(vModule))
+	assert.Equal(t, DebugLog, l.opts().lowestLevel())
+
+	l.Debug(context.Background(), "debug kept by vmodule")
+	l.Sync()
+	assert.Contains(t, outputCollects.String(), "debug kept by vmodule")
+	assert.Contains(t, outputCollects.String(), "vmodule_test.go")
+
+	assert.Nil(t, l.SetVModule("logs/*=warning"))
+	l.Info(context.Background(), "info dropped by vmodule")
+	l.Warning(context.Background(), "warning kept by vmodule")
+	l.Sync()
+	assert.NotContains(t, outputCollects.String(), "info dropped by vmodule")
+	assert.Contains(t, outputCollects.String(), "warning kept by vmodule")
+
+	assert.NotNil(t, l.SetVModule("logs/*=verbose"))
+	assert.Equal(t, "logs/*=warning", l.opts().vModule.String())
+	assert.Nil(t, l.SetVModule(""))
+	assert.Nil(t, l.opts().vModule)
+	l.Warning(context.Background(), "warning dropped by min level")
+	l.Sync()
+	assert.NotContains(t, outputCollects.String(), "warning dropped by min level")
+}
+
+func TestLoggingT_vModule_cachedPerPC(t *testing.T) {
+	defer func() {
+		runtimeCaller = runtime.Caller
+	}()
+	outputCollects := bytes.Buffer{}
+	l := NewLogging(WithOutput(NewOutPut(AllSeverities, &outputCollects)), WithMinLevel(ErrorLog))
+	assert.Nil(t, l.SetVModule("storage/*=debug"))
+
+	file := "/src/app/storage/db.go"
+	runtimeCaller = func(skip int) (pc uintptr, f string, line int, ok bool) {
+		return 100, file, 1, true
+	}
+	l.Debug(context.Background(), "debug from storage")
+	file = "/src/app/server/http.go"
+	l.Debug(context.Background(), "debug from cached pc")
+	l.Sync()
+	assert.Contains(t, outputCollects.String(), "debug from storage")
+	assert.Contains(t, outputCollects.String(), "debug from cached pc")
+
+	// a new VModule starts with empty cache, other files keep min level
+	assert.Nil(t, l.SetVModule("storage/*=debug"))
+	l.Debug(context.Background(), "debug from server")
+	l.Error(context.Background(), "error from server")
+	l.Sync()
+	assert.NotContains(t, outputCollects.String(), "debug from server")
+	assert.Contains(t, outputCollects.String(), "error from server")
+}
+
+func TestSetVModule(t *testing.T) {
+	defer testInitLogging()
+	testInitLogging()
+	assert.Nil(t, SetVModule("http=warning"))
+	assert.Equal(t, "http=warning", log().opts().vModule.String())
+	assert.NotNil(t, SetVModule("http"))
+	assert.Equal(t, "http=warning", log().opts().vModule.String())
+}